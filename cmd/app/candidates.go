@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"linkedin-automation-poc/internal/review"
+	"linkedin-automation-poc/internal/storage"
+)
+
+const candidatesUsage = `usage:
+  app candidates list [-status pending|approved|rejected|all]
+  app candidates review
+  app candidates approve <profile-url>...
+  app candidates reject <profile-url>...
+  app candidates serve [-addr 127.0.0.1:8088]`
+
+// cmdCandidates manages the review queue between search and connect.
+func cmdCandidates(ctx context.Context, a *app, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, candidatesUsage)
+		return exitUsage
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("candidates list", flag.ContinueOnError)
+		filter := fs.String("status", "pending", "pending, approved, rejected or all")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+			fmt.Fprintln(os.Stderr, candidatesUsage)
+			return exitUsage
+		}
+		var status storage.CandidateStatus
+		if *filter != "all" {
+			if status, err = storage.ParseCandidateStatus(*filter); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitUsage
+			}
+		}
+		return a.candidatesList(ctx, db, status)
+	case "approve", "reject":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, candidatesUsage)
+			return exitUsage
+		}
+		status := storage.CandidateApproved
+		if args[0] == "reject" {
+			status = storage.CandidateRejected
+		}
+		code := exitOK
+		for _, ref := range args[1:] {
+			updated, err := db.SetCandidateStatus(ctx, ref, status, time.Now())
+			switch {
+			case err != nil:
+				a.log.WithError(err).WithField("profile", ref).Error("failed to record review decision")
+				code = exitError
+			case !updated:
+				a.log.WithField("profile", ref).Warn("not in the candidate queue")
+				code = exitError
+			default:
+				a.log.WithField("profile", ref).WithField("status", status).Info("candidate reviewed")
+			}
+		}
+		return code
+	case "review":
+		return a.candidatesReview(ctx, db, os.Stdin, os.Stdout)
+	case "serve":
+		fs := flag.NewFlagSet("candidates serve", flag.ContinueOnError)
+		addr := fs.String("addr", "127.0.0.1:8088", "loopback address for the review page")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+			fmt.Fprintln(os.Stderr, candidatesUsage)
+			return exitUsage
+		}
+		h, err := review.NewHandler(db, a.log)
+		if err != nil {
+			a.log.WithError(err).Error("failed to set up review page")
+			return exitError
+		}
+		a.log.WithField("url", "http://"+*addr+"/").Info("serving candidate review page, press Ctrl+C to stop")
+		if err := review.ListenAndServe(ctx, *addr, h); err != nil {
+			a.log.WithError(err).Error("review page stopped")
+			return exitError
+		}
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, candidatesUsage)
+		return exitUsage
+	}
+}
+
+func (a *app) candidatesList(ctx context.Context, db *storage.Storage, status storage.CandidateStatus) int {
+	candidates, err := db.Candidates(ctx, status)
+	if err != nil {
+		a.log.WithError(err).Error("failed to list candidates")
+		return exitError
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tNAME\tHEADLINE\tKEYWORD\tPROFILE")
+	for _, c := range candidates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Status, c.Name, c.Headline, c.Keyword, c.ProfileURL)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// candidatesReview walks through pending candidates one at a time and asks
+// for a decision on each.
+func (a *app) candidatesReview(ctx context.Context, db *storage.Storage, in io.Reader, out io.Writer) int {
+	pending, err := db.Candidates(ctx, storage.CandidatePending)
+	if err != nil {
+		a.log.WithError(err).Error("failed to list candidates")
+		return exitError
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "No pending candidates.")
+		return exitOK
+	}
+
+	sc := bufio.NewScanner(in)
+	for i, c := range pending {
+		fmt.Fprintf(out, "\n[%d/%d] %s\n  %s\n  keyword: %s\n  %s\n", i+1, len(pending), c.Name, c.Headline, c.Keyword, c.ProfileURL)
+
+		var status storage.CandidateStatus
+		for status == "" {
+			fmt.Fprint(out, "(a)pprove, (r)eject, (s)kip, (q)uit? ")
+			if !sc.Scan() {
+				fmt.Fprintln(out)
+				return exitOK
+			}
+			switch strings.ToLower(strings.TrimSpace(sc.Text())) {
+			case "a", "approve":
+				status = storage.CandidateApproved
+			case "r", "reject":
+				status = storage.CandidateRejected
+			case "s", "skip":
+				status = storage.CandidatePending
+			case "q", "quit":
+				return exitOK
+			}
+		}
+		if status == storage.CandidatePending {
+			continue
+		}
+		if _, err := db.SetCandidateStatus(ctx, c.ProfileURL, status, time.Now()); err != nil {
+			a.log.WithError(err).WithField("profile", c.ProfileURL).Error("failed to record review decision")
+			return exitError
+		}
+	}
+	return exitOK
+}
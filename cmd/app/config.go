@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"linkedin-automation-poc/internal/config"
+)
+
+const configUsage = `usage:
+  app config validate
+  app config print [--effective]`
+
+// cmdConfig checks or shows the configuration. It loads the config itself so
+// it can report an invalid file instead of refusing to start.
+func cmdConfig(ctx context.Context, a *app, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+		if code, ok := parseFlags(fs, args[1:]); !ok {
+			return code
+		}
+		if a.cfgErr != nil {
+			printConfigError(a.configPath, a.cfgErr)
+			return exitUsage
+		}
+		fmt.Printf("%s: ok\n", a.configPath)
+		return exitOK
+	case "print":
+		fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+		effective := fs.Bool("effective", false, "print the merged result of defaults, file, LIAUTO_* variables and -set overrides")
+		if code, ok := parseFlags(fs, args[1:]); !ok {
+			return code
+		}
+		if !*effective {
+			data, err := os.ReadFile(a.configPath)
+			if err != nil {
+				a.log.WithError(err).Error("failed to read config")
+				return exitError
+			}
+			os.Stdout.Write(data)
+			return exitOK
+		}
+		// Print what the layers add up to even when it is invalid, then
+		// say why.
+		cfg, err := config.Build(a.configPath, a.overrides...)
+		if cfg == nil {
+			printConfigError(a.configPath, err)
+			return exitUsage
+		}
+		out, err := yaml.Marshal(cfg)
+		if err != nil {
+			a.log.WithError(err).Error("failed to encode config")
+			return exitError
+		}
+		os.Stdout.Write(out)
+		if a.cfgErr != nil {
+			printConfigError(a.configPath, a.cfgErr)
+			return exitUsage
+		}
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, configUsage)
+		return exitUsage
+	}
+}
+
+// printConfigError writes a config error to stderr, one problem per line.
+func printConfigError(path string, err error) {
+	var verr config.ValidationError
+	if !errors.As(err, &verr) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d problem(s):\n", path, len(verr))
+	for _, fe := range verr {
+		fmt.Fprintf(os.Stderr, "  %s\n", fe.Error())
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+const cooldownUsage = `usage:
+  app cooldown show
+  app cooldown clear`
+
+// cmdCooldown shows or clears the pause on sending set after a LinkedIn
+// warning or repeated checkpoints.
+func cmdCooldown(ctx context.Context, a *app, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, cooldownUsage)
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("cooldown "+args[0], flag.ContinueOnError)
+	if code, ok := parseFlags(fs, args[1:]); !ok {
+		return code
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "show":
+		cd, found, err := db.Cooldown(ctx)
+		if err != nil {
+			a.log.WithError(err).Error("failed to load cooldown")
+			return exitError
+		}
+		if !found || !cd.Active(time.Now()) {
+			fmt.Println("no cooldown")
+			return exitOK
+		}
+		fmt.Printf("sending paused until %s (%s)\n", cd.Until.Local().Format("2006-01-02 15:04"), cd.Reason)
+		return exitOK
+	case "clear":
+		cleared, err := db.ClearCooldown(ctx)
+		if err != nil {
+			a.log.WithError(err).Error("failed to clear cooldown")
+			return exitError
+		}
+		if !cleared {
+			a.log.Info("no cooldown to clear")
+			return exitOK
+		}
+		a.log.Warn("cooldown cleared; sending resumes on the next run")
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, cooldownUsage)
+		return exitUsage
+	}
+}
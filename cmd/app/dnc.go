@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+const dncUsage = `usage:
+  app dnc list
+  app dnc add [-company] [-reason TEXT] <profile-url|company>
+  app dnc remove [-company] <profile-url|company>
+  app dnc import <file.csv>
+
+The CSV file needs a header row with a profile_url or company column and an
+optional reason column; each row fills in one of profile_url or company.`
+
+// cmdDNC manages the do-not-contact list.
+func cmdDNC(ctx context.Context, a *app, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, dncUsage)
+		return exitUsage
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		return a.dncList(ctx, db)
+	case "add", "remove":
+		fs := flag.NewFlagSet("dnc "+args[0], flag.ContinueOnError)
+		company := fs.Bool("company", false, "treat the argument as a company name")
+		reason := fs.String("reason", "", "why this entry was added")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, dncUsage)
+			return exitUsage
+		}
+		kind := storage.DoNotContactProfile
+		if *company {
+			kind = storage.DoNotContactCompany
+		}
+		if args[0] == "add" {
+			if err := db.AddDoNotContact(ctx, kind, fs.Arg(0), *reason, time.Now()); err != nil {
+				a.log.WithError(err).Error("failed to add do-not-contact entry")
+				return exitError
+			}
+			a.log.WithField("kind", kind).WithField("value", fs.Arg(0)).Info("added to do-not-contact list")
+			return exitOK
+		}
+		removed, err := db.RemoveDoNotContact(ctx, kind, fs.Arg(0))
+		if err != nil {
+			a.log.WithError(err).Error("failed to remove do-not-contact entry")
+			return exitError
+		}
+		if !removed {
+			a.log.WithField("kind", kind).WithField("value", fs.Arg(0)).Warn("no matching do-not-contact entry")
+			return exitError
+		}
+		a.log.WithField("kind", kind).WithField("value", fs.Arg(0)).Info("removed from do-not-contact list")
+		return exitOK
+	case "import":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, dncUsage)
+			return exitUsage
+		}
+		n, err := dncImport(ctx, db, args[1])
+		if err != nil {
+			a.log.WithError(err).WithField("imported", n).Error("do-not-contact import failed")
+			return exitError
+		}
+		a.log.WithField("imported", n).Info("imported do-not-contact list")
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, dncUsage)
+		return exitUsage
+	}
+}
+
+func (a *app) dncList(ctx context.Context, db *storage.Storage) int {
+	entries, err := db.ListDoNotContact(ctx)
+	if err != nil {
+		a.log.WithError(err).Error("failed to list do-not-contact entries")
+		return exitError
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tVALUE\tREASON\tADDED AT")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Kind, e.Value, e.Reason, e.AddedAt.Local().Format(time.RFC3339))
+	}
+	w.Flush()
+	return exitOK
+}
+
+// dncImport adds every row of a CSV file and returns how many were added
+// before any error. Rows already on the list only have their reason updated.
+func dncImport(ctx context.Context, db *storage.Storage, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return 0, fmt.Errorf("read header: %w", err)
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	_, hasProfile := col["profile_url"]
+	_, hasCompany := col["company"]
+	if !hasProfile && !hasCompany {
+		return 0, errors.New("header must contain a profile_url or company column")
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	n := 0
+	now := time.Now()
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		kind, value := storage.DoNotContactProfile, field(rec, "profile_url")
+		if value == "" {
+			kind, value = storage.DoNotContactCompany, field(rec, "company")
+		}
+		if value == "" {
+			continue
+		}
+		if err := db.AddDoNotContact(ctx, kind, value, field(rec, "reason"), now); err != nil {
+			line, _ := r.FieldPos(0)
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		n++
+	}
+}
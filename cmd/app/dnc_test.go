@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+func TestDNCImport(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	dir := t.TempDir()
+	db, err := storage.New("file:"+filepath.Join(dir, "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	writeCSV := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Rows with neither column filled in are skipped; a profile_url that is
+	// not a profile stops the import at its line.
+	path := writeCSV("dnc.csv", `Reason, Profile_URL, Company
+asked us to stop, https://de.linkedin.com/in/Jane-Doe?trk=x,
+customer,, Acme  Corp
+,,
+short row
+competitor, https://www.linkedin.com/in/bob/, Globex
+bad, https://www.linkedin.com/company/initech/,
+never read, https://www.linkedin.com/in/carol/,
+`)
+	n, err := dncImport(ctx, db, path)
+	if err == nil || !strings.Contains(err.Error(), "line 7") {
+		t.Errorf("dncImport = %d, %v; want an error on line 7", n, err)
+	}
+	if n != 3 {
+		t.Errorf("imported %d rows before the error, want 3", n)
+	}
+	entries, err := db.ListDoNotContact(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, string(e.Kind)+" "+e.Value+" ("+e.Reason+")")
+	}
+	want := []string{
+		"profile https://www.linkedin.com/in/jane-doe/ (asked us to stop)",
+		"company acme corp (customer)",
+		// A row with both columns is keyed by its profile.
+		"profile https://www.linkedin.com/in/bob/ (competitor)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Importing again only updates the reasons.
+	path = writeCSV("again.csv", "company,reason\nACME CORP,former customer\n")
+	if n, err := dncImport(ctx, db, path); err != nil || n != 1 {
+		t.Errorf("second dncImport = %d, %v; want 1", n, err)
+	}
+	if e, found, err := db.MatchDoNotContact(ctx, "https://www.linkedin.com/in/ann/", "Acme Corp"); err != nil || !found || e.Reason != "former customer" {
+		t.Errorf("MatchDoNotContact = %+v, %v, %v; want the updated reason", e, found, err)
+	}
+	if entries, err := db.ListDoNotContact(ctx); err != nil || len(entries) != 3 {
+		t.Errorf("%d entries after the second import (%v), want 3", len(entries), err)
+	}
+
+	path = writeCSV("header.csv", "name,reason\nJane,x\n")
+	if _, err := dncImport(ctx, db, path); err == nil || !strings.Contains(err.Error(), "profile_url or company") {
+		t.Errorf("dncImport without a usable header = %v", err)
+	}
+}
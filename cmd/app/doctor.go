@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// check is one line of the doctor report.
+type check struct {
+	name   string
+	status string // ok, warn or fail
+	detail string
+}
+
+// cmdDoctor checks everything a run depends on without touching LinkedIn
+// beyond one page load, and exits non-zero when any check fails.
+func cmdDoctor(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var checks []check
+	add := func(name, status, detail string) {
+		checks = append(checks, check{name, status, detail})
+	}
+
+	if a.cfgErr != nil {
+		add("config", "fail", fmt.Sprintf("%s: %v", a.configPath, a.cfgErr))
+	} else {
+		add("config", "ok", a.configPath)
+		checks = append(checks, doctorDatabase(ctx, a))
+		checks = append(checks, doctorSite(ctx, a.cfg.LinkedIn.BaseURL))
+	}
+
+	if path, found := launcher.LookPath(); found {
+		add("browser", "ok", path)
+	} else {
+		add("browser", "warn", "no local Chrome/Chromium found; Rod will try to download one on first launch")
+	}
+
+	if a.cfgErr == nil {
+		checks = append(checks, doctorSession(a))
+	}
+
+	code := exitOK
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.name, c.status, c.detail)
+		if c.status == "fail" {
+			code = exitError
+		}
+	}
+	w.Flush()
+	return code
+}
+
+// doctorSession checks that the saved session can be decrypted and has not
+// expired, or that credentials are there to log in again.
+func doctorSession(a *app) check {
+	hasCreds := os.Getenv("LINKEDIN_EMAIL") != "" && os.Getenv("LINKEDIN_PASSWORD") != ""
+	sessions := auth.NewSessionStore(a.cfg.Session)
+	_, err := sessions.Load(time.Now())
+	switch {
+	case err == nil:
+		return check{"session", "ok", "saved session in " + sessions.Path()}
+	case hasCreds:
+		return check{"session", "warn", err.Error() + "; will log in with LINKEDIN_EMAIL / LINKEDIN_PASSWORD"}
+	default:
+		return check{"session", "fail", err.Error() + `; run "app login --interactive" or set LINKEDIN_EMAIL / LINKEDIN_PASSWORD`}
+	}
+}
+
+func doctorDatabase(ctx context.Context, a *app) check {
+	db, err := storage.Open(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		return check{"database", "fail", err.Error()}
+	}
+	defer db.Close()
+	states, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return check{"database", "fail", err.Error()}
+	}
+	pending := 0
+	for _, st := range states {
+		if !st.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return check{"database", "warn", fmt.Sprintf("%d pending migrations; they are applied on the next run (or with \"app migrate up\")", pending)}
+	}
+	return check{"database", "ok", fmt.Sprintf("schema at version %d", states[len(states)-1].Version)}
+}
+
+func doctorSite(ctx context.Context, baseURL string) check {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/login", nil)
+	if err != nil {
+		return check{"site", "fail", err.Error()}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return check{"site", "fail", err.Error()}
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return check{"site", "fail", fmt.Sprintf("%s answered %s", baseURL, resp.Status)}
+	}
+	return check{"site", "ok", fmt.Sprintf("%s answered %s", baseURL, resp.Status)}
+}
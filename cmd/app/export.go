@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+// recordType describes how one table is written to and read back from
+// export files. CSV files hold a single type with the columns as header;
+// JSON Lines files can mix types, each line naming its own.
+type recordType struct {
+	name    string
+	columns []string
+	// dateColumn is the timestamp -since and -until filter on.
+	dateColumn string
+	// rows returns one row per stored record, in column order.
+	rows func(ctx context.Context, db *storage.Storage) ([][]string, error)
+	// add parses one imported row into the batch.
+	add func(row map[string]string, batch *storage.Import) error
+}
+
+var recordTypes = []recordType{
+	{
+		name:       "sent_requests",
+		columns:    []string{"profile_url", "status", "reason", "sent_at", "confirmed_at", "unconfirmed_at", "updated_at"},
+		dateColumn: "sent_at",
+		rows: func(ctx context.Context, db *storage.Storage) ([][]string, error) {
+			recs, err := db.Requests(ctx)
+			var rows [][]string
+			for _, r := range recs {
+				rows = append(rows, []string{r.ProfileURL, string(r.Status), r.Reason, fileTime(r.SentAt), fileTime(r.ConfirmedAt), fileTime(r.UnconfirmedAt), fileTime(r.UpdatedAt)})
+			}
+			return rows, err
+		},
+		add: func(row map[string]string, batch *storage.Import) error {
+			r := storage.RequestRecord{ProfileURL: row["profile_url"], Reason: row["reason"]}
+			var err error
+			if r.Status, err = storage.ParseRequestStatus(row["status"]); err != nil {
+				return err
+			}
+			if err := parseFileTimes(row, map[string]*time.Time{
+				"sent_at": &r.SentAt, "confirmed_at": &r.ConfirmedAt, "unconfirmed_at": &r.UnconfirmedAt, "updated_at": &r.UpdatedAt,
+			}, "sent_at"); err != nil {
+				return err
+			}
+			batch.Requests = append(batch.Requests, r)
+			return nil
+		},
+	},
+	{
+		name:       "messages",
+		columns:    []string{"profile_url", "message_type", "sent_at", "status"},
+		dateColumn: "sent_at",
+		rows: func(ctx context.Context, db *storage.Storage) ([][]string, error) {
+			recs, err := db.Messages(ctx)
+			var rows [][]string
+			for _, m := range recs {
+				rows = append(rows, []string{m.ProfileURL, m.MessageType, fileTime(m.SentAt), string(m.Status)})
+			}
+			return rows, err
+		},
+		add: func(row map[string]string, batch *storage.Import) error {
+			m := storage.MessageRecord{ProfileURL: row["profile_url"], MessageType: row["message_type"]}
+			if m.MessageType == "" {
+				return fmt.Errorf("message_type is empty")
+			}
+			var err error
+			if m.Status, err = storage.ParseMessageStatus(row["status"]); err != nil {
+				return err
+			}
+			if err := parseFileTimes(row, map[string]*time.Time{"sent_at": &m.SentAt}, "sent_at"); err != nil {
+				return err
+			}
+			batch.Messages = append(batch.Messages, m)
+			return nil
+		},
+	},
+	{
+		name:       "candidates",
+		columns:    []string{"profile_url", "name", "headline", "company", "location", "degree", "keyword", "status", "found_at", "reviewed_at"},
+		dateColumn: "found_at",
+		rows: func(ctx context.Context, db *storage.Storage) ([][]string, error) {
+			recs, err := db.Candidates(ctx, "")
+			var rows [][]string
+			for _, c := range recs {
+				rows = append(rows, []string{c.ProfileURL, c.Name, c.Headline, c.Company, c.Location, c.Degree, c.Keyword, string(c.Status), fileTime(c.FoundAt), fileTime(c.ReviewedAt)})
+			}
+			return rows, err
+		},
+		add: func(row map[string]string, batch *storage.Import) error {
+			c := storage.Candidate{
+				ProfileURL: row["profile_url"], Name: row["name"], Headline: row["headline"], Company: row["company"],
+				Location: row["location"], Degree: row["degree"], Keyword: row["keyword"],
+			}
+			var err error
+			if c.Status, err = storage.ParseCandidateStatus(row["status"]); err != nil {
+				return err
+			}
+			if err := parseFileTimes(row, map[string]*time.Time{"found_at": &c.FoundAt, "reviewed_at": &c.ReviewedAt}, "found_at"); err != nil {
+				return err
+			}
+			batch.Candidates = append(batch.Candidates, c)
+			return nil
+		},
+	},
+	{
+		name:       "connections",
+		columns:    []string{"profile_url", "name", "first_seen_at"},
+		dateColumn: "first_seen_at",
+		rows: func(ctx context.Context, db *storage.Storage) ([][]string, error) {
+			recs, err := db.Connections(ctx)
+			var rows [][]string
+			for _, c := range recs {
+				rows = append(rows, []string{c.ProfileURL, c.Name, fileTime(c.FirstSeenAt)})
+			}
+			return rows, err
+		},
+		add: func(row map[string]string, batch *storage.Import) error {
+			c := storage.ConnectionRecord{ProfileURL: row["profile_url"], Name: row["name"]}
+			if err := parseFileTimes(row, map[string]*time.Time{"first_seen_at": &c.FirstSeenAt}, "first_seen_at"); err != nil {
+				return err
+			}
+			batch.Connections = append(batch.Connections, c)
+			return nil
+		},
+	},
+}
+
+func lookupRecordType(name string) (*recordType, error) {
+	for i := range recordTypes {
+		if recordTypes[i].name == name {
+			return &recordTypes[i], nil
+		}
+	}
+	names := make([]string, len(recordTypes))
+	for i, t := range recordTypes {
+		names[i] = t.name
+	}
+	return nil, fmt.Errorf("unknown record type %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// selectRecordTypes resolves -type values; none means every type.
+func selectRecordTypes(names []string) ([]*recordType, error) {
+	if len(names) == 0 {
+		names = make([]string, len(recordTypes))
+		for i, t := range recordTypes {
+			names[i] = t.name
+		}
+	}
+	var types []*recordType
+	for _, n := range names {
+		t, err := lookupRecordType(n)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// Export file formats.
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// fileFormat returns the -format value, or the format implied by path's
+// extension when the flag is empty.
+func fileFormat(flagValue, path string) (string, error) {
+	if flagValue == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jsonl", ".ndjson":
+			return formatJSONL, nil
+		default:
+			return formatCSV, nil
+		}
+	}
+	if flagValue != formatCSV && flagValue != formatJSONL {
+		return "", fmt.Errorf("unknown format %q (want csv or jsonl)", flagValue)
+	}
+	return flagValue, nil
+}
+
+// dateRange is the -since/-until window; zero ends are open.
+type dateRange struct {
+	since, until time.Time
+}
+
+// dateRangeFlags registers -since and -until on fs.
+func dateRangeFlags(fs *flag.FlagSet) (since, until *string) {
+	since = fs.String("since", "", "only records on or after this date (YYYY-MM-DD, UTC) or RFC 3339 time")
+	until = fs.String("until", "", "only records on or before this date (YYYY-MM-DD, the whole day counts) or before this RFC 3339 time")
+	return since, until
+}
+
+func parseDateRange(since, until string) (dateRange, error) {
+	var r dateRange
+	var err error
+	if since != "" {
+		if r.since, _, err = parseDateFlag(since); err != nil {
+			return r, fmt.Errorf("-since: %w", err)
+		}
+	}
+	if until != "" {
+		var dateOnly bool
+		if r.until, dateOnly, err = parseDateFlag(until); err != nil {
+			return r, fmt.Errorf("-until: %w", err)
+		}
+		if dateOnly {
+			r.until = r.until.AddDate(0, 0, 1)
+		}
+	}
+	return r, nil
+}
+
+func parseDateFlag(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is neither YYYY-MM-DD nor an RFC 3339 time", s)
+	}
+	return t, false, nil
+}
+
+// contains reports whether the value of a date column falls in the range.
+// Records without the date only match an open range.
+func (r dateRange) contains(value string) bool {
+	if r.since.IsZero() && r.until.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return false
+	}
+	return !t.Before(r.since) && (r.until.IsZero() || t.Before(r.until))
+}
+
+func cmdExport(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var typeNames stringList
+	fs.Var(&typeNames, "type", "record type: sent_requests, messages, candidates or connections; repeatable (default: all)")
+	format := fs.String("format", "", "csv or jsonl (default: from the -o extension, else csv)")
+	out := fs.String("o", "-", "output file, - for stdout; a directory when exporting several types as CSV")
+	since, until := dateRangeFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	types, err := selectRecordTypes(typeNames)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	f, err := fileFormat(*format, *out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	window, err := parseDateRange(*since, *until)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if f == formatCSV && len(types) > 1 && *out == "-" {
+		fmt.Fprintln(os.Stderr, "a CSV file holds one record type: pass -type, -format jsonl, or a directory as -o")
+		return exitUsage
+	}
+	if *out == "-" {
+		// Keep stdout clean for the export itself.
+		a.log.SetOutput(os.Stderr)
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	if f == formatCSV && len(types) > 1 {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			a.log.WithError(err).Error("failed to create output directory")
+			return exitError
+		}
+		for _, t := range types {
+			path := filepath.Join(*out, t.name+".csv")
+			if err := a.exportFile(ctx, db, path, f, []*recordType{t}, window); err != nil {
+				a.log.WithError(err).WithField("type", t.name).Error("export failed")
+				return exitError
+			}
+		}
+		return exitOK
+	}
+	if err := a.exportFile(ctx, db, *out, f, types, window); err != nil {
+		a.log.WithError(err).Error("export failed")
+		return exitError
+	}
+	return exitOK
+}
+
+// exportFile writes the records of types that fall in window to path.
+func (a *app) exportFile(ctx context.Context, db *storage.Storage, path, format string, types []*recordType, window dateRange) (err error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+
+	var cw *csv.Writer
+	if format == formatCSV {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(types[0].columns); err != nil {
+			return err
+		}
+	}
+	enc := json.NewEncoder(w)
+	for _, t := range types {
+		rows, err := t.rows(ctx, db)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", t.name, err)
+		}
+		dateIdx := columnIndex(t.columns, t.dateColumn)
+		n := 0
+		for _, row := range rows {
+			if !window.contains(row[dateIdx]) {
+				continue
+			}
+			n++
+			if cw != nil {
+				err = cw.Write(row)
+			} else {
+				err = enc.Encode(jsonLine(t, row))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if path != "-" {
+			a.log.WithField("type", t.name).WithField("rows", n).WithField("path", path).Info("export written")
+		}
+	}
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// jsonLine is one JSON Lines record: "type" plus the CSV columns, with empty
+// values left out.
+func jsonLine(t *recordType, row []string) map[string]string {
+	line := map[string]string{"type": t.name}
+	for i, col := range t.columns {
+		if row[i] != "" {
+			line[col] = row[i]
+		}
+	}
+	return line
+}
+
+func columnIndex(columns []string, name string) int {
+	for i, c := range columns {
+		if c == name {
+			return i
+		}
+	}
+	panic("export: no column " + name)
+}
+
+// fileTime formats a timestamp for export files: RFC 3339 in UTC with full
+// precision, so an import matches the stored value exactly. Zero times are
+// left blank.
+func fileTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseFileTimes parses the named timestamp columns of an imported row into
+// their targets; required must be present, the others may be blank.
+func parseFileTimes(row map[string]string, targets map[string]*time.Time, required string) error {
+	for col, dst := range targets {
+		v := row[col]
+		if v == "" {
+			if col == required {
+				return fmt.Errorf("%s is empty", col)
+			}
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an RFC 3339 time", col, v)
+		}
+		*dst = t
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/storage"
+)
+
+func newExportApp(t *testing.T) (*app, string) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return &app{log: log}, t.TempDir()
+}
+
+func openTestDB(t *testing.T, a *app, path string) *storage.Storage {
+	t.Helper()
+	db, err := storage.New("file:"+path, a.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// seedHistory stores one or two records of every exported type, dated from
+// 1 to 6 March 2026.
+func seedHistory(t *testing.T, db *storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+	day := func(d, hour int) time.Time { return time.Date(2026, 3, d, hour, 30, 0, 0, time.UTC) }
+	profile := func(id string) string { return "https://www.linkedin.com/in/" + id + "/" }
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	must(db.SetRequestStatus(ctx, profile("alice"), storage.RequestQueued, "", day(2, 9)))
+	must(db.SetRequestStatus(ctx, profile("alice"), storage.RequestSent, "", day(2, 9)))
+	must(db.SetRequestStatus(ctx, profile("bob"), storage.RequestQueued, "", day(5, 10)))
+	must(db.SetRequestStatus(ctx, profile("bob"), storage.RequestUnconfirmed, "page did not confirm the invitation", day(5, 10)))
+	must(db.SetRequestStatus(ctx, profile("carol"), storage.RequestQueued, "", day(3, 11)))
+	must(db.SetRequestStatus(ctx, profile("carol"), storage.RequestSent, "", day(3, 11)))
+	_, err := db.MarkRequestAccepted(ctx, profile("carol"), day(4, 8))
+	must(err)
+	must(db.RecordMessage(ctx, profile("carol"), messaging.FollowUpType, storage.MessageSent, day(4, 12)))
+	_, err = db.RecordConnection(ctx, profile("carol"), "Carol Smith", day(4, 8))
+	must(err)
+	_, err = db.SaveCandidate(ctx, storage.Candidate{ProfileURL: profile("dave"), Name: "Dave, Jr.", Headline: "Engineer at \"Acme\"", Company: "Acme", Keyword: "golang", Status: storage.CandidatePending}, day(1, 7))
+	must(err)
+	_, err = db.SaveCandidate(ctx, storage.Candidate{ProfileURL: profile("erin"), Name: "Erin", Keyword: "golang", Status: storage.CandidatePending}, day(6, 7))
+	must(err)
+	_, err = db.SetCandidateStatus(ctx, profile("erin"), storage.CandidateApproved, day(6, 9))
+	must(err)
+}
+
+// exportAll exports every record type inside window to dir, as one JSON
+// Lines file or as one CSV file per type, and returns the files written.
+func exportAll(t *testing.T, a *app, db *storage.Storage, dir, format string, window dateRange) []string {
+	t.Helper()
+	types, err := selectRecordTypes(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format == formatJSONL {
+		path := filepath.Join(dir, "history.jsonl")
+		if err := a.exportFile(context.Background(), db, path, format, types, window); err != nil {
+			t.Fatal(err)
+		}
+		return []string{path}
+	}
+	var paths []string
+	for _, typ := range types {
+		path := filepath.Join(dir, typ.name+".csv")
+		if err := a.exportFile(context.Background(), db, path, format, []*recordType{typ}, window); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func readFiles(t *testing.T, paths []string) string {
+	t.Helper()
+	var all bytes.Buffer
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(data)
+	}
+	return all.String()
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{formatJSONL, formatCSV} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			a, dir := newExportApp(t)
+			src := openTestDB(t, a, filepath.Join(dir, "src.db"))
+			seedHistory(t, src)
+			first := filepath.Join(dir, "first")
+			if err := os.Mkdir(first, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			paths := exportAll(t, a, src, first, format, dateRange{})
+
+			var batch storage.Import
+			for _, p := range paths {
+				if err := readImportFile(p, format, nil, dateRange{}, &batch); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dst := openTestDB(t, a, filepath.Join(dir, "dst.db"))
+			res, err := dst.Import(ctx, batch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := storage.ImportResult{
+				Requests:    storage.ImportCounts{Added: 3},
+				Messages:    storage.ImportCounts{Added: 1},
+				Candidates:  storage.ImportCounts{Added: 2},
+				Connections: storage.ImportCounts{Added: 1},
+			}
+			if res != want {
+				t.Errorf("Import = %+v, want %+v", res, want)
+			}
+
+			// Exporting the imported copy gives the same files.
+			second := filepath.Join(dir, "second")
+			if err := os.Mkdir(second, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			got, orig := readFiles(t, exportAll(t, a, dst, second, format, dateRange{})), readFiles(t, paths)
+			if got != orig {
+				t.Errorf("export after import =\n%s\nwant\n%s", got, orig)
+			}
+
+			// Importing the same files again duplicates nothing.
+			if res, err := dst.Import(ctx, batch); err != nil || res.Requests.Added+res.Messages.Added+res.Candidates.Added+res.Connections.Added != 0 {
+				t.Errorf("second Import = %+v, %v; want nothing added", res, err)
+			}
+		})
+	}
+}
+
+func TestImportByteOrderMark(t *testing.T) {
+	_, dir := newExportApp(t)
+	files := map[string]string{
+		"connections.csv": byteOrderMark + "Profile_URL,name,first_seen_at\r\nhttps://www.linkedin.com/in/carol/,Carol,2026-03-04T08:30:00Z\r\n",
+		"history.jsonl":   byteOrderMark + `{"type":"connections","profile_url":"https://www.linkedin.com/in/dan/","first_seen_at":"2026-03-05T08:30:00Z"}` + "\n",
+	}
+	var batch storage.Import
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		format, err := fileFormat("", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := readImportFile(path, format, nil, dateRange{}, &batch); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if len(batch.Connections) != 2 {
+		t.Errorf("read %+v, want both connections", batch.Connections)
+	}
+}
+
+func TestExportImportDateRange(t *testing.T) {
+	a, dir := newExportApp(t)
+	db := openTestDB(t, a, filepath.Join(dir, "test.db"))
+	seedHistory(t, db)
+
+	tests := []struct {
+		since, until string
+		want         []string
+	}{
+		{
+			since: "2026-03-03", until: "2026-03-04",
+			want: []string{"sent_requests carol", "messages carol", "connections carol"},
+		},
+		{
+			// A date-only -until counts the whole day; a time is exclusive.
+			since: "2026-03-04", until: "2026-03-04T12:30:00Z",
+			want: []string{"connections carol"},
+		},
+		{
+			since: "2026-03-05",
+			want:  []string{"sent_requests bob", "candidates erin"},
+		},
+		{
+			until: "2026-03-01",
+			want:  []string{"candidates dave"},
+		},
+		{
+			since: "2026-03-02T09:30:00+01:00", until: "2026-03-02",
+			want: []string{"sent_requests alice"},
+		},
+	}
+	all := exportAll(t, a, db, dir, formatJSONL, dateRange{})
+	for _, tt := range tests {
+		window, err := parseDateRange(tt.since, tt.until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := tt.since + ".." + tt.until
+
+		// export -since/-until
+		out := filepath.Join(t.TempDir(), "window.jsonl")
+		types, _ := selectRecordTypes(nil)
+		if err := a.exportFile(context.Background(), db, out, formatJSONL, types, window); err != nil {
+			t.Fatal(err)
+		}
+		var batch storage.Import
+		if err := readImportFile(out, formatJSONL, nil, dateRange{}, &batch); err != nil {
+			t.Fatal(err)
+		}
+		if got := batchSummary(batch); got != strings.Join(tt.want, ", ") {
+			t.Errorf("export %s = %s, want %s", name, got, strings.Join(tt.want, ", "))
+		}
+
+		// import -since/-until of a full export
+		batch = storage.Import{}
+		if err := readImportFile(all[0], formatJSONL, nil, window, &batch); err != nil {
+			t.Fatal(err)
+		}
+		if got := batchSummary(batch); got != strings.Join(tt.want, ", ") {
+			t.Errorf("import %s = %s, want %s", name, got, strings.Join(tt.want, ", "))
+		}
+	}
+
+	if _, err := parseDateRange("yesterday", ""); err == nil {
+		t.Error("parseDateRange accepted -since yesterday")
+	}
+}
+
+// batchSummary lists the type and profile ID of every record in batch.
+func batchSummary(batch storage.Import) string {
+	var out []string
+	id := func(url string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(url, "https://www.linkedin.com/in/"), "/")
+	}
+	for _, r := range batch.Requests {
+		out = append(out, "sent_requests "+id(r.ProfileURL))
+	}
+	for _, m := range batch.Messages {
+		out = append(out, "messages "+id(m.ProfileURL))
+	}
+	for _, c := range batch.Candidates {
+		out = append(out, "candidates "+id(c.ProfileURL))
+	}
+	for _, c := range batch.Connections {
+		out = append(out, "connections "+id(c.ProfileURL))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+func cmdImport(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	var typeNames stringList
+	fs.Var(&typeNames, "type", "only import these record types; for CSV, the type of the file (default: from the file name)")
+	format := fs.String("format", "", "csv or jsonl (default: from the file extension)")
+	since, until := dateRangeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: app import [flags] <file>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	var only []*recordType
+	if len(typeNames) > 0 {
+		var err error
+		if only, err = selectRecordTypes(typeNames); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+	window, err := parseDateRange(*since, *until)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// Read every file first so a bad row aborts before anything is stored.
+	var batch storage.Import
+	for _, path := range fs.Args() {
+		f, err := fileFormat(*format, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		if err := readImportFile(path, f, only, window, &batch); err != nil {
+			a.log.WithError(err).Error("import failed, nothing was stored")
+			return exitError
+		}
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	res, err := db.Import(ctx, batch)
+	if err != nil {
+		a.log.WithError(err).Error("import failed, nothing was stored")
+		return exitError
+	}
+	for _, c := range []struct {
+		name   string
+		counts storage.ImportCounts
+	}{
+		{"sent_requests", res.Requests},
+		{"messages", res.Messages},
+		{"candidates", res.Candidates},
+		{"connections", res.Connections},
+	} {
+		if c.counts == (storage.ImportCounts{}) {
+			continue
+		}
+		a.log.WithField("type", c.name).
+			WithField("added", c.counts.Added).
+			WithField("updated", c.counts.Updated).
+			WithField("already_present", c.counts.Existing).
+			Info("imported")
+	}
+	return exitOK
+}
+
+// byteOrderMark is what spreadsheet programs such as Excel put at the start
+// of the UTF-8 files they save.
+const byteOrderMark = "\ufeff"
+
+// readImportFile adds the rows of one export file to batch, keeping only the
+// wanted types (nil means all) dated inside window.
+func readImportFile(path, format string, only []*recordType, window dateRange, batch *storage.Import) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	wanted := func(t *recordType) bool {
+		if only == nil {
+			return true
+		}
+		for _, o := range only {
+			if o == t {
+				return true
+			}
+		}
+		return false
+	}
+	addRow := func(t *recordType, row map[string]string) error {
+		if !wanted(t) || !window.contains(row[t.dateColumn]) {
+			return nil
+		}
+		if row["profile_url"] == "" {
+			return errors.New("profile_url is empty")
+		}
+		return t.add(row, batch)
+	}
+
+	if format == formatJSONL {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; sc.Scan(); line++ {
+			text := sc.Text()
+			if line == 1 {
+				text = strings.TrimPrefix(text, byteOrderMark)
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			var row map[string]string
+			if err := json.Unmarshal([]byte(text), &row); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			t, err := lookupRecordType(row["type"])
+			if err == nil {
+				err = addRow(t, row)
+			}
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		return sc.Err()
+	}
+
+	// A CSV file holds one type: the single -type given, or the file name.
+	var t *recordType
+	switch {
+	case len(only) == 1:
+		t = only[0]
+	case len(only) > 1:
+		return fmt.Errorf("%s: a CSV file holds one record type, pass a single -type", path)
+	default:
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if t, err = lookupRecordType(name); err != nil {
+			return fmt.Errorf("%s: cannot tell the record type from the file name, pass -type: %w", path, err)
+		}
+	}
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("%s: read header: %w", path, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], byteOrderMark)
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.ToLower(header[i]))
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(rec) {
+				row[col] = strings.TrimSpace(rec[i])
+			}
+		}
+		if err := addRow(t, row); err != nil {
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+}
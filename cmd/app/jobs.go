@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+// cmdJobs lists the jobs of the latest plan made by "app run", or of the
+// plan given with -plan.
+func cmdJobs(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	planFlag := fs.Int64("plan", 0, "show this plan instead of the latest")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	planID := *planFlag
+	if planID == 0 {
+		latest, found, err := db.LatestPlan(ctx)
+		if err != nil {
+			a.log.WithError(err).Error("failed to load plans")
+			return exitError
+		}
+		if !found {
+			fmt.Println("no plans yet; \"app run\" makes one")
+			return exitOK
+		}
+		planID = latest
+	}
+	planned, err := db.Jobs(ctx, planID)
+	if err != nil {
+		a.log.WithError(err).Error("failed to load jobs")
+		return exitError
+	}
+	if len(planned) == 0 {
+		fmt.Printf("plan %d not found\n", planID)
+		return exitOK
+	}
+
+	counts := make(map[storage.JobStatus]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQ\tKIND\tTARGET\tSTATUS\tATTEMPTS\tLAST ERROR")
+	for _, j := range planned {
+		counts[j.Status]++
+		target := j.Target
+		if j.Page > 0 {
+			target = fmt.Sprintf("%s (page %d)", j.Target, j.Page)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", j.Seq, j.Kind, target, j.Status, j.Attempts, j.LastError)
+	}
+	w.Flush()
+	fmt.Printf("\nplan %d: %d pending, %d done, %d skipped, %d failed\n", planID,
+		counts[storage.JobPending]+counts[storage.JobRunning], counts[storage.JobDone], counts[storage.JobSkipped], counts[storage.JobFailed])
+	return exitOK
+}
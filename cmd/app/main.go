@@ -2,77 +2,360 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"linkedin-automation-poc/internal/auth"
-	"linkedin-automation-poc/internal/browser"
+	"github.com/sirupsen/logrus"
+
 	"linkedin-automation-poc/internal/config"
-	"linkedin-automation-poc/internal/connect"
 	"linkedin-automation-poc/internal/logger"
-	"linkedin-automation-poc/internal/messaging"
-	"linkedin-automation-poc/internal/search"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/runlock"
 	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// Exit codes let cron wrappers tell an expected stop from a failure.
+const (
+	exitOK = 0
+	// exitError is any unexpected failure, including a crash.
+	exitError = 1
+	// exitUsage covers bad flags or arguments and an invalid config file.
+	exitUsage = 2
+	// exitLimitReached means a daily or weekly limit stopped the run with work left.
+	exitLimitReached = 3
+	// exitCheckpoint means LinkedIn showed a checkpoint that needs a human.
+	exitCheckpoint = 4
+	// exitLoginRequired means there is no valid session and no way to log in.
+	exitLoginRequired = 5
+	// exitCooldown means sending is paused after a LinkedIn warning or
+	// repeated checkpoints.
+	exitCooldown = 6
+	// exitLocked means another run holds the run lock, e.g. an overlapping
+	// cron job; nothing was done.
+	exitLocked = 7
+	// exitInterrupted means Ctrl+C or SIGTERM stopped the run; a "run" plan
+	// resumes where it stopped.
+	exitInterrupted = 8
 )
 
-// main wires together config, logging, browser, storage and a small demo flow.
+// exitCodeFor maps a workflow error to the process exit code. An interrupt
+// comes first, whatever the workflow was doing when it came. A cooldown is
+// next: a repeated checkpoint starts one, and it must exit the same way as
+// the runs it then stops.
+func exitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, workflow.ErrCooldown):
+		return exitCooldown
+	case errors.Is(err, workflow.ErrCheckpoint):
+		return exitCheckpoint
+	case errors.Is(err, workflow.ErrLoginRequired):
+		return exitLoginRequired
+	case errors.Is(err, workflow.ErrLimitReached):
+		return exitLimitReached
+	default:
+		return exitError
+	}
+}
+
+// app carries what every command needs once global flags are parsed.
+type app struct {
+	configPath string
+	// overrides are the -set (and -dsn) values layered over the file.
+	overrides []string
+	cfg       *config.Config
+	// cfgErr is set when the config failed to load; only commands with
+	// loadsOwnConfig run in that case.
+	cfgErr error
+	log    *logrus.Logger
+	// runErr is the error that ended the command, kept for the run
+	// history.
+	runErr error
+	// runID names the runs row of a browser command and is logged with
+	// every entry once the row exists; it is empty otherwise.
+	runID string
+}
+
+type command struct {
+	name    string
+	summary string
+	// loadsOwnConfig commands run even when the config file is invalid.
+	loadsOwnConfig bool
+	// recordsRun commands drive the browser; their outcome is stored in the
+	// run history, whose latest entry "app status" shows.
+	recordsRun bool
+	run        func(ctx context.Context, a *app, args []string) int
+}
+
+var commands = []command{
+	{name: "login", summary: "log in and save the session cookies", recordsRun: true, run: cmdLogin},
+	{name: "logout", summary: "securely delete the saved session", run: cmdLogout},
+	{name: "search", summary: "search for people and queue them as candidates", recordsRun: true, run: cmdSearch},
+	{name: "connect", summary: "send invitations to approved candidates", recordsRun: true, run: cmdConnect},
+	{name: "followup", summary: "sync accepted connections and send follow-up messages", recordsRun: true, run: cmdFollowup},
+	{name: "run", summary: "login, search, connect and followup in one go", recordsRun: true, run: cmdRun},
+	{name: "status", summary: "show today's activity and remaining quota", run: cmdStatus},
+	{name: "jobs", summary: "list the planned jobs of the latest run", run: cmdJobs},
+	{name: "runs", summary: "list past runs or show what one of them did", run: cmdRuns},
+	{name: "cooldown", summary: "show or clear the pause on sending after a LinkedIn warning", run: cmdCooldown},
+	{name: "export", summary: "export stored records as CSV or JSON Lines", run: cmdExport},
+	{name: "import", summary: "import records from an export without duplicating them", run: cmdImport},
+	{name: "config", summary: "validate the config or print the effective values", loadsOwnConfig: true, run: cmdConfig},
+	{name: "doctor", summary: "check config, database, browser and site reachability", loadsOwnConfig: true, run: cmdDoctor},
+	{name: "candidates", summary: "review search results before they are contacted", run: cmdCandidates},
+	{name: "dnc", summary: "manage the do-not-contact list", run: cmdDNC},
+	{name: "migrate", summary: "show or apply schema migrations", run: cmdMigrate},
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "usage: app [global flags] <command> [command flags]")
+	fmt.Fprintln(out, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(out, "\nglobal flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(out, "\nexit codes: 0 ok, 1 error, 2 usage/config, 3 limit reached, 4 checkpoint, 5 login required, 6 cooldown, 7 another run in progress, 8 interrupted")
+}
+
+// main wires together config, logging and the subcommands.
 // This is an educational proof-of-concept only – DO NOT use it for production
 // scraping or to violate LinkedIn's Terms of Service.
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	log := logger.New()
 
-	cfg, err := config.Load("config.yaml")
-	if err != nil {
-		log.WithError(err).Fatal("failed to load config")
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to the YAML config file")
+	dsn := fs.String("dsn", "", "SQLite DSN; overrides database.dsn and SQLITE_DSN")
+	logLevel := fs.String("log-level", "", "log level (debug, info, warn, error); overrides LOG_LEVEL")
+	var overrides overrideList
+	fs.Var(&overrides, "set", "override a config value, e.g. -set connect.daily_limit=3; repeatable, wins over the file and LIAUTO_* variables")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
 	}
 
-	br, err := browser.New(ctx, cfg.Browser, log)
-	if err != nil {
-		log.WithError(err).Fatal("failed to initialise browser")
+	if *logLevel != "" {
+		level, err := logrus.ParseLevel(*logLevel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -log-level %q\n", *logLevel)
+			return exitUsage
+		}
+		log.SetLevel(level)
+	}
+
+	// Without a command, run the whole pipeline as earlier versions did.
+	name, rest := "run", fs.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
 	}
-	defer br.MustClose()
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(fs)
+		return exitUsage
+	}
+
+	if *dsn != "" {
+		overrides = append(overrides, "database.dsn="+*dsn)
+	}
+	a := &app{configPath: *configPath, overrides: overrides, log: log}
+	a.cfg, a.cfgErr = config.Load(*configPath, overrides...)
+	if a.cfgErr != nil && !cmd.loadsOwnConfig {
+		a.logConfigError()
+		return exitUsage
+	}
+	if a.cfgErr == nil {
+		// Validation has checked the URL; links on its host are profiles.
+		_ = profileurl.UseBaseURL(a.cfg.LinkedIn.BaseURL)
+	}
+
+	if cmd.recordsRun {
+		return a.runLocked(ctx, cmd, rest)
+	}
+	return a.runCommand(ctx, cmd, rest)
+}
 
-	db, err := storage.New(cfg.Database.DSN, log)
+// runLocked runs a browser command while holding the run lock, so two of
+// them never share the database and its limits, and records its outcome in
+// the run history. A run refused by the lock did nothing and is not recorded.
+// The command is canceled if another process takes the lock over.
+func (a *app) runLocked(ctx context.Context, cmd *command, args []string) int {
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
 	if err != nil {
-		log.WithError(err).Fatal("failed to initialise storage")
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
 	}
 	defer db.Close()
 
-	email := os.Getenv("LINKEDIN_EMAIL")
-	password := os.Getenv("LINKEDIN_PASSWORD")
-	if email == "" || password == "" {
-		log.Fatal("LINKEDIN_EMAIL and LINKEDIN_PASSWORD must be set in the environment")
+	lock, err := runlock.Acquire(ctx, db, cmd.name, a.log)
+	var locked *storage.RunLockedError
+	if errors.As(err, &locked) {
+		a.log.WithField("holder", locked.Holder.String()).Error("another run is in progress, not starting")
+		return exitLocked
 	}
+	if err != nil {
+		a.log.WithError(err).Error("failed to take the run lock")
+		return exitError
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			a.log.WithError(err).Warn("failed to release the run lock")
+		}
+	}()
 
-	if err := auth.Login(ctx, br, db, email, password, log); err != nil {
-		log.WithError(err).Fatal("login failed")
+	if a.startRun(ctx, db, cmd.name, args, time.Now()) {
+		ctx = storage.WithRunID(ctx, a.runID)
 	}
 
-	// Simple demo: run a single search and attempt a few connection requests.
-	profiles, err := search.SearchProfiles(ctx, br, cfg.Search, log)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	code := a.runCommand(ctx, cmd, args)
+	select {
+	case <-lock.Lost():
+		a.runErr = errors.New("run lock taken over by another process")
+		if code == exitOK || code == exitInterrupted {
+			code = exitError
+		}
+	default:
+	}
+	a.finishRun(db, code)
+	return code
+}
+
+// startRun adds the runs row of a browser command and, once it exists, logs
+// its ID with every entry. Failing to is only logged: the run history is not
+// worth refusing to run over.
+func (a *app) startRun(ctx context.Context, db *storage.Storage, name string, args []string, started time.Time) bool {
+	id, err := newRunID()
+	if err != nil {
+		a.log.WithError(err).Warn("failed to make a run ID")
+		return false
+	}
+	hash, err := a.cfg.Hash()
 	if err != nil {
-		log.WithError(err).Error("search failed, but continuing with any profiles found")
-		profiles = []string{} // Continue with empty list instead of crashing
+		a.log.WithError(err).Warn("failed to hash the config")
+	}
+	run := storage.Run{ID: id, Command: name, Args: strings.Join(args, " "), ConfigHash: hash, StartedAt: started}
+	if err := db.StartRun(ctx, run); err != nil {
+		a.log.WithError(err).Warn("failed to record the run start")
+		return false
 	}
+	a.runID = id
+	logger.AddField(a.log, "run_id", id)
+	return true
+}
 
-	if len(profiles) > 0 {
-		if err := connect.SendConnectionRequests(ctx, br, db, cfg.Connect, profiles, log); err != nil {
-			log.WithError(err).Error("connection workflow encountered errors, but continuing")
-		}
-	} else {
-		log.Warn("no profiles found to connect with")
+// finishRun records how the run ended and what it did in its runs row, if
+// it has one.
+func (a *app) finishRun(db *storage.Storage, code int) {
+	if a.runID == "" {
+		return
+	}
+	var msg string
+	if a.runErr != nil {
+		msg = a.runErr.Error()
+	}
+	// The run context may already be canceled (Ctrl+C); record it anyway.
+	if err := db.FinishRun(context.Background(), a.runID, code, outcomeNames[code], msg, time.Now()); err != nil {
+		a.log.WithError(err).Warn("failed to record the run outcome in the run history")
 	}
+}
 
-	// Demo: send follow‑up messages to newly accepted connections.
-	if err := messaging.SendFollowUps(ctx, br, db, cfg.Messaging, log); err != nil {
-		log.WithError(err).Error("follow‑up messaging encountered errors, but continuing")
+// logConfigError logs why the config failed to load, one line per problem.
+func (a *app) logConfigError() {
+	var verr config.ValidationError
+	if !errors.As(a.cfgErr, &verr) {
+		a.log.WithError(a.cfgErr).WithField("path", a.configPath).Error("failed to load config")
+		return
+	}
+	for _, fe := range verr {
+		a.log.WithField("setting", fe.Path).Error(fe.Message)
 	}
+	a.log.WithField("path", a.configPath).WithField("problems", len(verr)).Error("invalid config")
+}
+
+// runCommand runs cmd, turning a panic into exitError so a crash is still
+// recorded in the run history.
+func (a *app) runCommand(ctx context.Context, cmd *command, args []string) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.runErr = fmt.Errorf("panic: %v", r)
+			a.log.WithField("panic", r).Error("crashed")
+			code = exitError
+		}
+	}()
+	return cmd.run(ctx, a, args)
+}
+
+// outcomeNames describe exit codes in the run history.
+var outcomeNames = map[int]string{
+	exitOK:            "ok",
+	exitError:         "error",
+	exitUsage:         "usage",
+	exitLimitReached:  "limit_reached",
+	exitCheckpoint:    "checkpoint",
+	exitLoginRequired: "login_required",
+	exitCooldown:      "cooldown",
+	exitLocked:        "locked",
+	exitInterrupted:   "interrupted",
+}
 
-	// Give async logging/browsing a moment to settle in this simple demo.
-	time.Sleep(2 * time.Second)
+// overrideList is a repeatable flag kept verbatim, since override values may
+// contain commas.
+type overrideList []string
+
+func (l *overrideList) String() string { return strings.Join(*l, " ") }
+
+func (l *overrideList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// stringList is a repeatable string flag; comma-separated values are split.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
 }
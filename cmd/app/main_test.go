@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("boom"), exitError},
+		{fmt.Errorf("connect: %w", workflow.ErrLimitReached), exitLimitReached},
+		{fmt.Errorf("visit: %w", workflow.ErrLoginRequired), exitLoginRequired},
+		{fmt.Errorf("%w after 10m0s", auth.ErrLoginTimeout), exitLoginRequired},
+		{fmt.Errorf("visit: %w: not resolved within 5m", workflow.ErrCheckpoint), exitCheckpoint},
+		{fmt.Errorf("connect: %w until tomorrow", workflow.ErrCooldown), exitCooldown},
+		// A repeated checkpoint starts a cooldown and exits like one.
+		{fmt.Errorf("%w: 2 checkpoints within 24h: %w", workflow.ErrCheckpoint, workflow.ErrCooldown), exitCooldown},
+		{fmt.Errorf("connect: %w", context.Canceled), exitInterrupted},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRunLocked(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(statusConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dsn := "file:" + filepath.Join(dir, "test.db")
+	cfg, err := config.Load(path, "database.dsn="+dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	db, err := storage.New(dsn, quiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	a := &app{cfg: cfg, log: log}
+	ran := 0
+	cmd := &command{name: "connect", recordsRun: true, run: func(ctx context.Context, a *app, args []string) int {
+		ran++
+		a.log.Info("working")
+		return exitOK
+	}}
+
+	// Another process holds the lock: nothing runs and nothing is recorded.
+	now := time.Now()
+	other := storage.RunLockHolder{PID: 1, Host: "other", Command: "run", AcquiredAt: now, HeartbeatAt: now}
+	if _, err := db.AcquireRunLock(ctx, "other", other, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if code := a.runLocked(ctx, cmd, nil); code != exitLocked || ran != 0 {
+		t.Errorf("runLocked while locked = %d, ran %d times; want %d without running", code, ran, exitLocked)
+	}
+	if runs, err := db.Runs(ctx, 0); err != nil || len(runs) != 0 {
+		t.Errorf("runs after a locked run = %+v, %v; want none", runs, err)
+	}
+	if strings.Contains(out.String(), "run_id") {
+		t.Errorf("a run without a runs row logged a run_id:\n%s", out.String())
+	}
+
+	if err := db.ReleaseRunLock(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := a.runLocked(ctx, cmd, []string{"-max", "3"}); code != exitOK || ran != 1 {
+		t.Errorf("runLocked = %d, ran %d times; want %d after running once", code, ran, exitOK)
+	}
+	runs, err := db.Runs(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != a.runID || runs[0].Command != "connect" || runs[0].Args != "-max 3" || runs[0].Outcome != "ok" {
+		t.Fatalf("runs = %+v, want one ok connect run %s", runs, a.runID)
+	}
+	if !strings.Contains(out.String(), "run_id="+a.runID) {
+		t.Errorf("log lacks run_id=%s:\n%s", a.runID, out.String())
+	}
+	if _, found, err := db.RunLock(ctx); err != nil || found {
+		t.Errorf("run lock still held after the run (%v)", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+// cmdMigrate implements "migrate status" and "migrate up".
+func cmdMigrate(ctx context.Context, a *app, args []string) int {
+	if len(args) != 1 || (args[0] != "status" && args[0] != "up") {
+		fmt.Fprintln(os.Stderr, "usage: app migrate status|up")
+		return exitUsage
+	}
+
+	db, err := storage.Open(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to open storage")
+		return exitError
+	}
+	defer db.Close()
+
+	if args[0] == "up" {
+		if err := db.Migrate(ctx); err != nil {
+			a.log.WithError(err).Error("migration failed")
+			return exitError
+		}
+	}
+
+	states, err := db.MigrationStatus(ctx)
+	if err != nil {
+		a.log.WithError(err).Error("failed to read migration status")
+		return exitError
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range states {
+		status, appliedAt := "pending", ""
+		if st.Applied {
+			status, appliedAt = "applied", st.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, status, appliedAt)
+	}
+	w.Flush()
+	return exitOK
+}
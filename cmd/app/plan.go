@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/connect"
+	"linkedin-automation-poc/internal/jobs"
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/network"
+	"linkedin-automation-poc/internal/search"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// runPlan runs "app run" from the job queue: it resumes the unfinished plan
+// of an earlier run or, if there is none (or fresh is set), plans a new one,
+// and works through it. A new plan searches every keyword page by page,
+// invites the approved candidates not yet invited, syncs connections and
+// scans for opt-out replies; the sync adds a follow-up job for everyone who
+// accepted.
+func (a *app) runPlan(ctx context.Context, s *session, fresh bool) error {
+	planID, found, err := s.db.UnfinishedPlan(ctx)
+	if err != nil {
+		return fmt.Errorf("load plan: %w", err)
+	}
+	if found && fresh {
+		for _, kind := range []storage.JobKind{storage.JobSearchPage, storage.JobConnect, storage.JobSyncConnections, storage.JobScanOptOuts, storage.JobFollowUp} {
+			if _, err := s.db.SkipJobs(ctx, planID, kind, "", "abandoned by run --fresh", time.Now()); err != nil {
+				return fmt.Errorf("abandon plan: %w", err)
+			}
+		}
+		a.log.WithField("plan", planID).Info("abandoned unfinished plan")
+		found = false
+	}
+	if found {
+		a.log.WithField("plan", planID).Info("resuming unfinished plan; search keywords are those it was planned with")
+	} else {
+		planned, err := a.planJobs(ctx, s.db)
+		if err != nil {
+			return fmt.Errorf("plan run: %w", err)
+		}
+		if planID, err = s.db.CreatePlan(ctx, planned, time.Now()); err != nil {
+			return fmt.Errorf("store plan: %w", err)
+		}
+		a.log.WithField("plan", planID).WithField("jobs", len(planned)).Info("planned run")
+	}
+
+	h := &planHandlers{a: a, s: s}
+	w := jobs.NewWorker(s.db, a.cfg.Jobs, map[storage.JobKind]jobs.Handler{
+		storage.JobSearchPage:      h.searchPage,
+		storage.JobConnect:         h.connect,
+		storage.JobSyncConnections: h.syncConnections,
+		storage.JobScanOptOuts:     h.scanOptOuts,
+		storage.JobFollowUp:        h.followUp,
+	}, a.log)
+	return w.Run(ctx, planID)
+}
+
+// planJobs lists the jobs of a new plan.
+func (a *app) planJobs(ctx context.Context, db *storage.Storage) ([]storage.Job, error) {
+	var planned []storage.Job
+	for _, kw := range a.cfg.Search.Keywords {
+		for page := 1; page <= a.cfg.Search.MaxPages; page++ {
+			planned = append(planned, storage.Job{Kind: storage.JobSearchPage, Target: kw, Page: page})
+		}
+	}
+	approved, err := db.Candidates(ctx, storage.CandidateApproved)
+	if err != nil {
+		return nil, fmt.Errorf("load approved candidates: %w", err)
+	}
+	for _, c := range approved {
+		st, found, err := db.RequestStatusFor(ctx, c.ProfileURL)
+		if err != nil {
+			return nil, err
+		}
+		if found && st.Settled() {
+			continue
+		}
+		planned = append(planned, storage.Job{Kind: storage.JobConnect, Target: c.ProfileURL})
+	}
+	return append(planned, storage.Job{Kind: storage.JobSyncConnections}, storage.Job{Kind: storage.JobScanOptOuts}), nil
+}
+
+// planHandlers run the jobs of a plan in a session. The per-run caps are
+// counted here, since every job is a separate workflow call.
+type planHandlers struct {
+	a *app
+	s *session
+	// invited and messaged count what this process sent.
+	invited, messaged int
+}
+
+func (h *planHandlers) searchPage(ctx context.Context, job storage.Job) error {
+	results, st, err := search.SearchPage(ctx, h.s.drv, h.s.db, h.s.guard, h.a.cfg.LinkedIn.BaseURL, h.a.cfg.Search, job.Target, job.Page, nil, nil, h.a.log)
+	if err != nil {
+		return err
+	}
+	h.a.saveCandidates(ctx, h.s.db, results)
+	if st.Last {
+		if _, err := h.s.db.SkipJobs(ctx, job.PlanID, storage.JobSearchPage, job.Target, "no more result pages", time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connect invites one candidate. An attempt that ends in a failed request
+// is a failed job, so it is retried.
+func (h *planHandlers) connect(ctx context.Context, job storage.Job) error {
+	cfg := h.a.cfg.Connect
+	if cfg.MaxPerRun > 0 && h.invited >= cfg.MaxPerRun {
+		return h.skipRest(ctx, job, "per-run connect cap reached")
+	}
+	c, found, err := h.s.db.CandidateFor(ctx, job.Target)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return jobs.Skip("no longer in the candidate queue")
+	}
+	if err := connect.SendConnectionRequests(ctx, h.s.drv, h.s.db, h.s.guard, h.s.brk, h.s.quota, h.a.cfg.LinkedIn.BaseURL, cfg, []storage.Candidate{c}, nil, h.a.log); err != nil {
+		return err
+	}
+	switch st, _, err := h.s.db.RequestStatusFor(ctx, c.ProfileURL); {
+	case err != nil:
+		return err
+	case st == storage.RequestSent:
+		h.invited++
+	case st == storage.RequestFailed:
+		return errors.New("invitation failed; see sent_requests for the reason")
+	}
+	return nil
+}
+
+// syncConnections records who accepted and adds a follow-up job for each of
+// them. A failed sync only means the follow-ups use previously synced data.
+func (h *planHandlers) syncConnections(ctx context.Context, job storage.Job) error {
+	if _, err := network.SyncConnections(ctx, h.s.drv, h.s.db, h.s.guard, h.a.cfg.LinkedIn.BaseURL, h.a.log); err != nil {
+		var lost *auth.SessionError
+		if errors.As(err, &lost) {
+			return fmt.Errorf("sync connections: %w", err)
+		}
+		h.a.log.WithError(err).Error("connection sync failed, follow-ups will use previously synced data")
+	}
+	accepted, err := h.s.db.AcceptedInvitations(ctx)
+	if err != nil {
+		return err
+	}
+	// A sync resumed after a crash may have added some already.
+	existing, err := h.s.db.Jobs(ctx, job.PlanID)
+	if err != nil {
+		return err
+	}
+	planned := make(map[string]bool)
+	for _, j := range existing {
+		if j.Kind == storage.JobFollowUp {
+			planned[j.Target] = true
+		}
+	}
+	var add []storage.Job
+	for _, u := range accepted {
+		if !planned[u] {
+			add = append(add, storage.Job{Kind: storage.JobFollowUp, Target: u})
+		}
+	}
+	return h.s.db.AppendJobs(ctx, job.PlanID, add, time.Now())
+}
+
+// scanOptOuts runs before the follow-ups, which the sync appends after it.
+func (h *planHandlers) scanOptOuts(ctx context.Context, job storage.Job) error {
+	return h.a.scanOptOuts(ctx, h.s)
+}
+
+func (h *planHandlers) followUp(ctx context.Context, job storage.Job) error {
+	cfg := h.a.cfg.Messaging
+	if cfg.MaxPerRun > 0 && h.messaged >= cfg.MaxPerRun {
+		return h.skipRest(ctx, job, "per-run messaging cap reached")
+	}
+	started := time.Now()
+	if err := messaging.SendFollowUps(ctx, h.s.drv, h.s.db, h.s.guard, h.s.brk, h.s.quota, h.a.cfg.LinkedIn.BaseURL, cfg, []string{job.Target}, nil, h.a.log); err != nil {
+		return err
+	}
+	if last, found, err := h.s.db.LastMessageAt(ctx, job.Target, messaging.FollowUpType); err == nil && found && !last.Before(started.Truncate(time.Second)) {
+		h.messaged++
+	}
+	return nil
+}
+
+// skipRest skips job and the other pending jobs of its kind.
+func (h *planHandlers) skipRest(ctx context.Context, job storage.Job, reason string) error {
+	n, err := h.s.db.SkipJobs(ctx, job.PlanID, job.Kind, "", reason, time.Now())
+	if err != nil {
+		return err
+	}
+	h.a.log.WithField("kind", job.Kind).WithField("skipped", n+1).Info(reason)
+	return jobs.Skip(reason)
+}
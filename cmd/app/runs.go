@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+const runsUsage = `usage:
+  app runs list [-n N]
+  app runs show <id>`
+
+// newRunID returns a random ID for the runs row of a browser command. It is
+// logged with every entry of the run and ties the row to the invitations and
+// messages the run wrote.
+func newRunID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// cmdRuns lists the recorded runs or shows one of them with what it sent.
+func cmdRuns(ctx context.Context, a *app, args []string) int {
+	var limit *int
+	switch {
+	case len(args) > 0 && args[0] == "list":
+		fs := flag.NewFlagSet("runs list", flag.ContinueOnError)
+		limit = fs.Int("n", 20, "show this many of the latest runs; 0 for all")
+		if code, ok := parseFlags(fs, args[1:]); !ok {
+			return code
+		}
+	case len(args) == 2 && args[0] == "show" && args[1] != "":
+	default:
+		fmt.Fprintln(os.Stderr, runsUsage)
+		return exitUsage
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	if limit == nil {
+		return showRun(ctx, a, db, args[1])
+	}
+	runs, err := db.Runs(ctx, *limit)
+	if err != nil {
+		a.log.WithError(err).Error("failed to load runs")
+		return exitError
+	}
+	if len(runs) == 0 {
+		fmt.Println("no runs yet")
+		return exitOK
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCOMMAND\tSTARTED\tTOOK\tOUTCOME\tFOUND\tINVITED\tFAILED\tMESSAGED")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n", r.ID, r.Command, r.StartedAt.Local().Format("2006-01-02 15:04"),
+			runTook(r), runOutcome(r), r.Found, r.Invited, r.InviteFailed, r.Messaged)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// showRun prints run id, which may be a unique prefix, and the invitations
+// and messages it wrote.
+func showRun(ctx context.Context, a *app, db *storage.Storage, id string) int {
+	r, found, err := db.FindRun(ctx, id)
+	if err != nil {
+		a.log.WithError(err).Error("failed to load run")
+		return exitError
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "no run %q\n", id)
+		return exitUsage
+	}
+	requests, err := db.RunRequests(ctx, r.ID)
+	if err != nil {
+		a.log.WithError(err).Error("failed to load the run's invitations")
+		return exitError
+	}
+	messages, err := db.RunMessages(ctx, r.ID)
+	if err != nil {
+		a.log.WithError(err).Error("failed to load the run's messages")
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "run\t%s\n", r.ID)
+	fmt.Fprintf(w, "command\t%s %s\n", r.Command, r.Args)
+	fmt.Fprintf(w, "config hash\t%s\n", r.ConfigHash)
+	fmt.Fprintf(w, "started\t%s\n", r.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	if r.Finished() {
+		fmt.Fprintf(w, "finished\t%s (took %s)\n", r.FinishedAt.Local().Format("2006-01-02 15:04:05"), runTook(r))
+		fmt.Fprintf(w, "outcome\t%s (exit code %d)\n", r.Outcome, r.ExitCode)
+	} else {
+		fmt.Fprintf(w, "outcome\t%s\n", runOutcome(r))
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "error\t%s\n", r.Error)
+	}
+	fmt.Fprintf(w, "counts\t%d found, %d invited, %d invitations failed, %d messaged\n", r.Found, r.Invited, r.InviteFailed, r.Messaged)
+	w.Flush()
+
+	if len(requests) > 0 {
+		fmt.Println("\ninvitations:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PROFILE\tSTATUS\tREASON\tUPDATED")
+		for _, req := range requests {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", req.ProfileURL, req.Status, req.Reason, req.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	}
+	if len(messages) > 0 {
+		fmt.Println("\nmessages:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PROFILE\tTYPE\tSTATUS\tSENT")
+		for _, m := range messages {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.ProfileURL, m.MessageType, m.Status, m.SentAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	}
+	return exitOK
+}
+
+func runTook(r storage.Run) string {
+	if !r.Finished() {
+		return "-"
+	}
+	return r.FinishedAt.Sub(r.StartedAt).Round(time.Second).String()
+}
+
+// runOutcome is the outcome of a finished run. One that never recorded an
+// end either crashed or is still going.
+func runOutcome(r storage.Run) string {
+	if !r.Finished() {
+		return "unfinished"
+	}
+	return r.Outcome
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/quota"
+	"linkedin-automation-poc/internal/runlock"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// activity is how much of one action's quota has been used. A WeeklyLimit of
+// 0 means no weekly cap.
+type activity struct {
+	Today       int `json:"today"`
+	ThisWeek    int `json:"this_week"`
+	DailyLimit  int `json:"daily_limit"`
+	WeeklyLimit int `json:"weekly_limit"`
+	// Remaining is how many more the daily and weekly limits allow now.
+	Remaining int `json:"remaining"`
+	// NextFree is when the next slot frees up, once none remain.
+	NextFree *time.Time `json:"next_free,omitempty"`
+}
+
+func newActivity(u quota.Usage) activity {
+	a := activity{Today: u.Today, ThisWeek: u.ThisWeek, DailyLimit: u.Limits.Daily, WeeklyLimit: u.Limits.Weekly, Remaining: u.Remaining()}
+	if next := u.NextFree(); a.Remaining == 0 && !next.IsZero() {
+		next = next.UTC()
+		a.NextFree = &next
+	}
+	return a
+}
+
+type cooldownStatus struct {
+	Active bool       `json:"active"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// lastRunStatus is the most recent browser command that ran to an end.
+type lastRunStatus struct {
+	RunID      string    `json:"run_id"`
+	Command    string    `json:"command"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	ExitCode   int       `json:"exit_code"`
+	Outcome    string    `json:"outcome"`
+	// Error is the message of the error that ended the run, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// runningStatus is the holder of the run lock.
+type runningStatus struct {
+	Command string    `json:"command"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Since   time.Time `json:"since"`
+	// Stale means the holder stopped refreshing the lock, probably because
+	// it crashed; the next run takes the lock over.
+	Stale bool `json:"stale"`
+}
+
+// statusReport is what "app status" prints, as a table or as JSON.
+type statusReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Invitations activity  `json:"invitations"`
+	FollowUps   activity  `json:"follow_ups"`
+	// AwaitingAcceptance counts sent and pending invitations.
+	AwaitingAcceptance int                             `json:"awaiting_acceptance"`
+	Accepted           int                             `json:"accepted"`
+	Candidates         map[storage.CandidateStatus]int `json:"candidates"`
+	LastRun            *lastRunStatus                  `json:"last_run"`
+	Running            *runningStatus                  `json:"running,omitempty"`
+	Cooldown           cooldownStatus                  `json:"cooldown"`
+}
+
+func cmdStatus(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *asJSON {
+		// Keep stdout parseable; migration notices and errors go to stderr.
+		a.log.SetOutput(os.Stderr)
+	}
+
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		a.log.WithError(err).Error("failed to initialise storage")
+		return exitError
+	}
+	defer db.Close()
+
+	st, err := a.collectStatus(ctx, db, time.Now())
+	if err != nil {
+		a.log.WithError(err).Error("failed to collect status")
+		return exitError
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(st); err != nil {
+			return exitError
+		}
+		return exitOK
+	}
+	writeStatusTable(os.Stdout, st)
+	return exitOK
+}
+
+// collectStatus gathers activity counts and run state. Days and weeks are
+// counted as the workflows' limits count them, per the quota settings.
+func (a *app) collectStatus(ctx context.Context, db *storage.Storage, now time.Time) (*statusReport, error) {
+	q, err := quota.New(db, a.cfg.Quota)
+	if err != nil {
+		return nil, err
+	}
+	invites, err := q.Usage(ctx, storage.QuotaConnect, quota.Limits{Daily: a.cfg.Connect.DailyLimit, Weekly: a.cfg.Connect.WeeklyLimit}, now)
+	if err != nil {
+		return nil, err
+	}
+	followUps, err := q.Usage(ctx, messaging.FollowUpType, quota.Limits{Daily: a.cfg.Messaging.DailyLimit, Weekly: a.cfg.Messaging.WeeklyLimit}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := db.CountRequestsByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count requests: %w", err)
+	}
+	candidates, err := db.CountCandidatesByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count candidates: %w", err)
+	}
+
+	st := &statusReport{
+		GeneratedAt:        now.UTC(),
+		Invitations:        newActivity(invites),
+		FollowUps:          newActivity(followUps),
+		AwaitingAcceptance: requests[storage.RequestSent] + requests[storage.RequestPending],
+		Accepted:           requests[storage.RequestAccepted],
+		Candidates:         candidates,
+	}
+
+	// Runs refused for bad usage or by the run lock did nothing.
+	run, found, err := db.LastRun(ctx, outcomeNames[exitUsage], outcomeNames[exitLocked])
+	if err != nil {
+		return nil, fmt.Errorf("load last run: %w", err)
+	}
+	if found {
+		st.LastRun = &lastRunStatus{
+			RunID:      run.ID,
+			Command:    run.Command,
+			StartedAt:  run.StartedAt.UTC(),
+			FinishedAt: run.FinishedAt.UTC(),
+			ExitCode:   run.ExitCode,
+			Outcome:    run.Outcome,
+			Error:      run.Error,
+		}
+	}
+	holder, found, err := db.RunLock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load run lock: %w", err)
+	}
+	if found {
+		st.Running = &runningStatus{
+			Command: holder.Command,
+			PID:     holder.PID,
+			Host:    holder.Host,
+			Since:   holder.AcquiredAt.UTC(),
+			Stale:   now.Sub(holder.HeartbeatAt) > runlock.StaleAfter,
+		}
+	}
+	cd, found, err := db.Cooldown(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load cooldown: %w", err)
+	}
+	if found && cd.Active(now) {
+		until := cd.Until.UTC()
+		st.Cooldown = cooldownStatus{Active: true, Until: &until, Reason: cd.Reason}
+	}
+	return st, nil
+}
+
+func writeStatusTable(out io.Writer, st *statusReport) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\tTODAY\tTHIS WEEK\tDAILY LIMIT\tWEEKLY LIMIT\tREMAINING\tNEXT SLOT")
+	for _, row := range []struct {
+		name string
+		a    activity
+	}{{"invitations", st.Invitations}, {"follow-ups", st.FollowUps}} {
+		weekly, next := "-", "now"
+		if row.a.WeeklyLimit > 0 {
+			weekly = fmt.Sprint(row.a.WeeklyLimit)
+		}
+		if row.a.NextFree != nil {
+			next = row.a.NextFree.Local().Format("2006-01-02 15:04")
+		} else if row.a.Remaining == 0 {
+			next = "never (limit is 0)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\t%s\n", row.name, row.a.Today, row.a.ThisWeek, row.a.DailyLimit, weekly, row.a.Remaining, next)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "invitations awaiting acceptance\t%d\n", st.AwaitingAcceptance)
+	fmt.Fprintf(w, "invitations accepted\t%d\n", st.Accepted)
+	fmt.Fprintf(w, "candidates pending review\t%d\n", st.Candidates[storage.CandidatePending])
+	fmt.Fprintf(w, "candidates approved\t%d\n", st.Candidates[storage.CandidateApproved])
+	if r := st.LastRun; r != nil {
+		fmt.Fprintf(w, "last run\t%s %s at %s (took %s), run %s\n", r.Command, r.Outcome,
+			r.FinishedAt.Local().Format("2006-01-02 15:04"), r.FinishedAt.Sub(r.StartedAt).Round(time.Second), r.RunID)
+		if r.Error != "" {
+			fmt.Fprintf(w, "last run error\t%s\n", r.Error)
+		}
+	} else {
+		fmt.Fprintln(w, "last run\tnever")
+	}
+	if r := st.Running; r != nil {
+		state := "running"
+		if r.Stale {
+			state = "stale lock"
+		}
+		fmt.Fprintf(w, "in progress\t%s (pid %d on %s) since %s, %s\n", r.Command, r.PID, r.Host, r.Since.Local().Format("2006-01-02 15:04"), state)
+	}
+	if st.Cooldown.Active {
+		fmt.Fprintf(w, "cooldown\tactive until %s (%s)\n", st.Cooldown.Until.Local().Format("2006-01-02 15:04"), st.Cooldown.Reason)
+	} else {
+		fmt.Fprintln(w, "cooldown\tnone")
+	}
+	w.Flush()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/storage"
+)
+
+const statusConfig = `
+search:
+  keywords: [golang]
+quota:
+  timezone: UTC
+  window: calendar
+connect:
+  daily_limit: 2
+  weekly_limit: 5
+messaging:
+  templates: ["Hi {{FIRST_NAME}}"]
+  daily_limit: 1
+  weekly_limit: 3
+`
+
+func TestCollectStatus(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(statusConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := storage.New("file:"+filepath.Join(dir, "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A Wednesday afternoon; the week started on Monday.
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	invite := func(id string, at time.Time) {
+		t.Helper()
+		url := "https://www.linkedin.com/in/" + id + "/"
+		if err := db.SetRequestStatus(ctx, url, storage.RequestQueued, "", at); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetRequestStatus(ctx, url, storage.RequestSent, "", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invite("monday", monday)
+	invite("alice", now.Add(-2*time.Hour))
+	invite("bob", now.Add(-time.Hour))
+	if _, err := db.MarkRequestAccepted(ctx, "https://www.linkedin.com/in/monday/", monday.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordMessage(ctx, "https://www.linkedin.com/in/monday/", messaging.FollowUpType, storage.MessageSent, monday.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SaveCandidate(ctx, storage.Candidate{ProfileURL: "https://www.linkedin.com/in/carol/", Status: storage.CandidatePending}, monday); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExtendCooldown(ctx, storage.Cooldown{Until: now.Add(48 * time.Hour), Reason: "limit: weekly invitation limit"}); err != nil {
+		t.Fatal(err)
+	}
+	runs := []struct {
+		id      string
+		code    int
+		started time.Time
+	}{
+		{"r1", exitLimitReached, now.Add(-3 * time.Hour)},
+		// Older versions recorded runs refused by the lock; they did nothing
+		// and are not the last run.
+		{"r2", exitLocked, now.Add(-30 * time.Minute)},
+	}
+	for _, r := range runs {
+		if err := db.StartRun(ctx, storage.Run{ID: r.id, Command: "connect", StartedAt: r.started}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.FinishRun(ctx, r.id, r.code, outcomeNames[r.code], "connect: daily limit reached", r.started.Add(10*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := &app{cfg: cfg, log: log}
+	st, err := a.collectStatus(ctx, db, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "generated_at": "2026-03-04T15:00:00Z",
+  "invitations": {
+    "today": 2,
+    "this_week": 3,
+    "daily_limit": 2,
+    "weekly_limit": 5,
+    "remaining": 0,
+    "next_free": "2026-03-05T00:00:00Z"
+  },
+  "follow_ups": {
+    "today": 0,
+    "this_week": 1,
+    "daily_limit": 1,
+    "weekly_limit": 3,
+    "remaining": 1
+  },
+  "awaiting_acceptance": 2,
+  "accepted": 1,
+  "candidates": {
+    "pending": 1
+  },
+  "last_run": {
+    "run_id": "r1",
+    "command": "connect",
+    "started_at": "2026-03-04T12:00:00Z",
+    "finished_at": "2026-03-04T12:10:00Z",
+    "exit_code": 3,
+    "outcome": "limit_reached",
+    "error": "connect: daily limit reached"
+  },
+  "cooldown": {
+    "active": true,
+    "until": "2026-03-06T15:00:00Z",
+    "reason": "limit: weekly invitation limit"
+  }
+}`
+	if string(got) != want {
+		t.Errorf("status JSON =\n%s\nwant\n%s", got, want)
+	}
+
+	var table bytes.Buffer
+	writeStatusTable(&table, st)
+	var lines []string
+	for _, line := range strings.Split(table.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	for _, prefix := range []string{
+		"invitations 2 3 2 5 0 ",
+		"follow-ups 0 1 1 3 1 now",
+		"invitations awaiting acceptance 2",
+		"invitations accepted 1",
+		"candidates pending review 1",
+		"last run connect limit_reached at ",
+		"last run error connect: daily limit reached",
+		"cooldown active until ",
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || strings.HasPrefix(line, prefix)
+		}
+		if !found {
+			t.Errorf("status table lacks a line starting %q:\n%s", prefix, table.String())
+		}
+	}
+}
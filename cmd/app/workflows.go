@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/breaker"
+	"linkedin-automation-poc/internal/browser"
+	"linkedin-automation-poc/internal/connect"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/dryrun"
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/network"
+	"linkedin-automation-poc/internal/quota"
+	"linkedin-automation-poc/internal/search"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// session is a logged-in browser tab plus the database.
+type session struct {
+	br    *rod.Browser
+	drv   driver.Driver
+	db    *storage.Storage
+	guard *auth.Guard
+	brk   *breaker.Breaker
+	quota *quota.Quota
+}
+
+func (s *session) Close() {
+	s.db.Close()
+	s.br.MustClose()
+}
+
+// openSession launches the browser, opens storage and logs in, reusing saved
+// cookies when they are still valid.
+func (a *app) openSession(ctx context.Context) (*session, error) {
+	db, err := storage.New(a.cfg.Database.DSN, a.log)
+	if err != nil {
+		return nil, fmt.Errorf("initialise storage: %w", err)
+	}
+	q, err := quota.New(db, a.cfg.Quota)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	br, drv, err := a.openBrowser(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &session{br: br, drv: drv, db: db, brk: breaker.New(drv, db, a.cfg.Breaker, a.log), quota: q}
+
+	// Credentials are optional: a session saved by "login --interactive"
+	// is enough.
+	email := os.Getenv("LINKEDIN_EMAIL")
+	password := os.Getenv("LINKEDIN_PASSWORD")
+	checkpoints := auth.NewCheckpointHandler(drv, db, a.cfg.Checkpoint, !a.cfg.Browser.Headless, a.log)
+	s.guard = auth.NewGuard(drv, auth.NewSessionStore(a.cfg.Session), checkpoints, a.log)
+	if err := auth.Login(ctx, drv, s.guard, a.cfg.LinkedIn.BaseURL, email, password, a.log); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// keepSession saves the browser's refreshed cookies after a run that ended
+// with the session intact, so the next run does not start from an older
+// copy.
+func (a *app) keepSession(s *session, code int) {
+	if code != exitOK && code != exitLimitReached && code != exitCooldown {
+		return
+	}
+	if err := s.guard.SaveSession(); err != nil {
+		a.log.WithError(err).Warn("failed to save refreshed session cookies")
+	}
+}
+
+// openBrowser launches the browser and opens a tab.
+func (a *app) openBrowser(ctx context.Context) (*rod.Browser, driver.Driver, error) {
+	br, err := browser.New(ctx, a.cfg.Browser, a.log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("initialise browser: %w", err)
+	}
+	drv, err := driver.NewRod(br)
+	if err != nil {
+		br.MustClose()
+		return nil, nil, fmt.Errorf("open browser tab: %w", err)
+	}
+	return br, drv, nil
+}
+
+// exitWith logs a workflow error, if any, and returns its exit code. A
+// limit is an expected stop and logged at info level.
+func (a *app) exitWith(err error, msg string) int {
+	code := exitCodeFor(err)
+	if err == nil {
+		return code
+	}
+	a.runErr = err
+	entry := a.log.WithError(err).WithField("exit_code", code)
+	if code == exitLimitReached {
+		entry.Info(msg)
+	} else {
+		entry.Error(msg)
+	}
+	return code
+}
+
+// dryRunFlags registers --dry-run and --report on a command.
+func dryRunFlags(fs *flag.FlagSet) (dryRun *bool, reportPath *string) {
+	dryRun = fs.Bool("dry-run", false, "visit profiles and render templates without clicking Connect, Send or Message or writing sent_requests/messages")
+	reportPath = fs.String("report", "dry_run_report.json", "where --dry-run writes its JSON report")
+	return dryRun, reportPath
+}
+
+// newReport returns the report a dry run collects its decisions in, or nil
+// for a real run.
+func (a *app) newReport(dryRun bool) *dryrun.Report {
+	if !dryRun {
+		return nil
+	}
+	a.log.Info("dry run: nothing will be sent or recorded")
+	return &dryrun.Report{}
+}
+
+// finishReport saves a dry-run report as JSON and prints it as a table.
+func (a *app) finishReport(report *dryrun.Report, path string) {
+	if report == nil {
+		return
+	}
+	if err := writeReport(report, path); err != nil {
+		a.log.WithError(err).Error("failed to write dry-run report")
+	}
+}
+
+func writeReport(report *dryrun.Report, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("\nDry run: %d entries (JSON report: %s)\n\n", len(report.Entries), path)
+	return report.WriteTable(os.Stdout)
+}
+
+// parseFlags parses a command's flags; when ok is false the command should
+// return code straight away (after -h or a usage error).
+func parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "%s: unexpected arguments %v\n", fs.Name(), fs.Args())
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+func cmdLogin(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	interactive := fs.Bool("interactive", false, "open a visible browser and wait for you to sign in yourself; no credentials needed")
+	timeout := fs.Duration("timeout", 10*time.Minute, "how long --interactive waits for the sign-in")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *interactive {
+		return a.interactiveLogin(ctx, *timeout)
+	}
+	s, err := a.openSession(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer s.Close()
+	a.keepSession(s, exitOK)
+	return exitOK
+}
+
+// interactiveLogin lets the user sign in by hand and saves the session.
+func (a *app) interactiveLogin(ctx context.Context, timeout time.Duration) int {
+	sessions := auth.NewSessionStore(a.cfg.Session)
+	if err := sessions.Ready(); err != nil {
+		return a.exitWith(err, "cannot save a session")
+	}
+	// The user has to see the page to sign in.
+	a.cfg.Browser.Headless = false
+	br, drv, err := a.openBrowser(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer br.MustClose()
+	return a.exitWith(auth.InteractiveLogin(ctx, drv, sessions, a.cfg.LinkedIn.BaseURL, timeout, a.log), "login failed")
+}
+
+// cmdLogout deletes the saved session so the next run has to log in again.
+func cmdLogout(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("logout", flag.ContinueOnError)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	removed, err := auth.NewSessionStore(a.cfg.Session).Delete()
+	for _, path := range removed {
+		a.log.WithField("path", path).Info("deleted saved session")
+	}
+	if err != nil {
+		a.log.WithError(err).Error("failed to delete saved session")
+		return exitError
+	}
+	if len(removed) == 0 {
+		a.log.WithField("path", a.cfg.Session.CookieFile).Info("no saved session")
+	}
+	return exitOK
+}
+
+func cmdSearch(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	var keywords stringList
+	fs.Var(&keywords, "keyword", "search keyword; repeat or comma-separate to search several (default: search.keywords)")
+	max := fs.Int("max", 0, "queue at most this many search results (0 = all)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if len(keywords) > 0 {
+		a.cfg.Search.Keywords = keywords
+	}
+
+	s, err := a.openSession(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer s.Close()
+
+	code := a.exitWith(a.searchAndQueue(ctx, s, *max, nil), "search stopped")
+	a.keepSession(s, code)
+	return code
+}
+
+// searchAndQueue runs the configured search and saves the results as pending
+// candidates for review. A dry run saves nothing and lists the results in
+// report instead.
+func (a *app) searchAndQueue(ctx context.Context, s *session, max int, report *dryrun.Report) error {
+	results, stats, searchErr := search.SearchProfiles(ctx, s.drv, s.db, s.guard, a.cfg.LinkedIn.BaseURL, a.cfg.Search, report, a.log)
+	a.logSearchStats(stats)
+	if max > 0 && len(results) > max {
+		results = results[:max]
+	}
+	if report != nil {
+		a.previewCandidates(ctx, s.db, results, report)
+	} else {
+		a.saveCandidates(ctx, s.db, results)
+	}
+	return searchErr
+}
+
+// logSearchStats sums up the result pages read, so a search that found
+// nobody because every page came back without cards stands out in the log.
+func (a *app) logSearchStats(stats []search.PageStats) {
+	var total search.PageStats
+	empty := 0
+	for _, st := range stats {
+		total.Cards += st.Cards
+		total.Parsed += st.Parsed
+		total.Duplicates += st.Duplicates
+		total.Excluded += st.Excluded
+		if st.Cards == 0 {
+			empty++
+		}
+	}
+	entry := a.log.WithField("pages", len(stats)).
+		WithField("empty_pages", empty).
+		WithField("cards", total.Cards).
+		WithField("parsed", total.Parsed).
+		WithField("duplicates", total.Duplicates).
+		WithField("excluded", total.Excluded)
+	if len(stats) > 0 && empty == len(stats) {
+		entry.Warn("no search result page had any cards - LinkedIn may have changed its markup")
+		return
+	}
+	entry.Info("search result pages read")
+}
+
+// previewCandidates adds the search results a real run would queue for
+// review to report, and skips those already in the queue.
+func (a *app) previewCandidates(ctx context.Context, db *storage.Storage, results []search.SearchResult, report *dryrun.Report) {
+	for _, r := range results {
+		c, found, err := db.CandidateFor(ctx, r.ProfileURL)
+		switch {
+		case err != nil:
+			report.Skip("search", r.ProfileURL, "check candidate queue: "+err.Error())
+		case found:
+			report.Skip("search", r.ProfileURL, "already a candidate ("+string(c.Status)+")")
+		default:
+			report.Add(dryrun.Entry{Workflow: "search", Profile: r.ProfileURL, Action: dryrun.ActionQueue, Text: candidateSummary(r)})
+		}
+	}
+	a.log.WithField("found", len(results)).Info("dry run: search results listed in the report, not saved")
+}
+
+// candidateSummary is the name and headline of a search result.
+func candidateSummary(r search.SearchResult) string {
+	if r.Headline == "" {
+		return r.Name
+	}
+	return r.Name + " – " + r.Headline
+}
+
+// saveCandidates queues search results as pending candidates for review.
+func (a *app) saveCandidates(ctx context.Context, db *storage.Storage, results []search.SearchResult) {
+	added := 0
+	for _, r := range results {
+		isNew, err := db.SaveCandidate(ctx, r.Candidate(), time.Now())
+		if err != nil {
+			a.log.WithError(err).WithField("profile", r.ProfileURL).Warn("failed to save candidate")
+			continue
+		}
+		if isNew {
+			added++
+		}
+	}
+	a.log.WithField("found", len(results)).WithField("new_candidates", added).Info("saved search results for review")
+}
+
+func cmdConnect(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	max := fs.Int("max", a.cfg.Connect.MaxPerRun, "send at most this many invitations in this run (0 = only the daily and weekly limits apply)")
+	var profiles stringList
+	fs.Var(&profiles, "profile", "only invite this approved candidate; repeatable")
+	dryRun, reportPath := dryRunFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	a.cfg.Connect.MaxPerRun = *max
+
+	s, err := a.openSession(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer s.Close()
+
+	report := a.newReport(*dryRun)
+	defer a.finishReport(report, *reportPath)
+	code := a.exitWith(a.connect(ctx, s, profiles, report), "connect stopped")
+	a.keepSession(s, code)
+	return code
+}
+
+// connect invites approved candidates, or only the given profiles when any
+// are named.
+func (a *app) connect(ctx context.Context, s *session, only []string, report *dryrun.Report) error {
+	var targets []storage.Candidate
+	if len(only) > 0 {
+		for _, ref := range only {
+			c, found, err := s.db.CandidateFor(ctx, ref)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("%s is not in the candidate queue; run search first", ref)
+			}
+			targets = append(targets, c)
+		}
+	} else {
+		approved, err := s.db.Candidates(ctx, storage.CandidateApproved)
+		if err != nil {
+			return fmt.Errorf("load approved candidates: %w", err)
+		}
+		targets = approved
+	}
+	if len(targets) == 0 {
+		a.log.Warn("no approved candidates to connect with - review them with \"app candidates review\"")
+		return nil
+	}
+	return connect.SendConnectionRequests(ctx, s.drv, s.db, s.guard, s.brk, s.quota, a.cfg.LinkedIn.BaseURL, a.cfg.Connect, targets, report, a.log)
+}
+
+func cmdFollowup(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("followup", flag.ContinueOnError)
+	max := fs.Int("max", a.cfg.Messaging.MaxPerRun, "send at most this many follow-ups in this run (0 = only the daily and weekly limits apply)")
+	var profiles stringList
+	fs.Var(&profiles, "profile", "only follow up with this profile; repeatable")
+	dryRun, reportPath := dryRunFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	a.cfg.Messaging.MaxPerRun = *max
+
+	s, err := a.openSession(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer s.Close()
+
+	report := a.newReport(*dryRun)
+	defer a.finishReport(report, *reportPath)
+	code := a.exitWith(a.followup(ctx, s, profiles, report), "follow-up stopped")
+	a.keepSession(s, code)
+	return code
+}
+
+// followup records who accepted and who replied asking not to be contacted,
+// then follows up only with those who accepted. Syncing updates
+// sent_requests and the scan opens threads, so a dry run skips both and
+// relies on previously stored data.
+func (a *app) followup(ctx context.Context, s *session, only []string, report *dryrun.Report) error {
+	if report != nil {
+		a.log.Info("dry run: skipping connection sync and opt-out scan, follow-ups use previously stored data")
+	} else {
+		if _, err := network.SyncConnections(ctx, s.drv, s.db, s.guard, a.cfg.LinkedIn.BaseURL, a.log); err != nil {
+			var lost *auth.SessionError
+			if errors.As(err, &lost) {
+				return fmt.Errorf("followup: sync connections: %w", err)
+			}
+			a.log.WithError(err).Error("connection sync failed, follow-ups will use previously synced data")
+		}
+		if err := a.scanOptOuts(ctx, s); err != nil {
+			return fmt.Errorf("followup: %w", err)
+		}
+	}
+	return messaging.SendFollowUps(ctx, s.drv, s.db, s.guard, s.brk, s.quota, a.cfg.LinkedIn.BaseURL, a.cfg.Messaging, only, report, a.log)
+}
+
+// scanOptOuts adds people who replied asking not to be contacted to the
+// do-not-contact list. Only a lost session or an interrupt is returned; any
+// other failure just leaves the list as it was.
+func (a *app) scanOptOuts(ctx context.Context, s *session) error {
+	if _, err := messaging.ScanOptOuts(ctx, s.drv, s.db, s.guard, a.cfg.LinkedIn.BaseURL, a.log); err != nil {
+		var lost *auth.SessionError
+		if errors.As(err, &lost) || errors.Is(err, context.Canceled) {
+			return err
+		}
+		a.log.WithError(err).Error("opt-out scan failed")
+	}
+	return nil
+}
+
+// cmdRun is the original demo flow: search, connect with approved
+// candidates, then follow up. A checkpoint, lost session or cooldown stops it
+// at once; a limit only ends its own step. A live run works through a
+// persisted plan (see runPlan), so an interrupted run resumes where it
+// stopped; a dry run runs the steps directly.
+func cmdRun(ctx context.Context, a *app, args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var keywords stringList
+	fs.Var(&keywords, "keyword", "search keyword for a new plan; repeat or comma-separate to search several (default: search.keywords)")
+	fresh := fs.Bool("fresh", false, "abandon an unfinished plan instead of resuming it")
+	dryRun, reportPath := dryRunFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if len(keywords) > 0 {
+		a.cfg.Search.Keywords = keywords
+	}
+
+	s, err := a.openSession(ctx)
+	if err != nil {
+		return a.exitWith(err, "login failed")
+	}
+	defer s.Close()
+
+	if !*dryRun {
+		code := a.exitWith(a.runPlan(ctx, s, *fresh), "run stopped")
+		a.keepSession(s, code)
+		return code
+	}
+
+	report := a.newReport(*dryRun)
+	defer a.finishReport(report, *reportPath)
+
+	code := exitOK
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"search", func() error { return a.searchAndQueue(ctx, s, 0, report) }},
+		{"connect", func() error { return a.connect(ctx, s, nil, report) }},
+		{"followup", func() error { return a.followup(ctx, s, nil, report) }},
+	}
+	for _, step := range steps {
+		err := step.run()
+		if err == nil {
+			continue
+		}
+		stepCode := a.exitWith(err, step.name+" stopped")
+		if workflow.Stops(err) {
+			return stepCode
+		}
+		// An error outranks a limit; keep the most serious code.
+		if code == exitOK || stepCode == exitError {
+			code = stepCode
+		}
+	}
+	a.keepSession(s, code)
+	return code
+}
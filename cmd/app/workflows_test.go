@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/dryrun"
+	"linkedin-automation-poc/internal/search"
+	"linkedin-automation-poc/internal/storage"
+)
+
+func TestPreviewCandidatesSavesNothing(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	db, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const known, fresh = "https://www.linkedin.com/in/known/", "https://www.linkedin.com/in/fresh/"
+	if _, err := db.SaveCandidate(ctx, storage.Candidate{ProfileURL: known, Name: "Known"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &app{log: log}
+	report := &dryrun.Report{}
+	a.previewCandidates(ctx, db, []search.SearchResult{
+		{ProfileURL: known, Name: "Known"},
+		{ProfileURL: fresh, Name: "Fresh Face", Headline: "Golang Developer"},
+	}, report)
+
+	want := []dryrun.Entry{
+		{Workflow: "search", Profile: known, Action: dryrun.ActionSkip, Reason: "already a candidate (pending)"},
+		{Workflow: "search", Profile: fresh, Action: dryrun.ActionQueue, Text: "Fresh Face – Golang Developer"},
+	}
+	if len(report.Entries) != len(want) {
+		t.Fatalf("report = %+v, want %+v", report.Entries, want)
+	}
+	for i := range want {
+		if report.Entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, report.Entries[i], want[i])
+		}
+	}
+
+	all, err := db.Candidates(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("candidates after a dry run = %d, want 1", len(all))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"linkedin-automation-poc/internal/fakelinkedin"
+	"linkedin-automation-poc/internal/logger"
+)
+
+// main serves the fake LinkedIn fixture on a fixed address so the real
+// workflows can be pointed at it, e.g.:
+//
+//	go run ./cmd/fakelinkedin -addr 127.0.0.1:8099
+//	LINKEDIN_BASE_URL=http://127.0.0.1:8099 go run ./cmd/app
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8099", "address to listen on")
+	email := flag.String("email", "", "accepted login email (any non-empty value when empty)")
+	password := flag.String("password", "", "accepted login password")
+	autoAccept := flag.Bool("auto-accept", false, "accept every invitation immediately")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log := logger.New()
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.WithError(err).Fatal("failed to listen")
+	}
+
+	srv := fakelinkedin.NewUnstarted(fakelinkedin.Options{Email: *email, Password: *password, AutoAccept: *autoAccept})
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	log.WithField("base_url", srv.URL).Info("fake LinkedIn listening")
+	<-ctx.Done()
+
+	log.WithField("invitations", len(srv.Invitations())).
+		WithField("messages", len(srv.Messages())).
+		Info("fake LinkedIn shutting down")
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// checkpointPoll is how often the page is re-read while a human works on a
+// checkpoint.
+const checkpointPoll = 2 * time.Second
+
+// notifyTimeout bounds checkpoint.notify_command.
+const notifyTimeout = 30 * time.Second
+
+// CheckpointHandler is the one place security checkpoints are dealt with.
+// Workflows reach it through Guard.Check, and since they run one action at a
+// time, waiting here pauses all automation.
+type CheckpointHandler struct {
+	drv   driver.Driver
+	store *storage.Storage
+	cfg   config.CheckpointConfig
+	// visible is false for a headless browser, where nobody can resolve
+	// the checkpoint.
+	visible bool
+	log     *logrus.Logger
+}
+
+// NewCheckpointHandler returns a handler for the tab drv that keeps its
+// checkpoint history and cooldowns in store.
+func NewCheckpointHandler(drv driver.Driver, store *storage.Storage, cfg config.CheckpointConfig, visible bool, log *logrus.Logger) *CheckpointHandler {
+	return &CheckpointHandler{drv: drv, store: store, cfg: cfg, visible: visible, log: log}
+}
+
+// Handle is called while the tab shows the checkpoint page url. It records
+// the checkpoint, notifies the operator and waits up to the configured
+// timeout for a human to resolve it. It returns nil once the page moves on,
+// and otherwise a *SessionError, wrapped with the reason the run stopped. A
+// checkpoint within checkpoint.repeat_window of the previous one starts a
+// cooldown and stops the run even when resolved; that error also wraps
+// workflow.ErrCooldown, so it ends the run like the cooldown it started.
+func (h *CheckpointHandler) Handle(ctx context.Context, url string) error {
+	lost := &SessionError{Page: PageCheckpoint, URL: url}
+	if h == nil {
+		return lost
+	}
+	log := h.log.WithField("url", url)
+
+	now := time.Now()
+	recent, err := h.store.RecordCheckpoint(ctx, storage.Checkpoint{At: now, URL: url}, h.cfg.RepeatWindow)
+	if err != nil {
+		log.WithError(err).Warn("failed to record checkpoint")
+	}
+	if len(recent) > 1 {
+		reason := fmt.Sprintf("%d checkpoints within %s", len(recent), h.cfg.RepeatWindow)
+		// A longer pause already in force, e.g. after a restriction, stays.
+		cd, err := h.store.ExtendCooldown(ctx, storage.Cooldown{Until: now.Add(h.cfg.Cooldown), Reason: reason})
+		if err != nil {
+			log.WithError(err).Error("failed to store cooldown")
+		}
+		until := cd.Until
+		h.notify(ctx, url)
+		log.WithField("until", until.Format(time.RFC3339)).Error("⚠️  repeated LinkedIn checkpoint - pausing all sending; resolve it in the browser before the next run")
+		return fmt.Errorf("%w: %s: %w until %s", lost, reason, workflow.ErrCooldown, until.Format(time.RFC3339))
+	}
+
+	h.notify(ctx, url)
+	if !h.visible {
+		log.Error("⚠️  LinkedIn checkpoint in a headless browser - run with browser.headless: false and resolve it by hand")
+		return fmt.Errorf("%w: browser is headless, nobody can resolve it", lost)
+	}
+	if h.cfg.Timeout <= 0 {
+		log.Error("⚠️  LinkedIn checkpoint - resolve it in the browser, then run again")
+		return fmt.Errorf("%w: checkpoint.timeout is 0", lost)
+	}
+
+	log.WithField("timeout", h.cfg.Timeout).Warn("⚠️  LinkedIn checkpoint - automation paused; complete the verification in the browser window")
+	deadline := now.Add(h.cfg.Timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", lost, ctx.Err())
+		case <-time.After(checkpointPoll):
+		}
+		if u, err := h.drv.URL(); err == nil && classifyPage(u) != PageCheckpoint {
+			log.WithField("now_at", u).Info("checkpoint resolved, resuming")
+			return nil
+		}
+	}
+	log.WithField("timeout", h.cfg.Timeout).Error("checkpoint not resolved in time, stopping")
+	return fmt.Errorf("%w: not resolved within %s", lost, h.cfg.Timeout)
+}
+
+// notify gets the operator's attention: a terminal bell and, when
+// configured, checkpoint.notify_command. A failing command is only logged.
+func (h *CheckpointHandler) notify(ctx context.Context, url string) {
+	fmt.Fprint(os.Stderr, "\a")
+	if h.cfg.NotifyCommand == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, h.cfg.NotifyCommand)
+	cmd.Env = append(os.Environ(), "CHECKPOINT_URL="+url)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		h.log.WithError(err).WithField("output", string(out)).Warn("checkpoint.notify_command failed")
+	}
+}
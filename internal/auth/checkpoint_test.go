@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+func TestHandleRepeatedCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cfg := config.CheckpointConfig{Timeout: time.Minute, RepeatWindow: time.Hour, Cooldown: 48 * time.Hour}
+	// A headless browser stops at the first checkpoint without waiting.
+	h := NewCheckpointHandler(driver.NewFake(), store, cfg, false, log)
+	const url = "https://www.linkedin.com/checkpoint/challenge/x"
+
+	err = h.Handle(ctx, url)
+	if !errors.Is(err, workflow.ErrCheckpoint) || errors.Is(err, workflow.ErrCooldown) {
+		t.Fatalf("first checkpoint: %v, want a checkpoint without a cooldown", err)
+	}
+	if _, found, err := store.Cooldown(ctx); err != nil || found {
+		t.Fatalf("cooldown after one checkpoint: found = %v, err = %v", found, err)
+	}
+
+	err = h.Handle(ctx, url)
+	var lost *SessionError
+	if !errors.Is(err, workflow.ErrCooldown) || !errors.As(err, &lost) || lost.Page != PageCheckpoint {
+		t.Fatalf("repeated checkpoint: %v, want a checkpoint *SessionError wrapping ErrCooldown", err)
+	}
+	cd, found, err := store.Cooldown(ctx)
+	if err != nil || !found {
+		t.Fatalf("cooldown after a repeated checkpoint: found = %v, err = %v", found, err)
+	}
+	if left := time.Until(cd.Until); left < 47*time.Hour || left > 48*time.Hour {
+		t.Errorf("cooldown until %s, want about 48h from now", cd.Until)
+	}
+}
+
+func TestHandleRepeatedCheckpointKeepsLongerCooldown(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// A restriction paused sending for 14 days before the checkpoints.
+	restriction := storage.Cooldown{Until: time.Now().Add(14 * 24 * time.Hour).UTC(), Reason: "restricted: Your account has been restricted"}
+	if _, err := store.ExtendCooldown(ctx, restriction); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.CheckpointConfig{Timeout: time.Minute, RepeatWindow: time.Hour, Cooldown: 48 * time.Hour}
+	h := NewCheckpointHandler(driver.NewFake(), store, cfg, false, log)
+	const url = "https://www.linkedin.com/checkpoint/challenge/x"
+	h.Handle(ctx, url)
+	if err := h.Handle(ctx, url); !errors.Is(err, workflow.ErrCooldown) {
+		t.Fatalf("repeated checkpoint: %v, want ErrCooldown", err)
+	}
+
+	cd, _, err := store.Cooldown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cd.Until.Equal(restriction.Until) || cd.Reason != restriction.Reason {
+		t.Errorf("cooldown = %+v, want the 14-day restriction kept", cd)
+	}
+}
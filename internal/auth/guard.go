@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// Page is a kind of page LinkedIn sends a browser to when it no longer
+// trusts the session.
+type Page string
+
+const (
+	PageLogin      Page = "login"
+	PageAuthwall   Page = "authwall"
+	PageCheckpoint Page = "checkpoint"
+)
+
+// SessionError means a workflow landed on a login, authwall or checkpoint
+// page instead of the page it asked for. It wraps workflow.ErrCheckpoint for
+// checkpoints and workflow.ErrLoginRequired otherwise.
+type SessionError struct {
+	Page Page
+	URL  string
+}
+
+func (e *SessionError) Error() string {
+	if e.Page == PageCheckpoint {
+		return fmt.Sprintf("LinkedIn checkpoint (%s)", e.URL)
+	}
+	return fmt.Sprintf("session lost: redirected to %s page (%s)", e.Page, e.URL)
+}
+
+func (e *SessionError) Unwrap() error {
+	if e.Page == PageCheckpoint {
+		return workflow.ErrCheckpoint
+	}
+	return workflow.ErrLoginRequired
+}
+
+// Guard watches the session while workflows run. Workflows call Check after
+// each navigation, so an expired session stops them instead of being
+// mistaken for a profile without buttons. A nil *Guard checks nothing.
+type Guard struct {
+	drv         driver.Driver
+	sessions    *SessionStore
+	checkpoints *CheckpointHandler
+	log         *logrus.Logger
+}
+
+// NewGuard returns a guard for the tab drv. sessions is where SaveSession
+// writes refreshed cookies; checkpoints handles checkpoint pages, and when
+// nil they stop the run at once.
+func NewGuard(drv driver.Driver, sessions *SessionStore, checkpoints *CheckpointHandler, log *logrus.Logger) *Guard {
+	return &Guard{drv: drv, sessions: sessions, checkpoints: checkpoints, log: log}
+}
+
+// Check returns a *SessionError when the current page is a login or authwall
+// page. A checkpoint page is passed to the CheckpointHandler; once a human
+// resolves it, target (the page the workflow navigated to, if any) is loaded
+// again and checked. Failing to read the URL is not treated as a lost
+// session.
+func (g *Guard) Check(ctx context.Context, target string) error {
+	if g == nil {
+		return nil
+	}
+	u, err := g.drv.URL()
+	if err != nil {
+		return nil
+	}
+	switch page := classifyPage(u); page {
+	case "":
+		return nil
+	case PageCheckpoint:
+		if err := g.checkpoints.Handle(ctx, u); err != nil {
+			return err
+		}
+		if target != "" {
+			if err := g.drv.Navigate(ctx, target); err != nil {
+				return fmt.Errorf("reload %s after checkpoint: %w", target, err)
+			}
+		}
+		return g.Check(ctx, "")
+	default:
+		g.log.WithField("url", u).WithField("page", page).Warn("LinkedIn session lost, stopping")
+		return &SessionError{Page: page, URL: u}
+	}
+}
+
+// SaveSession stores the browser's current cookies, which LinkedIn refreshes
+// as the tool browses, so the next run starts from the newest session. It
+// saves nothing when the tab is not logged in or no session key is
+// configured.
+func (g *Guard) SaveSession() error {
+	if g == nil || g.sessions.Ready() != nil {
+		return nil
+	}
+	if u, err := g.drv.URL(); err != nil || classifyPage(u) != "" {
+		return nil
+	}
+	return saveCookies(g.drv, g.sessions, g.log)
+}
+
+// classifyPage tells which kind of signed-out page rawURL is, or "" for any
+// other page.
+func classifyPage(rawURL string) Page {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	path := strings.ToLower(u.Path)
+	switch {
+	case strings.HasPrefix(path, "/authwall"):
+		return PageAuthwall
+	case strings.HasPrefix(path, "/login"),
+		strings.HasPrefix(path, "/uas/login"),
+		strings.HasPrefix(path, "/checkpoint/lg/login"),
+		strings.HasPrefix(path, "/checkpoint/rm/sign-in-another-account"):
+		return PageLogin
+	case strings.HasPrefix(path, "/checkpoint"), strings.Contains(path, "challenge"):
+		return PageCheckpoint
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+func TestClassifyPage(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Page
+	}{
+		{"https://www.linkedin.com/feed/", ""},
+		{"https://www.linkedin.com/in/jane-doe/", ""},
+		{"https://www.linkedin.com/search/results/people/?keywords=login", ""},
+		{"http://127.0.0.1:8099/mynetwork/", ""},
+		{"https://www.linkedin.com/login", PageLogin},
+		{"https://www.linkedin.com/login?session_redirect=%2Ffeed%2F", PageLogin},
+		{"https://www.linkedin.com/uas/login?trk=guest", PageLogin},
+		{"https://www.linkedin.com/checkpoint/lg/login-submit", PageLogin},
+		{"https://www.linkedin.com/checkpoint/rm/sign-in-another-account", PageLogin},
+		{"https://www.linkedin.com/authwall?trk=gf", PageAuthwall},
+		{"https://www.linkedin.com/AuthWall", PageAuthwall},
+		{"https://www.linkedin.com/checkpoint/challenge/AgF3x", PageCheckpoint},
+		{"https://www.linkedin.com/checkpoint/lg/verify", PageCheckpoint},
+		{"http://127.0.0.1:8099/challenge", PageCheckpoint},
+		{"about:blank", ""},
+		{"%zz", ""},
+	}
+	for _, tt := range tests {
+		if got := classifyPage(tt.url); got != tt.want {
+			t.Errorf("classifyPage(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	const (
+		feed       = "https://www.linkedin.com/feed/"
+		authwall   = "https://www.linkedin.com/authwall"
+		checkpoint = "https://www.linkedin.com/checkpoint/challenge/x"
+	)
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{feed, nil},
+		{authwall, workflow.ErrLoginRequired},
+		{checkpoint, workflow.ErrCheckpoint},
+	}
+	for _, tt := range tests {
+		f := driver.NewFake()
+		f.Pages[tt.url] = &driver.FakePage{}
+		if err := f.Navigate(context.Background(), tt.url); err != nil {
+			t.Fatal(err)
+		}
+		// Without a CheckpointHandler a checkpoint stops the run at once.
+		err := NewGuard(f, nil, nil, log).Check(context.Background(), "")
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("Check on %s: %v", tt.url, err)
+			}
+			continue
+		}
+		var lost *SessionError
+		if !errors.Is(err, tt.want) || !errors.As(err, &lost) || lost.URL != tt.url {
+			t.Errorf("Check on %s = %v, want a *SessionError wrapping %v", tt.url, err, tt.want)
+		}
+	}
+
+	var g *Guard
+	if err := g.Check(context.Background(), ""); err != nil {
+		t.Errorf("nil Guard Check = %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// ErrLoginTimeout means the user did not finish signing in in time. It wraps
+// workflow.ErrLoginRequired: the run still has no session.
+var ErrLoginTimeout = fmt.Errorf("%w: timed out waiting for sign-in", workflow.ErrLoginRequired)
+
+// InteractiveLogin opens the login page and waits for the user to sign in in
+// the browser window, completing any verification LinkedIn asks for. The
+// tool never sees the password. Once the feed loads, the session is saved to
+// sessions. Later runs reuse it without credentials.
+func InteractiveLogin(ctx context.Context, drv driver.Driver, sessions *SessionStore, baseURL string, timeout time.Duration, log *logrus.Logger) error {
+	// Fail before the user signs in if the session could not be saved.
+	if err := sessions.Ready(); err != nil {
+		return err
+	}
+	if err := drv.Navigate(ctx, baseURL+"/login"); err != nil {
+		return fmt.Errorf("navigate to login: %w", err)
+	}
+	log.WithField("timeout", timeout).Info("sign in to LinkedIn in the browser window, including any verification; waiting for the feed")
+
+	deadline := time.Now().Add(timeout)
+	lastURL := ""
+	for !onFeed(drv, baseURL) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s", ErrLoginTimeout, timeout)
+		}
+		// Log page changes so the operator can see progress, e.g. a
+		// verification step.
+		if u, err := drv.URL(); err == nil && u != lastURL {
+			log.WithField("url", u).Debug("waiting for sign-in")
+			lastURL = u
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	if err := saveCookies(drv, sessions, log); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	log.Info("signed in; later runs will reuse this session")
+	return nil
+}
+
+func onFeed(drv driver.Driver, baseURL string) bool {
+	u, err := drv.URL()
+	return err == nil && strings.HasPrefix(u, baseURL+"/feed")
+}
@@ -2,51 +2,53 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/sirupsen/logrus"
 
-	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/workflow"
 )
 
-const cookieFile = "linkedin_session_cookies.json"
-
 // Login performs a LinkedIn login sequence, reusing session cookies when
 // possible. It tries to detect obvious failure states such as invalid
-// credentials or checkpoint / captcha pages.
-func Login(ctx context.Context, br *rod.Browser, store *storage.Storage, email, password string, log *logrus.Logger) error {
-	page, err := br.Page(proto.TargetCreateTarget{URL: "about:blank"})
-	if err != nil {
-		return fmt.Errorf("open blank page: %w", err)
+// credentials or checkpoint / captcha pages. baseURL is the site root, e.g.
+// "https://www.linkedin.com" or the URL of a local fixture server.
+//
+// Credentials are only needed when the saved session is missing or expired;
+// without them Login fails with workflow.ErrLoginRequired, and the user can
+// sign in by hand with InteractiveLogin instead. The page after the login
+// form goes through guard, so a checkpoint is handed to its
+// CheckpointHandler; cookies are saved to the guard's session store.
+func Login(ctx context.Context, drv driver.Driver, guard *Guard, baseURL, email, password string, log *logrus.Logger) error {
+	sessions := guard.sessions
+	if path, found := LegacyCookieFile(); found {
+		log.WithField("path", path).Warn("plaintext cookie file from an earlier version is no longer used - delete it with \"app logout\"")
 	}
 
 	// Attempt to restore existing cookies first to avoid logging in on every
 	// run. This keeps the demo closer to how a user would behave across
 	// sessions.
-	if err := loadCookies(ctx, br, log); err == nil {
+	if err := loadCookies(drv, sessions, log); err == nil {
 		log.Info("restored existing LinkedIn session cookies – testing session")
-		if err := page.Navigate("https://www.linkedin.com/feed/"); err == nil {
-			if waitForURLContains(page, "linkedin.com/feed", 10*time.Second) == nil {
+		if err := drv.Navigate(ctx, baseURL+"/feed/"); err == nil {
+			if waitForURLContains(drv, baseURL+"/feed", 10*time.Second) == nil {
 				log.Info("existing session appears valid, skipping login form")
 				return nil
 			}
 		}
 	}
 
-	log.Info("performing fresh LinkedIn login")
-	if err := page.Navigate("https://www.linkedin.com/login"); err != nil {
-		return fmt.Errorf("navigate to login: %w", err)
+	if email == "" || password == "" {
+		return fmt.Errorf("%w: no valid saved session - run \"app login --interactive\" (or set LINKEDIN_EMAIL / LINKEDIN_PASSWORD)", workflow.ErrLoginRequired)
 	}
 
-	if err := page.Timeout(20 * time.Second).WaitLoad(); err != nil {
-		return fmt.Errorf("wait login page load: %w", err)
+	log.Info("performing fresh LinkedIn login")
+	if err := drv.Navigate(ctx, baseURL+"/login"); err != nil {
+		return fmt.Errorf("navigate to login: %w", err)
 	}
 
 	// Basic form interaction; selectors can change over time, so we try a
@@ -64,27 +66,37 @@ func Login(ctx context.Context, br *rod.Browser, store *storage.Storage, email,
 		"input[name='session_password']",
 	}
 
-	usernameEl, err := firstExistingElement(page, usernameSelectors)
+	usernameEl, err := firstExistingElement(drv, usernameSelectors)
 	if err != nil {
 		return fmt.Errorf("locate username field: %w", err)
 	}
-	if err := usernameEl.Input(email); err != nil {
+	if err := usernameEl.Type(email); err != nil {
 		return fmt.Errorf("fill username: %w", err)
 	}
 
-	passwordEl, err := firstExistingElement(page, passwordSelectors)
+	passwordEl, err := firstExistingElement(drv, passwordSelectors)
 	if err != nil {
 		return fmt.Errorf("locate password field: %w", err)
 	}
-	if err := passwordEl.Input(password); err != nil {
+	if err := passwordEl.Type(password); err != nil {
 		return fmt.Errorf("fill password: %w", err)
 	}
 
-	if err := page.MustElement("button[type=submit]").Click("left", 1); err != nil {
+	submitEl, err := firstExistingElement(drv, []string{"button[type=submit]"})
+	if err != nil {
+		return fmt.Errorf("locate submit button: %w", err)
+	}
+	loginURL, _ := drv.URL()
+	if err := submitEl.Click(); err != nil {
 		return fmt.Errorf("click submit: %w", err)
 	}
 
-	if err := page.Timeout(30 * time.Second).WaitLoad(); err != nil {
+	// The login form is still loaded right after the click, so wait for the
+	// browser to leave it before waiting for the next page to load.
+	if err := waitForURLChange(drv, loginURL, 30*time.Second); err != nil {
+		return fmt.Errorf("wait post‑login navigation: %w", err)
+	}
+	if err := drv.WaitLoad(30 * time.Second); err != nil {
 		return fmt.Errorf("wait post‑login load: %w", err)
 	}
 
@@ -93,18 +105,20 @@ func Login(ctx context.Context, br *rod.Browser, store *storage.Storage, email,
 	// that we *must* land on /feed – we simply log the current URL and
 	// proceed, so the rest of the demo can run even if additional manual
 	// interaction is required.
-	if info, err := page.Info(); err == nil {
-		log.WithField("url", info.URL).Info("post‑login page URL")
-
-		// If we're on a checkpoint page, warn the user
-		if strings.Contains(info.URL, "checkpoint") || strings.Contains(info.URL, "challenge") {
-			log.Warn("⚠️  LinkedIn checkpoint detected - you may need to complete verification manually")
-			log.Warn("The app will continue, but some features may not work until checkpoint is resolved")
+	if currentURL, err := drv.URL(); err == nil {
+		log.WithField("url", currentURL).Info("post‑login page URL")
+		if classifyPage(currentURL) == PageLogin {
+			return fmt.Errorf("%w: LinkedIn did not accept the credentials (%s)", workflow.ErrLoginRequired, currentURL)
 		}
 	}
+	// A checkpoint needs a human; the guard waits for one instead of
+	// automating around it.
+	if err := guard.Check(ctx, ""); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
 
 	// Persist cookies so they can be reused in later runs or after a crash.
-	if err := saveCookies(ctx, br, log); err != nil {
+	if err := saveCookies(drv, sessions, log); err != nil {
 		log.WithError(err).Warn("failed to persist cookies; session will not survive restart")
 	}
 
@@ -112,11 +126,11 @@ func Login(ctx context.Context, br *rod.Browser, store *storage.Storage, email,
 	return nil
 }
 
-func waitForURLContains(page *rod.Page, needle string, timeout time.Duration) error {
+func waitForURLContains(drv driver.Driver, needle string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		info, err := page.Info()
-		if err == nil && info.URL != "" && strings.Contains(info.URL, needle) {
+		currentURL, err := drv.URL()
+		if err == nil && currentURL != "" && strings.Contains(currentURL, needle) {
 			return nil
 		}
 		time.Sleep(500 * time.Millisecond)
@@ -124,52 +138,44 @@ func waitForURLContains(page *rod.Page, needle string, timeout time.Duration) er
 	return errors.New("timeout waiting for target URL")
 }
 
-// saveCookies serializes all browser cookies to disk so they can be restored
-// on the next run. This keeps the PoC resilient to restarts.
-func saveCookies(ctx context.Context, br *rod.Browser, log *logrus.Logger) error {
-	// Use the DevTools protocol helper to fetch all cookies known to the browser.
-	resp, err := proto.NetworkGetAllCookies{}.Call(br)
-	if err != nil {
-		return fmt.Errorf("get cookies: %w", err)
+func waitForURLChange(drv driver.Driver, from string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		currentURL, err := drv.URL()
+		if err == nil && currentURL != "" && currentURL != from {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
 	}
+	return errors.New("timeout waiting for the page to change")
+}
 
-	b, err := json.Marshal(resp.Cookies)
+// saveCookies encrypts all browser cookies into the session store so they
+// can be restored on the next run. This keeps the PoC resilient to restarts.
+func saveCookies(drv driver.Driver, sessions *SessionStore, log *logrus.Logger) error {
+	cookies, err := drv.Cookies()
 	if err != nil {
-		return fmt.Errorf("marshal cookies: %w", err)
+		return fmt.Errorf("get cookies: %w", err)
 	}
-	if err := os.WriteFile(cookieFile, b, 0o600); err != nil {
-		return fmt.Errorf("write cookie file: %w", err)
+	if err := sessions.Save(cookies, time.Now()); err != nil {
+		return err
 	}
-	log.WithField("path", cookieFile).Info("session cookies saved")
+	log.WithField("path", sessions.Path()).Info("session cookies saved")
 	return nil
 }
 
-// loadCookies reads cookies from disk and installs them into the browser.
-func loadCookies(ctx context.Context, br *rod.Browser, log *logrus.Logger) error {
-	b, err := os.ReadFile(cookieFile)
+// loadCookies decrypts the saved session and installs it into the browser.
+// A missing session is normal; anything else is logged so an expired or
+// undecryptable session is not silently replaced by a fresh login.
+func loadCookies(drv driver.Driver, sessions *SessionStore, log *logrus.Logger) error {
+	cookies, err := sessions.Load(time.Now())
 	if err != nil {
+		if !errors.Is(err, ErrNoSession) {
+			log.WithError(err).WithField("path", sessions.Path()).Warn("saved session cannot be used")
+		}
 		return err
 	}
-	var cookies []*proto.NetworkCookie
-	if err := json.Unmarshal(b, &cookies); err != nil {
-		return fmt.Errorf("unmarshal cookies: %w", err)
-	}
-
-	params := make([]*proto.NetworkCookieParam, 0, len(cookies))
-	for _, c := range cookies {
-		params = append(params, &proto.NetworkCookieParam{
-			Name:     c.Name,
-			Value:    c.Value,
-			Domain:   c.Domain,
-			Path:     c.Path,
-			Expires:  c.Expires,
-			HTTPOnly: c.HTTPOnly,
-			Secure:   c.Secure,
-		})
-	}
-
-	// Rod exposes a convenience wrapper for setting cookies on the browser.
-	if err := br.SetCookies(params); err != nil {
+	if err := drv.SetCookies(cookies); err != nil {
 		return fmt.Errorf("set cookies: %w", err)
 	}
 	log.WithField("count", len(cookies)).Info("restored cookies into browser")
@@ -179,10 +185,10 @@ func loadCookies(ctx context.Context, br *rod.Browser, log *logrus.Logger) error
 // firstExistingElement iterates over a list of CSS selectors and returns the
 // first element that exists on the page. This makes the login flow resilient
 // to minor LinkedIn markup changes between deployments.
-func firstExistingElement(page *rod.Page, selectors []string) (*rod.Element, error) {
+func firstExistingElement(drv driver.Driver, selectors []string) (driver.Element, error) {
 	for _, sel := range selectors {
-		el, err := page.Element(sel)
-		if err == nil && el != nil {
+		el, found, err := drv.Find(sel)
+		if err == nil && found {
 			return el, nil
 		}
 	}
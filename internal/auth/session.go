@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+)
+
+// SessionKeyEnv holds the session encryption key; it takes precedence over
+// session.key_file.
+const SessionKeyEnv = "LINKEDIN_SESSION_KEY"
+
+// legacyCookieFile is where earlier versions kept the cookies as plain JSON.
+const legacyCookieFile = "linkedin_session_cookies.json"
+
+// authCookie is LinkedIn's session token; when it has expired the saved
+// session is useless.
+const authCookie = "li_at"
+
+var (
+	// ErrNoSession means no session has been saved yet.
+	ErrNoSession = errors.New("no saved session")
+	// ErrNoSessionKey means neither LINKEDIN_SESSION_KEY nor
+	// session.key_file provides a key.
+	ErrNoSessionKey = errors.New("no session key: set " + SessionKeyEnv + " or session.key_file")
+	// ErrSessionDecrypt means the session file could not be decrypted: the
+	// key is wrong or the file is damaged.
+	ErrSessionDecrypt = errors.New("cannot decrypt saved session")
+	// ErrSessionExpired means the saved session token has expired.
+	ErrSessionExpired = errors.New("saved session has expired")
+)
+
+// sessionMagic starts every session file and is authenticated with the
+// ciphertext, so the format can change later.
+var sessionMagic = []byte("LIAUTO-SESSION-1\n")
+
+// savedSession is the plaintext inside the encrypted file.
+type savedSession struct {
+	SavedAt time.Time       `json:"saved_at"`
+	Cookies []driver.Cookie `json:"cookies"`
+}
+
+// SessionStore keeps the browser cookies encrypted at rest with AES-256-GCM.
+type SessionStore struct {
+	path string
+	// key is nil when none is configured; only Save and Load need it.
+	key    []byte
+	keyErr error
+}
+
+// NewSessionStore returns the store described by cfg. A missing key is not
+// an error here, so "logout" and "doctor" work without one; Save and Load
+// report it.
+func NewSessionStore(cfg config.SessionConfig) *SessionStore {
+	s := &SessionStore{path: cfg.CookieFile}
+	s.key, s.keyErr = loadSessionKey(cfg.KeyFile)
+	return s
+}
+
+// loadSessionKey reads a 32-byte key, base64 or hex encoded, from
+// LINKEDIN_SESSION_KEY or else from keyFile.
+func loadSessionKey(keyFile string) ([]byte, error) {
+	raw, source := os.Getenv(SessionKeyEnv), SessionKeyEnv
+	if raw == "" {
+		if keyFile == "" {
+			return nil, ErrNoSessionKey
+		}
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read session key file: %w", err)
+		}
+		raw, source = string(b), keyFile
+	}
+	raw = strings.TrimSpace(raw)
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(raw); err == nil && len(key) == 32 {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("session key from %s must be 32 bytes, base64 or hex encoded (e.g. openssl rand -base64 32)", source)
+}
+
+// Ready reports why sessions cannot be saved or loaded, or nil when a key is
+// configured.
+func (s *SessionStore) Ready() error {
+	return s.keyErr
+}
+
+// Path is where the encrypted session is kept.
+func (s *SessionStore) Path() string {
+	return s.path
+}
+
+// Exists reports whether a session file is present.
+func (s *SessionStore) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Save encrypts cookies and writes them to the session file, replacing it
+// atomically.
+func (s *SessionStore) Save(cookies []driver.Cookie, now time.Time) error {
+	if s.keyErr != nil {
+		return s.keyErr
+	}
+	plain, err := json.Marshal(savedSession{SavedAt: now.UTC(), Cookies: cookies})
+	if err != nil {
+		return fmt.Errorf("encode cookies: %w", err)
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := append(append([]byte{}, sessionMagic...), nonce...)
+	out = gcm.Seal(out, nonce, plain, sessionMagic)
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return fmt.Errorf("write session file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write session file: %w", err)
+	}
+	return nil
+}
+
+// Load decrypts the saved cookies. It fails with ErrNoSession,
+// ErrNoSessionKey, ErrSessionDecrypt or ErrSessionExpired (checked against
+// the li_at token at now); expired cookies other than the token are dropped.
+func (s *SessionStore) Load(now time.Time) ([]driver.Cookie, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read session file: %w", err)
+	}
+	if s.keyErr != nil {
+		return nil, s.keyErr
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(sessionMagic)+gcm.NonceSize() || string(data[:len(sessionMagic)]) != string(sessionMagic) {
+		return nil, fmt.Errorf("%w: %s is not a session file", ErrSessionDecrypt, s.path)
+	}
+	body := data[len(sessionMagic):]
+	nonce, ciphertext := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, sessionMagic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s (wrong key, or the file was modified)", ErrSessionDecrypt, s.path)
+	}
+	var saved savedSession
+	if err := json.Unmarshal(plain, &saved); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSessionDecrypt, s.path, err)
+	}
+
+	var live []driver.Cookie
+	for _, c := range saved.Cookies {
+		expired := c.Expires > 0 && time.Unix(int64(c.Expires), 0).Before(now)
+		if c.Name == authCookie && expired {
+			return nil, fmt.Errorf("%w: token expired %s (saved %s)", ErrSessionExpired,
+				time.Unix(int64(c.Expires), 0).UTC().Format(time.RFC3339), saved.SavedAt.Format(time.RFC3339))
+		}
+		if !expired {
+			live = append(live, c)
+		}
+	}
+	return live, nil
+}
+
+// Delete overwrites the session file with random bytes before removing it,
+// and removes the plaintext cookie file of earlier versions too. removed
+// lists the files deleted. Overwriting does not defeat copy-on-write or
+// journaling filesystems; the encryption is what protects old copies.
+func (s *SessionStore) Delete() (removed []string, err error) {
+	for _, path := range []string{s.path, legacyCookieFile} {
+		ok, err := shred(path)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, path)
+		}
+	}
+	return removed, nil
+}
+
+// LegacyCookieFile returns the path of a plaintext cookie file left by an
+// earlier version, if there is one.
+func LegacyCookieFile() (string, bool) {
+	_, err := os.Stat(legacyCookieFile)
+	return legacyCookieFile, err == nil
+}
+
+func shred(path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = io.CopyN(f, rand.Reader, info.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("overwrite %s: %w", path, err)
+	}
+	return true, os.Remove(path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
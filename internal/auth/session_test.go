@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+)
+
+var (
+	testKey  = bytes.Repeat([]byte{0x42}, 32)
+	otherKey = bytes.Repeat([]byte{0x17}, 32)
+)
+
+// newTestSessionStore returns a store keeping its file in a temp dir, with
+// key given through LINKEDIN_SESSION_KEY.
+func newTestSessionStore(t *testing.T, dir string, key []byte) *SessionStore {
+	t.Helper()
+	t.Setenv(SessionKeyEnv, base64.StdEncoding.EncodeToString(key))
+	return NewSessionStore(config.SessionConfig{CookieFile: filepath.Join(dir, "session.enc")})
+}
+
+func TestLoadSessionKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "session.key")
+	if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(testKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		keyFile string
+		wantErr string
+	}{
+		{name: "base64 env", env: base64.StdEncoding.EncodeToString(testKey)},
+		{name: "url-safe base64 env", env: base64.RawURLEncoding.EncodeToString(testKey)},
+		{name: "hex file with newline", keyFile: keyFile},
+		{name: "env wins over file", env: " " + base64.StdEncoding.EncodeToString(testKey) + " ", keyFile: "/nonexistent"},
+		{name: "nothing configured", wantErr: ErrNoSessionKey.Error()},
+		{name: "too short", env: base64.StdEncoding.EncodeToString(testKey[:16]), wantErr: "must be 32 bytes"},
+		{name: "not encoded", env: "correct horse battery staple", wantErr: "from " + SessionKeyEnv},
+		{name: "missing file", keyFile: filepath.Join(dir, "missing.key"), wantErr: "read session key file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(SessionKeyEnv, tt.env)
+			key, err := loadSessionKey(tt.keyFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadSessionKey = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(key, testKey) {
+				t.Errorf("loadSessionKey = %x, %v; want the test key", key, err)
+			}
+		})
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	future := float64(now.Add(24 * time.Hour).Unix())
+	past := float64(now.Add(-time.Hour).Unix())
+	token := driver.Cookie{Name: authCookie, Value: "token", Domain: ".linkedin.com", Path: "/", Expires: future, HTTPOnly: true, Secure: true}
+	session := driver.Cookie{Name: "JSESSIONID", Value: "ajax:1", Domain: ".www.linkedin.com", Path: "/"}
+	stale := driver.Cookie{Name: "lidc", Value: "old", Domain: ".linkedin.com", Path: "/", Expires: past}
+
+	tests := []struct {
+		name    string
+		cookies []driver.Cookie
+		want    []driver.Cookie
+		wantErr error
+	}{
+		{name: "live cookies", cookies: []driver.Cookie{token, session}, want: []driver.Cookie{token, session}},
+		{name: "expired cookie dropped", cookies: []driver.Cookie{token, stale, session}, want: []driver.Cookie{token, session}},
+		{
+			name:    "expired token",
+			cookies: []driver.Cookie{session, {Name: authCookie, Value: "token", Expires: past}},
+			wantErr: ErrSessionExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSessionStore(t, t.TempDir(), testKey)
+			if err := s.Ready(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Save(tt.cookies, now); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(s.Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(data, sessionMagic) || bytes.Contains(data, []byte("token")) {
+				t.Errorf("session file is not encrypted: %q", data)
+			}
+
+			got, err := s.Load(now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Load = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionLoadErrors(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	cookies := []driver.Cookie{{Name: authCookie, Value: "token", Expires: float64(now.Add(time.Hour).Unix())}}
+
+	tests := []struct {
+		name string
+		// damage changes the saved file; loadKey is the key Load uses.
+		damage  func(data []byte) []byte
+		loadKey []byte
+		wantErr error
+	}{
+		{name: "wrong key", loadKey: otherKey, wantErr: ErrSessionDecrypt},
+		{
+			name: "flipped ciphertext byte",
+			damage: func(data []byte) []byte {
+				data[len(data)-1] ^= 1
+				return data
+			},
+			wantErr: ErrSessionDecrypt,
+		},
+		{
+			name: "edited header",
+			damage: func(data []byte) []byte {
+				data[0] = 'X'
+				return data
+			},
+			wantErr: ErrSessionDecrypt,
+		},
+		{
+			name:    "truncated",
+			damage:  func(data []byte) []byte { return data[:len(sessionMagic)+4] },
+			wantErr: ErrSessionDecrypt,
+		},
+		{
+			name:    "plaintext cookies",
+			damage:  func([]byte) []byte { return []byte(`[{"name":"li_at","value":"token"}]`) },
+			wantErr: ErrSessionDecrypt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := newTestSessionStore(t, dir, testKey)
+			if err := s.Save(cookies, now); err != nil {
+				t.Fatal(err)
+			}
+			if tt.damage != nil {
+				data, err := os.ReadFile(s.Path())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(s.Path(), tt.damage(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.loadKey != nil {
+				s = newTestSessionStore(t, dir, tt.loadKey)
+			}
+			if _, err := s.Load(now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionWithoutKeyOrFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(SessionKeyEnv, "")
+	s := NewSessionStore(config.SessionConfig{CookieFile: filepath.Join(dir, "session.enc")})
+	if _, err := s.Load(time.Now()); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Load without a file = %v, want ErrNoSession", err)
+	}
+	if err := s.Save(nil, time.Now()); !errors.Is(err, ErrNoSessionKey) {
+		t.Errorf("Save without a key = %v, want ErrNoSessionKey", err)
+	}
+	if s.Exists() {
+		t.Error("Save without a key wrote a file")
+	}
+}
+
+func TestSessionDelete(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The legacy cookie file lives in the working directory.
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	s := newTestSessionStore(t, dir, testKey)
+	if err := s.Save([]driver.Cookie{{Name: authCookie, Value: "token"}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacyCookieFile, []byte(`[{"name":"li_at","value":"token"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LegacyCookieFile(); !ok {
+		t.Fatal("LegacyCookieFile did not find the plaintext file")
+	}
+
+	removed, err := s.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{s.Path(), legacyCookieFile}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("Delete removed %q, want %q", removed, want)
+	}
+	if s.Exists() {
+		t.Error("session file still exists")
+	}
+	if _, ok := LegacyCookieFile(); ok {
+		t.Error("legacy cookie file still exists")
+	}
+
+	// Nothing left to delete is not an error.
+	if removed, err := s.Delete(); err != nil || len(removed) != 0 {
+		t.Errorf("second Delete = %q, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestShredOverwritesBeforeRemoving(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	secret := bytes.Repeat([]byte("li_at=token;"), 100)
+	if err := os.WriteFile(path, secret, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A second link keeps the inode reachable after the removal.
+	link := path + ".link"
+	if err := os.Link(path, link); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	ok, err := shred(path)
+	if err != nil || !ok {
+		t.Fatalf("shred = %v, %v", ok, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stat after shred: %v, want not exist", err)
+	}
+	left, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != len(secret) || bytes.Contains(left, []byte("li_at")) {
+		t.Errorf("shredded contents still hold the secret (%d bytes)", len(left))
+	}
+}
@@ -0,0 +1,136 @@
+// Package breaker stops all outbound actions once LinkedIn warns that the
+// account is being limited. The pause is stored as a cooldown, so later runs
+// refuse to send too until it ends or an operator clears it.
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// Kind is a kind of platform warning.
+type Kind string
+
+const (
+	// InvitationLimit is the banner saying no more invitations can be sent
+	// for now.
+	InvitationLimit Kind = "invitation_limit"
+	// WeeklyLimit is the "you've reached the weekly limit" dialog.
+	WeeklyLimit Kind = "weekly_limit"
+	// Restricted is a notice that the account has been restricted.
+	Restricted Kind = "restricted"
+)
+
+// Warning is a platform warning found on the page.
+type Warning struct {
+	Kind Kind
+	Text string
+}
+
+// warningSelectors are where LinkedIn shows banners, toasts and dialogs.
+// Only these are read, so a post that merely mentions a limit is ignored.
+const warningSelectors = "[role=alert], [role=alertdialog], [role=dialog], .artdeco-modal, .artdeco-toast-item, .artdeco-inline-feedback, .ip-fuse-limit-alert"
+
+// patterns are tried in order, most serious first.
+var patterns = []struct {
+	kind Kind
+	re   *regexp.Regexp
+}{
+	{Restricted, regexp.MustCompile(`(?i)(account|profile) (has been |is |was )?(temporarily )?restricted|restricted your account`)},
+	{WeeklyLimit, regexp.MustCompile(`(?i)weekly (invitation )?limit`)},
+	{InvitationLimit, regexp.MustCompile(`(?i)invitation limit|(too many|maximum number of) (pending )?invitations|out of invitations`)},
+}
+
+// Detect looks for a platform warning on the current page.
+func Detect(drv driver.Driver) (Warning, bool, error) {
+	els, err := drv.FindAll(warningSelectors)
+	if err != nil {
+		return Warning{}, false, err
+	}
+	var texts []string
+	for _, el := range els {
+		if t, err := el.Text(); err == nil && strings.TrimSpace(t) != "" {
+			texts = append(texts, strings.Join(strings.Fields(t), " "))
+		}
+	}
+	for _, p := range patterns {
+		for _, t := range texts {
+			if p.re.MatchString(t) {
+				return Warning{Kind: p.kind, Text: t}, true, nil
+			}
+		}
+	}
+	return Warning{}, false, nil
+}
+
+// Breaker trips on platform warnings. A nil *Breaker never trips.
+type Breaker struct {
+	drv   driver.Driver
+	store *storage.Storage
+	cfg   config.BreakerConfig
+	log   *logrus.Logger
+}
+
+// New returns a breaker for the tab drv that keeps its cooldown in store.
+func New(drv driver.Driver, store *storage.Storage, cfg config.BreakerConfig, log *logrus.Logger) *Breaker {
+	return &Breaker{drv: drv, store: store, cfg: cfg, log: log}
+}
+
+// Allow returns an error wrapping workflow.ErrCooldown while a stored
+// cooldown is in force at now.
+func (b *Breaker) Allow(ctx context.Context, now time.Time) error {
+	if b == nil {
+		return nil
+	}
+	cd, found, err := b.store.Cooldown(ctx)
+	if err != nil {
+		return fmt.Errorf("load cooldown: %w", err)
+	}
+	if found && cd.Active(now) {
+		return fmt.Errorf("%w until %s (%s); clear it with \"app cooldown clear\"", workflow.ErrCooldown, cd.Until.Local().Format("2006-01-02 15:04"), cd.Reason)
+	}
+	return nil
+}
+
+// Check trips the breaker when the current page shows a platform warning:
+// it stores a cooldown and returns an error wrapping workflow.ErrCooldown,
+// after which the caller must not send anything else. A failed page lookup
+// is logged, not treated as a warning.
+func (b *Breaker) Check(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	w, found, err := Detect(b.drv)
+	if err != nil {
+		b.log.WithError(err).Warn("failed to look for platform warnings")
+		return nil
+	}
+	if !found {
+		return nil
+	}
+
+	now := time.Now()
+	pause := b.cfg.LimitCooldown
+	if w.Kind == Restricted {
+		pause = b.cfg.RestrictionCooldown
+	}
+	cd, err := b.store.ExtendCooldown(ctx, storage.Cooldown{Until: now.Add(pause), Reason: string(w.Kind) + ": " + w.Text})
+	if err != nil {
+		b.log.WithError(err).Error("failed to store cooldown")
+	}
+	b.log.WithField("warning", w.Kind).
+		WithField("text", w.Text).
+		WithField("until", cd.Until.Format(time.RFC3339)).
+		Error("⚠️  LinkedIn warning - stopping all sending")
+	return fmt.Errorf("%w until %s: LinkedIn showed %s warning %q", workflow.ErrCooldown, cd.Until.Local().Format("2006-01-02 15:04"), w.Kind, w.Text)
+}
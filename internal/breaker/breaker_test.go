@@ -0,0 +1,128 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+func TestDetect(t *testing.T) {
+	alert := func(text string) *driver.FakeElement {
+		return driver.El([]string{"[role=alert]"}, text, nil)
+	}
+	tests := []struct {
+		name     string
+		elements []*driver.FakeElement
+		want     Warning
+		found    bool
+	}{
+		{name: "nothing"},
+		{
+			name:     "invitation limit toast",
+			elements: []*driver.FakeElement{driver.El([]string{".artdeco-toast-item"}, "You've reached the\n  invitation limit", nil)},
+			want:     Warning{Kind: InvitationLimit, Text: "You've reached the invitation limit"},
+			found:    true,
+		},
+		{
+			name:     "weekly limit dialog",
+			elements: []*driver.FakeElement{driver.El([]string{"[role=dialog]"}, "You've reached the weekly invitation limit", nil)},
+			want:     Warning{Kind: WeeklyLimit, Text: "You've reached the weekly invitation limit"},
+			found:    true,
+		},
+		{
+			name:     "too many pending",
+			elements: []*driver.FakeElement{alert("You have too many pending invitations")},
+			want:     Warning{Kind: InvitationLimit, Text: "You have too many pending invitations"},
+			found:    true,
+		},
+		{
+			name: "restriction wins",
+			elements: []*driver.FakeElement{
+				alert("Out of invitations for now"),
+				driver.El([]string{".artdeco-modal"}, "Your account has been temporarily restricted", nil),
+			},
+			want:  Warning{Kind: Restricted, Text: "Your account has been temporarily restricted"},
+			found: true,
+		},
+		{
+			name:     "post text",
+			elements: []*driver.FakeElement{driver.El([]string{".feed-shared-update-v2"}, "Hit my weekly invitation limit again!", nil)},
+		},
+		{
+			name:     "unrelated alert",
+			elements: []*driver.FakeElement{alert("Invitation sent"), alert("   ")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := driver.NewFake()
+			f.Current().Add(tt.elements...)
+			got, found, err := Detect(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.found || got != tt.want {
+				t.Errorf("Detect = %+v, %v; want %+v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	f := driver.NewFake()
+	b := New(f, store, config.BreakerConfig{LimitCooldown: 7 * 24 * time.Hour, RestrictionCooldown: 14 * 24 * time.Hour}, log)
+	if err := b.Check(ctx); err != nil {
+		t.Fatalf("Check without a warning: %v", err)
+	}
+	if err := b.Allow(ctx, time.Now()); err != nil {
+		t.Fatalf("Allow without a cooldown: %v", err)
+	}
+
+	restricted := driver.El([]string{"[role=alert]"}, "We've restricted your account", nil)
+	f.Current().Add(restricted)
+	if err := b.Check(ctx); !errors.Is(err, workflow.ErrCooldown) {
+		t.Fatalf("Check on a restriction = %v, want ErrCooldown", err)
+	}
+	cd, _, err := store.Cooldown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left := time.Until(cd.Until); left < 13*24*time.Hour {
+		t.Errorf("cooldown until %s, want the restriction cooldown", cd.Until)
+	}
+
+	// A limit warning must not shorten the restriction's longer pause.
+	f.Current().Remove(restricted)
+	f.Current().Add(driver.El([]string{"[role=alert]"}, "Out of invitations", nil))
+	if err := b.Check(ctx); !errors.Is(err, workflow.ErrCooldown) {
+		t.Fatalf("Check on a limit = %v, want ErrCooldown", err)
+	}
+	if after, _, _ := store.Cooldown(ctx); !after.Until.Equal(cd.Until) {
+		t.Errorf("cooldown moved from %s to %s", cd.Until, after.Until)
+	}
+	if err := b.Allow(ctx, time.Now()); !errors.Is(err, workflow.ErrCooldown) {
+		t.Errorf("Allow during the cooldown = %v, want ErrCooldown", err)
+	}
+	if err := b.Allow(ctx, cd.Until.Add(time.Second)); err != nil {
+		t.Errorf("Allow after the cooldown: %v", err)
+	}
+}
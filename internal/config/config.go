@@ -1,22 +1,44 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"linkedin-automation-poc/internal/template"
 )
 
 // Config is the root configuration structure for the PoC.
 type Config struct {
+	LinkedIn LinkedInConfig `yaml:"linkedin"`
 	Browser  BrowserConfig  `yaml:"browser"`
 	Database DatabaseConfig `yaml:"database"`
+	Session  SessionConfig  `yaml:"session"`
+	Checkpoint CheckpointConfig `yaml:"checkpoint"`
+	Breaker    BreakerConfig    `yaml:"breaker"`
+	Quota      QuotaConfig      `yaml:"quota"`
+	Jobs       JobsConfig       `yaml:"jobs"`
 	Search   SearchConfig   `yaml:"search"`
 	Connect  ConnectConfig  `yaml:"connect"`
 	Messaging MessagingConfig `yaml:"messaging"`
 }
 
+// LinkedInConfig describes the site the workflows talk to. BaseURL is only
+// changed when pointing the tool at a local stand-in such as the
+// fakelinkedin fixture server.
+type LinkedInConfig struct {
+	BaseURL string `yaml:"base_url"`
+}
+
 type BrowserConfig struct {
 	Headless      bool   `yaml:"headless"`
 	ViewportWidth  int    `yaml:"viewport_width"`
@@ -29,6 +51,64 @@ type DatabaseConfig struct {
 	DSN string `yaml:"dsn"`
 }
 
+// SessionConfig says where the encrypted session cookies are kept and where
+// the key comes from. LINKEDIN_SESSION_KEY, when set, takes precedence over
+// KeyFile.
+type SessionConfig struct {
+	CookieFile string `yaml:"cookie_file"`
+	// KeyFile holds a 32-byte AES key, base64 or hex encoded.
+	KeyFile string `yaml:"key_file"`
+}
+
+// CheckpointConfig says how a LinkedIn security checkpoint is handled: all
+// automation pauses while a human resolves it in the visible browser.
+type CheckpointConfig struct {
+	// Timeout is how long to wait for the human; 0 stops the run at once.
+	Timeout time.Duration `yaml:"timeout"`
+	// A checkpoint within RepeatWindow of the previous one stops the run
+	// and starts a cooldown of Cooldown, during which nothing is sent.
+	RepeatWindow time.Duration `yaml:"repeat_window"`
+	Cooldown     time.Duration `yaml:"cooldown"`
+	// NotifyCommand, if set, is run by the shell when a checkpoint appears,
+	// with the page in CHECKPOINT_URL, e.g. to send a chat message.
+	NotifyCommand string `yaml:"notify_command"`
+}
+
+// BreakerConfig says how long sending pauses after LinkedIn warns that the
+// account is being limited.
+type BreakerConfig struct {
+	// LimitCooldown follows an invitation-limit banner or weekly-limit
+	// dialog.
+	LimitCooldown time.Duration `yaml:"limit_cooldown"`
+	// RestrictionCooldown follows a notice that the account is restricted.
+	RestrictionCooldown time.Duration `yaml:"restriction_cooldown"`
+}
+
+// QuotaConfig says how the daily and weekly limits of connect and messaging
+// are counted.
+type QuotaConfig struct {
+	// Timezone is an IANA name such as "Europe/Berlin", or "Local" for the
+	// machine's zone; days start at its midnight and weeks on its Monday.
+	Timezone string `yaml:"timezone"`
+	// Window is "calendar" (limits reset at midnight and on Monday) or
+	// "rolling" (the last 24 hours and the last 7 days).
+	Window string `yaml:"window"`
+}
+
+// Location loads Timezone.
+func (c QuotaConfig) Location() (*time.Location, error) {
+	return time.LoadLocation(c.Timezone)
+}
+
+// JobsConfig says how "app run" retries a planned action that failed.
+type JobsConfig struct {
+	// MaxAttempts is how often a job is tried before it is given up.
+	MaxAttempts int `yaml:"max_attempts"`
+	// RetryBackoff is the wait before the first retry; it doubles with
+	// every further attempt.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+}
+
 type SearchConfig struct {
 	Keywords      []string      `yaml:"keywords"`
 	MaxPages      int           `yaml:"max_pages"`
@@ -41,6 +121,24 @@ type ConnectConfig struct {
 	NoteTemplate      string        `yaml:"note_template"`
 	ActionDelayMin    time.Duration `yaml:"action_delay_min"`
 	ActionDelayMax    time.Duration `yaml:"action_delay_max"`
+	// WeeklyLimit caps invitations per week on top of the daily limit; 0
+	// means no weekly cap.
+	WeeklyLimit int `yaml:"weekly_limit"`
+	// MaxPerRun caps invitations sent by a single run on top of the daily
+	// limit; 0 means no extra cap. The --max flag overrides it.
+	MaxPerRun int `yaml:"max_per_run"`
+	// NoteMaxLength is the invitation-note character limit; defaults to
+	// LinkedIn's 300. Accounts with a lower limit can set it lower.
+	NoteMaxLength int `yaml:"note_max_length"`
+}
+
+// Note parses the invitation-note template.
+func (c ConnectConfig) Note() (*template.Template, error) {
+	t, err := template.Parse("connect.note_template", c.NoteTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("connect.note_template: %w", err)
+	}
+	return t, nil
 }
 
 type MessagingConfig struct {
@@ -49,86 +147,267 @@ type MessagingConfig struct {
 	DailyLimit       int           `yaml:"daily_limit"`
 	ActionDelayMin   time.Duration `yaml:"action_delay_min"`
 	ActionDelayMax   time.Duration `yaml:"action_delay_max"`
+	// WeeklyLimit caps follow-ups per week on top of the daily limit; 0
+	// means no weekly cap.
+	WeeklyLimit int `yaml:"weekly_limit"`
+	// MaxPerRun caps follow-ups sent by a single run on top of the daily
+	// limit; 0 means no extra cap. The --max flag overrides it.
+	MaxPerRun int `yaml:"max_per_run"`
+	// Recontact maps a message type (e.g. "followup") to a re-contact
+	// policy: "never", "always" or a minimum gap in days such as "30d".
+	// Types without an entry default to "never".
+	Recontact map[string]string `yaml:"recontact"`
 }
 
-// Load reads YAML config from disk, applies environment overrides and
-// sensible defaults. Time durations are parsed by the yaml library using the
-// Go duration syntax such as "1s", "500ms", "2m".
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+// ParsedTemplates parses the follow-up message templates.
+func (c MessagingConfig) ParsedTemplates() ([]*template.Template, error) {
+	out := make([]*template.Template, len(c.Templates))
+	for i, text := range c.Templates {
+		name := fmt.Sprintf("messaging.templates[%d]", i)
+		t, err := template.Parse(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[i] = t
 	}
+	return out, nil
+}
 
-	applyDefaults(&cfg)
-	if err := validate(&cfg); err != nil {
-		return nil, err
-	}
+// RecontactPolicy says whether a profile that already received a message of
+// some type may receive another one.
+type RecontactPolicy struct {
+	// Never forbids any further message of the type.
+	Never bool
+	// MinGap is the minimum time since the last message of the type; zero
+	// with Never == false means "always allowed".
+	MinGap time.Duration
+}
 
-	// Example of simple environment override for DSN:
-	if v := os.Getenv("SQLITE_DSN"); v != "" {
-		cfg.Database.DSN = v
+// ParseRecontactPolicy parses "never", "always" or "<N>d".
+func ParseRecontactPolicy(s string) (RecontactPolicy, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); {
+	case v == "never":
+		return RecontactPolicy{Never: true}, nil
+	case v == "always":
+		return RecontactPolicy{}, nil
+	case strings.HasSuffix(v, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || days <= 0 {
+			return RecontactPolicy{}, fmt.Errorf("invalid re-contact policy %q: want never, always or <days>d", s)
+		}
+		return RecontactPolicy{MinGap: time.Duration(days) * 24 * time.Hour}, nil
+	default:
+		return RecontactPolicy{}, fmt.Errorf("invalid re-contact policy %q: want never, always or <days>d", s)
 	}
-
-	return &cfg, nil
 }
 
-func applyDefaults(cfg *Config) {
-	if cfg.Browser.ViewportWidth == 0 {
-		cfg.Browser.ViewportWidth = 1366
+// RecontactPolicyFor returns the policy for a message type, defaulting to
+// "never" so a misconfigured type cannot spam people.
+func (c MessagingConfig) RecontactPolicyFor(msgType string) RecontactPolicy {
+	raw, ok := c.Recontact[msgType]
+	if !ok {
+		return RecontactPolicy{Never: true}
 	}
-	if cfg.Browser.ViewportHeight == 0 {
-		cfg.Browser.ViewportHeight = 768
-	}
-	if cfg.Search.MaxPages == 0 {
-		cfg.Search.MaxPages = 1
+	p, err := ParseRecontactPolicy(raw)
+	if err != nil {
+		return RecontactPolicy{Never: true}
 	}
-	if cfg.Connect.DailyLimit == 0 {
-		cfg.Connect.DailyLimit = 10
+	return p
+}
+
+// Load builds the configuration in layers (defaults, then the YAML file at
+// path, then LIAUTO_* environment variables, then overrides such as
+// "connect.daily_limit=3" from the command line) and validates the result.
+// Validation failures are returned as a ValidationError listing every
+// problem. Time durations use the Go duration syntax such as "1s", "500ms",
+// "2m".
+func Load(path string, overrides ...string) (*Config, error) {
+	cfg, err := Build(path, overrides...)
+	var errs ValidationError
+	if err != nil && !errors.As(err, &errs) {
+		return nil, err
 	}
-	if cfg.Messaging.DailyLimit == 0 {
-		cfg.Messaging.DailyLimit = 10
+	// Validate even when building found problems, so one run reports them
+	// all.
+	if verr, ok := Validate(cfg).(ValidationError); ok {
+		errs = append(errs, verr...)
 	}
-	if cfg.Search.PageDelayMin == 0 {
-		cfg.Search.PageDelayMin = 2 * time.Second
+	if len(errs) > 0 {
+		return nil, errs
 	}
-	if cfg.Search.PageDelayMax == 0 {
-		cfg.Search.PageDelayMax = 5 * time.Second
+	return cfg, nil
+}
+
+// Hash returns the SHA-256 of the settings as "config print --effective"
+// shows them, so runs made with the same effective config can be told
+// apart from the rest.
+func (c *Config) Hash() (string, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
 	}
-	if cfg.Connect.ActionDelayMin == 0 {
-		cfg.Connect.ActionDelayMin = 2 * time.Second
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Build layers the configuration like Load but does not validate it, so
+// "config print --effective" can show an invalid result. Unknown keys and
+// values of the wrong type in the file, unknown LIAUTO_* variables and
+// unparsable override values are reported as a ValidationError.
+func Build(path string, overrides ...string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.Connect.ActionDelayMax == 0 {
-		cfg.Connect.ActionDelayMax = 5 * time.Second
+
+	cfg := defaults()
+	var errs ValidationError
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
+		// The rest of the file was still decoded; report every bad key.
+		errs = append(errs, decodeErrors(path, data, typeErr)...)
 	}
-	if cfg.Messaging.ActionDelayMin == 0 {
-		cfg.Messaging.ActionDelayMin = 2 * time.Second
+
+	errs = append(errs, applyEnv(cfg, os.Environ())...)
+	for _, o := range overrides {
+		if err := applyOverride(cfg, o); err != nil {
+			errs = append(errs, *err)
+		}
 	}
-	if cfg.Messaging.ActionDelayMax == 0 {
-		cfg.Messaging.ActionDelayMax = 5 * time.Second
+
+	// Workflows build URLs as BaseURL + "/path", so keep it slash-free.
+	cfg.LinkedIn.BaseURL = strings.TrimRight(cfg.LinkedIn.BaseURL, "/")
+
+	if len(errs) > 0 {
+		return cfg, errs
 	}
-	if cfg.Database.DSN == "" {
-		cfg.Database.DSN = "file:linkedin_poc.db?_fk=1"
+	return cfg, nil
+}
+
+// decodeErrors turns the messages of a YAML type error, such as "line 5:
+// cannot unmarshal !!str `ten` into int", into field errors for the setting
+// on that line. A message whose line cannot be tied to one setting keeps the
+// file name as its path.
+func decodeErrors(file string, data []byte, typeErr *yaml.TypeError) ValidationError {
+	lines := settingLines(data)
+	var errs ValidationError
+	for _, msg := range typeErr.Errors {
+		var line int
+		rest, ok := strings.CutPrefix(msg, "line ")
+		if ok {
+			var num string
+			num, rest, ok = strings.Cut(rest, ": ")
+			line, _ = strconv.Atoi(num)
+		}
+		path := lines[line]
+		if !ok || path == "" {
+			errs = append(errs, FieldError{Path: file, Message: msg})
+			continue
+		}
+		if strings.HasPrefix(rest, "field ") && strings.Contains(rest, " not found in type ") {
+			rest = "no such setting"
+		}
+		errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("%s (%s line %d)", rest, file, line)})
 	}
+	return errs
 }
 
-func validate(cfg *Config) error {
-	if len(cfg.Search.Keywords) == 0 {
-		return errors.New("at least one search keyword must be configured")
+// settingLines maps each line of a YAML document to the path of the setting
+// written on it, such as "connect.daily_limit" or "search.keywords[1]". A
+// line holding several settings, as flow collections can, maps to the
+// setting containing them all, or to "" if there is none.
+func settingLines(data []byte) map[int]string {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
 	}
-	if cfg.Connect.DailyLimit < 0 {
-		return errors.New("connect.daily_limit cannot be negative")
+	// Paths are kept as elements (".daily_limit", "[1]") so two of them
+	// can be cut back to the setting they share.
+	lines := make(map[int][]string)
+	mark := func(line int, path []string) {
+		prev, seen := lines[line]
+		if !seen {
+			lines[line] = path
+			return
+		}
+		n := 0
+		for n < len(prev) && n < len(path) && prev[n] == path[n] {
+			n++
+		}
+		lines[line] = prev[:n]
 	}
-	if cfg.Messaging.DailyLimit < 0 {
-		return errors.New("messaging.daily_limit cannot be negative")
+	var walk func(n *yaml.Node, path []string)
+	walk = func(n *yaml.Node, path []string) {
+		// A block collection starts on its first entry's line, which
+		// belongs to that entry.
+		if len(path) > 0 && (n.Kind == yaml.ScalarNode || n.Style&yaml.FlowStyle != 0) {
+			mark(n.Line, path)
+		}
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(c, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := append(path[:len(path):len(path)], "."+n.Content[i].Value)
+				mark(n.Content[i].Line, key)
+				walk(n.Content[i+1], key)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i)))
+			}
+		}
 	}
-	return nil
-}
-
+	walk(&doc, nil)
 
+	out := make(map[int]string, len(lines))
+	for line, path := range lines {
+		out[line] = strings.TrimPrefix(strings.Join(path, ""), ".")
+	}
+	return out
+}
 
+// defaults is the bottom layer: every value a missing key falls back to.
+func defaults() *Config {
+	return &Config{
+		LinkedIn: LinkedInConfig{BaseURL: "https://www.linkedin.com"},
+		Browser:  BrowserConfig{ViewportWidth: 1366, ViewportHeight: 768},
+		Database: DatabaseConfig{DSN: "file:linkedin_poc.db?_fk=1"},
+		Session:  SessionConfig{CookieFile: "linkedin_session.enc"},
+		Checkpoint: CheckpointConfig{
+			Timeout:      5 * time.Minute,
+			RepeatWindow: 24 * time.Hour,
+			Cooldown:     48 * time.Hour,
+		},
+		Breaker: BreakerConfig{
+			LimitCooldown:       7 * 24 * time.Hour,
+			RestrictionCooldown: 14 * 24 * time.Hour,
+		},
+		Quota: QuotaConfig{Timezone: "Local", Window: "calendar"},
+		Jobs:  JobsConfig{MaxAttempts: 3, RetryBackoff: 30 * time.Second},
+		Search: SearchConfig{
+			MaxPages:     1,
+			PageDelayMin: 2 * time.Second,
+			PageDelayMax: 5 * time.Second,
+		},
+		Connect: ConnectConfig{
+			DailyLimit:     10,
+			WeeklyLimit:    100,
+			ActionDelayMin: 2 * time.Second,
+			ActionDelayMax: 5 * time.Second,
+			NoteMaxLength:  template.NoteLimit,
+		},
+		Messaging: MessagingConfig{
+			DailyLimit:     10,
+			ActionDelayMin: 2 * time.Second,
+			ActionDelayMax: 5 * time.Second,
+		},
+	}
+}
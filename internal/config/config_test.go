@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validConfig is a configuration with every setting Validate requires.
+func validConfig() *Config {
+	cfg := defaults()
+	cfg.Search.Keywords = []string{"golang developer"}
+	return cfg
+}
+
+func TestBuildDecodeErrors(t *testing.T) {
+	path := writeConfig(t, `connect:
+  daily_limit: ten
+  dialy_limit: 3
+search:
+  keywords:
+    - golang
+    - [nested]
+messaging: {daily_limit: 2, weekly_limit: many, max_per_run: few}
+`)
+	cfg, err := Build(path)
+	var errs ValidationError
+	if !errors.As(err, &errs) {
+		t.Fatalf("Build: %v, want a ValidationError", err)
+	}
+	got := make([]string, len(errs))
+	for i, fe := range errs {
+		got[i] = fe.Path
+	}
+	// A line with several settings names the one holding them.
+	want := []string{"connect.daily_limit", "connect.dialy_limit", "search.keywords[1]", "messaging", "messaging"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("error paths = %q, want %q\n%v", got, want, err)
+	}
+	if want := "no such setting (" + path + " line 3)"; errs[1].Message != want {
+		t.Errorf("unknown key message = %q, want %q", errs[1].Message, want)
+	}
+	if cfg.Messaging.DailyLimit != 2 {
+		t.Errorf("messaging.daily_limit = %d, want the rest of the file decoded", cfg.Messaging.DailyLimit)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(validConfig()); err != nil {
+		t.Fatalf("Validate(valid config): %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Config)
+		paths  []string
+	}{
+		{"base url", func(c *Config) { c.LinkedIn.BaseURL = "www.linkedin.com" }, []string{"linkedin.base_url"}},
+		{"no keywords", func(c *Config) { c.Search.Keywords = nil }, []string{"search.keywords"}},
+		{"empty keyword", func(c *Config) { c.Search.Keywords = []string{"go", " "} }, []string{"search.keywords[1]"}},
+		{"delays", func(c *Config) {
+			c.Connect.ActionDelayMin = -time.Second
+			c.Connect.ActionDelayMax = -2 * time.Second
+		}, []string{"connect.action_delay_min", "connect.action_delay_max"}},
+		{"timezone", func(c *Config) { c.Quota.Timezone = "Mars/Olympus" }, []string{"quota.timezone"}},
+		{"note placeholder", func(c *Config) { c.Connect.NoteTemplate = "Hi {{NAME}}" }, []string{"connect.note_template"}},
+		{"note too long", func(c *Config) {
+			c.Connect.NoteTemplate = "Hi {{FIRST_NAME|there}}, nice to meet you"
+			c.Connect.NoteMaxLength = 20
+		}, []string{"connect.note_template"}},
+		{"note length out of range", func(c *Config) { c.Connect.NoteMaxLength = 301 }, []string{"connect.note_max_length"}},
+		{"recontact", func(c *Config) { c.Messaging.Recontact = map[string]string{"followup": "weekly"} }, []string{"messaging.recontact.followup"}},
+		{"several", func(c *Config) {
+			c.Search.MaxPages = 0
+			c.Jobs.MaxAttempts = 0
+			c.Messaging.DailyLimit = -1
+		}, []string{"jobs.max_attempts", "search.max_pages", "messaging.daily_limit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(cfg)
+			var errs ValidationError
+			if !errors.As(Validate(cfg), &errs) {
+				t.Fatalf("Validate = nil, want errors for %v", tt.paths)
+			}
+			var got []string
+			for _, fe := range errs {
+				got = append(got, fe.Path)
+			}
+			if !reflect.DeepEqual(got, tt.paths) {
+				t.Errorf("error paths = %q, want %q (%v)", got, tt.paths, errs)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := validConfig()
+	errs := applyEnv(cfg, []string{
+		"HOME=/root",
+		"SQLITE_DSN=file:legacy.db",
+		"LIAUTO_DATABASE_DSN=file:new.db",
+		"LINKEDIN_BASE_URL=http://127.0.0.1:8099",
+		"LIAUTO_CONNECT_DAILY_LIMIT=3",
+		"LIAUTO_SEARCH_KEYWORDS=golang developer, backend engineer",
+		"LIAUTO_MESSAGING_RECONTACT_FOLLOWUP=30d",
+		"LIAUTO_BROWSER_HEADLESS=true",
+		"LIAUTO_JOBS_RETRY_BACKOFF=1m",
+		"LIAUTO_CONNECT_WEEKLY_LIMIT=lots",
+		"LIAUTO_NO_SUCH_THING=1",
+	})
+
+	if cfg.Database.DSN != "file:new.db" {
+		t.Errorf("database.dsn = %q, want the LIAUTO_ variable to win", cfg.Database.DSN)
+	}
+	if cfg.LinkedIn.BaseURL != "http://127.0.0.1:8099" {
+		t.Errorf("linkedin.base_url = %q", cfg.LinkedIn.BaseURL)
+	}
+	if cfg.Connect.DailyLimit != 3 || !cfg.Browser.Headless || cfg.Jobs.RetryBackoff != time.Minute {
+		t.Errorf("daily_limit = %d, headless = %v, retry_backoff = %s", cfg.Connect.DailyLimit, cfg.Browser.Headless, cfg.Jobs.RetryBackoff)
+	}
+	if want := []string{"golang developer", "backend engineer"}; !reflect.DeepEqual(cfg.Search.Keywords, want) {
+		t.Errorf("search.keywords = %q, want %q", cfg.Search.Keywords, want)
+	}
+	if got := cfg.Messaging.Recontact["followup"]; got != "30d" {
+		t.Errorf("messaging.recontact.followup = %q, want 30d", got)
+	}
+	if cfg.Connect.WeeklyLimit != 100 {
+		t.Errorf("connect.weekly_limit = %d, want the default kept", cfg.Connect.WeeklyLimit)
+	}
+
+	want := ValidationError{
+		{Path: "connect.weekly_limit", Message: `LIAUTO_CONNECT_WEEKLY_LIMIT: "lots" is not a whole number`},
+		{Path: "LIAUTO_NO_SUCH_THING", Message: "no such setting"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+}
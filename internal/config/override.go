@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts every environment override. The rest of the name is the
+// YAML path in upper case with dots as underscores, so connect.daily_limit
+// is LIAUTO_CONNECT_DAILY_LIMIT and messaging.recontact.followup is
+// LIAUTO_MESSAGING_RECONTACT_FOLLOWUP.
+const EnvPrefix = "LIAUTO_"
+
+// legacyEnv are the variables supported before LIAUTO_*; they are applied
+// first, so a LIAUTO_ variable for the same setting wins.
+var legacyEnv = map[string]string{
+	"SQLITE_DSN":        "database.dsn",
+	"LINKEDIN_BASE_URL": "linkedin.base_url",
+}
+
+// setting is one configurable value, addressed by its YAML path.
+type setting struct {
+	path  string
+	value reflect.Value
+}
+
+// settings lists every leaf of cfg. Maps are leaves too; their keys are set
+// with one more path element.
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			f := v.Field(i)
+			if f.Kind() == reflect.Struct {
+				walk(path, f)
+				continue
+			}
+			out = append(out, setting{path: path, value: f})
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return out
+}
+
+func envName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// applyEnv applies the legacy variables, then every LIAUTO_ variable in
+// environ (as returned by os.Environ), in name order.
+func applyEnv(cfg *Config, environ []string) ValidationError {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+
+	var errs ValidationError
+	for _, name := range sortedKeys(legacyEnv) {
+		if v := vars[name]; v != "" {
+			if err := set(cfg, legacyEnv[name], v); err != nil {
+				errs.add(legacyEnv[name], "%s: %v", name, err)
+			}
+		}
+	}
+
+	all := settings(cfg)
+	for _, name := range sortedKeys(vars) {
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		path, ok := pathForEnv(all, name)
+		if !ok {
+			errs.add(name, "no such setting")
+			continue
+		}
+		if err := set(cfg, path, vars[name]); err != nil {
+			errs.add(path, "%s: %v", name, err)
+		}
+	}
+	return errs
+}
+
+// pathForEnv finds the YAML path an environment variable name refers to.
+func pathForEnv(all []setting, name string) (string, bool) {
+	for _, s := range all {
+		env := envName(s.path)
+		if name == env {
+			return s.path, true
+		}
+		if s.value.Kind() == reflect.Map && strings.HasPrefix(name, env+"_") {
+			return s.path + "." + strings.ToLower(strings.TrimPrefix(name, env+"_")), true
+		}
+	}
+	return "", false
+}
+
+// applyOverride applies one "path=value" command-line override.
+func applyOverride(cfg *Config, override string) *FieldError {
+	path, value, ok := strings.Cut(override, "=")
+	if !ok {
+		return &FieldError{Path: override, Message: "override must look like path=value"}
+	}
+	path = strings.TrimSpace(path)
+	if err := set(cfg, path, value); err != nil {
+		return &FieldError{Path: path, Message: "-set: " + err.Error()}
+	}
+	return nil
+}
+
+// set parses value into the setting at path. Lists take comma-separated
+// values or YAML flow syntax ("[a, b]"); map entries are set one key at a
+// time ("messaging.recontact.followup").
+func set(cfg *Config, path, value string) error {
+	for _, s := range settings(cfg) {
+		if s.path == path {
+			return setValue(s.value, value)
+		}
+		if s.value.Kind() == reflect.Map && strings.HasPrefix(path, s.path+".") {
+			if s.value.IsNil() {
+				s.value.Set(reflect.MakeMap(s.value.Type()))
+			}
+			elem := reflect.New(s.value.Type().Elem()).Elem()
+			if err := setValue(elem, value); err != nil {
+				return err
+			}
+			s.value.SetMapIndex(reflect.ValueOf(strings.TrimPrefix(path, s.path+".")), elem)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such setting")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 500ms or 2m", value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var list []string
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			if err := yaml.Unmarshal([]byte(value), &list); err != nil {
+				return fmt.Errorf("%q is not a YAML list: %v", value, err)
+			}
+		} else {
+			for _, part := range strings.Split(value, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					list = append(list, part)
+				}
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case v.Kind() == reflect.Map:
+		m := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(value), m.Interface()); err != nil {
+			return fmt.Errorf("%q is not a YAML map such as {followup: 30d}: %v", value, err)
+		}
+		v.Set(m.Elem())
+	default:
+		return fmt.Errorf("cannot set a %s from text", v.Type())
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	// Embed the timezone database so quota.timezone works on machines
+	// without one, such as Windows.
+	_ "time/tzdata"
+
+	"linkedin-automation-poc/internal/template"
+)
+
+// FieldError is one problem with the configuration. Path is the YAML path of
+// the setting, such as "connect.action_delay_min" or
+// "messaging.templates[1]"; for problems with the file itself it is the
+// file name.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	if len(e) == 1 {
+		return "invalid config: " + e[0].Error()
+	}
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return fmt.Sprintf("invalid config (%d problems): %s", len(e), strings.Join(lines, "; "))
+}
+
+func (e *ValidationError) add(path, format string, args ...any) {
+	*e = append(*e, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a built configuration and returns a ValidationError with
+// every problem, or nil.
+func Validate(cfg *Config) error {
+	var errs ValidationError
+
+	if u, err := url.Parse(cfg.LinkedIn.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.add("linkedin.base_url", "%q is not an http(s) URL", cfg.LinkedIn.BaseURL)
+	}
+
+	positive(&errs, "browser.viewport_width", cfg.Browser.ViewportWidth)
+	positive(&errs, "browser.viewport_height", cfg.Browser.ViewportHeight)
+
+	if strings.TrimSpace(cfg.Database.DSN) == "" {
+		errs.add("database.dsn", "must not be empty")
+	}
+
+	if strings.TrimSpace(cfg.Session.CookieFile) == "" {
+		errs.add("session.cookie_file", "must not be empty")
+	}
+
+	if cfg.Checkpoint.Timeout < 0 {
+		errs.add("checkpoint.timeout", "cannot be negative, got %s", cfg.Checkpoint.Timeout)
+	}
+	if cfg.Checkpoint.RepeatWindow <= 0 {
+		errs.add("checkpoint.repeat_window", "must be positive, got %s", cfg.Checkpoint.RepeatWindow)
+	}
+	if cfg.Checkpoint.Cooldown <= 0 {
+		errs.add("checkpoint.cooldown", "must be positive, got %s", cfg.Checkpoint.Cooldown)
+	}
+
+	if cfg.Breaker.LimitCooldown <= 0 {
+		errs.add("breaker.limit_cooldown", "must be positive, got %s", cfg.Breaker.LimitCooldown)
+	}
+	if cfg.Breaker.RestrictionCooldown <= 0 {
+		errs.add("breaker.restriction_cooldown", "must be positive, got %s", cfg.Breaker.RestrictionCooldown)
+	}
+
+	if _, err := cfg.Quota.Location(); err != nil {
+		errs.add("quota.timezone", "unknown timezone %q: want an IANA name such as Europe/Berlin, UTC or Local", cfg.Quota.Timezone)
+	}
+	if cfg.Quota.Window != "calendar" && cfg.Quota.Window != "rolling" {
+		errs.add("quota.window", "must be calendar or rolling, got %q", cfg.Quota.Window)
+	}
+
+	if cfg.Jobs.MaxAttempts < 1 {
+		errs.add("jobs.max_attempts", "must be at least 1, got %d", cfg.Jobs.MaxAttempts)
+	}
+	if cfg.Jobs.RetryBackoff <= 0 {
+		errs.add("jobs.retry_backoff", "must be positive, got %s", cfg.Jobs.RetryBackoff)
+	}
+
+	if len(cfg.Search.Keywords) == 0 {
+		errs.add("search.keywords", "at least one search keyword must be configured")
+	}
+	for i, kw := range cfg.Search.Keywords {
+		if strings.TrimSpace(kw) == "" {
+			errs.add(fmt.Sprintf("search.keywords[%d]", i), "must not be empty")
+		}
+	}
+	positive(&errs, "search.max_pages", cfg.Search.MaxPages)
+	delayRange(&errs, "search.page_delay", cfg.Search.PageDelayMin, cfg.Search.PageDelayMax)
+
+	nonNegative(&errs, "connect.daily_limit", cfg.Connect.DailyLimit)
+	nonNegative(&errs, "connect.weekly_limit", cfg.Connect.WeeklyLimit)
+	nonNegative(&errs, "connect.max_per_run", cfg.Connect.MaxPerRun)
+	delayRange(&errs, "connect.action_delay", cfg.Connect.ActionDelayMin, cfg.Connect.ActionDelayMax)
+	validLength := true
+	if cfg.Connect.NoteMaxLength < 1 || cfg.Connect.NoteMaxLength > template.NoteLimit {
+		errs.add("connect.note_max_length", "must be between 1 and %d, got %d", template.NoteLimit, cfg.Connect.NoteMaxLength)
+		validLength = false
+	}
+	if note, err := template.Parse("connect.note_template", cfg.Connect.NoteTemplate); err != nil {
+		errs.add("connect.note_template", "%v", err)
+	} else if n := note.FallbackLength(); validLength && n > cfg.Connect.NoteMaxLength {
+		// Placeholders can only make a note longer than its fallbacks if
+		// the profile has the detail, but a note that is too long even
+		// without any would fail for every profile missing them.
+		errs.add("connect.note_template", "%d characters with placeholder fallbacks, over the %d-character limit (connect.note_max_length)", n, cfg.Connect.NoteMaxLength)
+	}
+
+	for i, text := range cfg.Messaging.Templates {
+		path := fmt.Sprintf("messaging.templates[%d]", i)
+		if strings.TrimSpace(text) == "" {
+			errs.add(path, "must not be empty")
+		} else if _, err := template.Parse(path, text); err != nil {
+			errs.add(path, "%v", err)
+		}
+	}
+	if cfg.Messaging.CheckInterval < 0 {
+		errs.add("messaging.check_interval", "cannot be negative")
+	}
+	nonNegative(&errs, "messaging.daily_limit", cfg.Messaging.DailyLimit)
+	nonNegative(&errs, "messaging.weekly_limit", cfg.Messaging.WeeklyLimit)
+	nonNegative(&errs, "messaging.max_per_run", cfg.Messaging.MaxPerRun)
+	delayRange(&errs, "messaging.action_delay", cfg.Messaging.ActionDelayMin, cfg.Messaging.ActionDelayMax)
+	for _, msgType := range sortedKeys(cfg.Messaging.Recontact) {
+		if _, err := ParseRecontactPolicy(cfg.Messaging.Recontact[msgType]); err != nil {
+			errs.add("messaging.recontact."+msgType, "%v", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func positive(errs *ValidationError, path string, v int) {
+	if v < 1 {
+		errs.add(path, "must be at least 1, got %d", v)
+	}
+}
+
+func nonNegative(errs *ValidationError, path string, v int) {
+	if v < 0 {
+		errs.add(path, "cannot be negative, got %d", v)
+	}
+}
+
+// delayRange checks a <prefix>_min/<prefix>_max pair.
+func delayRange(errs *ValidationError, prefix string, min, max time.Duration) {
+	if min < 0 {
+		errs.add(prefix+"_min", "cannot be negative, got %s", min)
+	}
+	if max < min {
+		errs.add(prefix+"_max", "%s is less than %s_min (%s)", max, prefix, min)
+	}
+}
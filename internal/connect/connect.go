@@ -2,144 +2,367 @@ package connect
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/sirupsen/logrus"
 
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/breaker"
 	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/dryrun"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/quota"
 	"linkedin-automation-poc/internal/stealth"
 	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/template"
 )
 
-// SendConnectionRequests iterates over profile URLs, enforcing a simple
-// daily limit and recording sent requests in SQLite so duplicates are
-// avoided.
+// confirmTimeout is how long to wait for the page to acknowledge an
+// invitation after clicking Send. Tests shorten it.
+var confirmTimeout = 10 * time.Second
+
+// workflowName labels this workflow's dry-run report entries.
+const workflowName = "connect"
+
+// SendConnectionRequests sends invitations to approved candidates and
+// records every attempt in SQLite so nobody is invited twice. Each attempt
+// moves through explicit states (see storage.RequestStatus); only
+// invitations the page confirmed count as sent. Profiles are canonicalized
+// before any lookup and visited on baseURL; anyone on the do-not-contact
+// list (by profile or company) is skipped.
+//
+// q enforces the daily and weekly limits: a slot is reserved before each
+// invitation and released once the outcome is recorded. Stopping at a limit
+// with candidates left returns a *quota.LimitError, which wraps
+// workflow.ErrLimitReached. guard is checked after every profile visit; a
+// lost session stops the run with its *auth.SessionError. brk refuses to
+// start during a cooldown and is checked after every attempt; a platform
+// warning stops the run with an error wrapping workflow.ErrCooldown. A
+// canceled ctx stops it with an error wrapping ctx.Err().
+//
+// With a non-nil report the run is a dry run: profiles are still visited and
+// notes rendered, but nothing is clicked or stored and every decision is
+// added to the report instead.
 func SendConnectionRequests(
 	ctx context.Context,
-	br *rod.Browser,
+	drv driver.Driver,
 	store *storage.Storage,
+	guard *auth.Guard,
+	brk *breaker.Breaker,
+	q *quota.Quota,
+	baseURL string,
 	cfg config.ConnectConfig,
-	profiles []string,
+	profiles []storage.Candidate,
+	report *dryrun.Report,
 	log *logrus.Logger,
 ) error {
-	page, err := br.Page(proto.TargetCreateTarget{URL: "about:blank"})
-	if err != nil {
-		return fmt.Errorf("open page: %w", err)
-	}
+	dryRun := report != nil
 
-	today := time.Now().Truncate(24 * time.Hour)
-	sentToday, err := store.CountRequestsSince(ctx, today)
+	note, err := cfg.Note()
 	if err != nil {
 		return err
 	}
 
-	for _, profileURL := range profiles {
+	if err := brk.Allow(ctx, time.Now()); err != nil {
+		if !dryRun {
+			return fmt.Errorf("connect: %w", err)
+		}
+		log.WithError(err).Warn("cooldown in force; a real run would send nothing")
+	}
+
+	limits := quota.Limits{Daily: cfg.DailyLimit, Weekly: cfg.WeeklyLimit}
+	var planned quota.Usage
+	if dryRun {
+		if planned, err = q.Usage(ctx, storage.QuotaConnect, limits, time.Now()); err != nil {
+			return err
+		}
+	}
+	sentThisRun := 0
+
+	for i, p := range profiles {
 		// Check if context was canceled (user closed browser, timeout, etc.)
 		select {
 		case <-ctx.Done():
 			log.WithError(ctx.Err()).Warn("context canceled, stopping connection requests")
-			return nil
+			return fmt.Errorf("connect: %w", ctx.Err())
 		default:
 		}
 
-		if sentToday >= cfg.DailyLimit {
-			log.WithField("limit", cfg.DailyLimit).Info("daily connect limit reached")
-			return nil
+		// Only people a human approved in the review queue are contacted.
+		if p.Status != storage.CandidateApproved {
+			log.WithField("profile", p.ProfileURL).WithField("candidate_status", p.Status).Debug("candidate not approved, skipping")
+			if dryRun {
+				report.Skip(workflowName, p.ProfileURL, "candidate is "+string(p.Status)+", not approved")
+			}
+			continue
+		}
+
+		profileURL, err := profileurl.Canonical(p.ProfileURL)
+		if err != nil {
+			log.WithError(err).WithField("profile", p.ProfileURL).Warn("not a profile URL, skipping")
+			if dryRun {
+				report.Skip(workflowName, p.ProfileURL, "not a profile URL")
+			}
+			continue
+		}
+
+		dnc, blocked, err := store.MatchDoNotContact(ctx, profileURL, p.Company)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to check do-not-contact list, skipping")
+			if dryRun {
+				report.Skip(workflowName, profileURL, "check do-not-contact list: "+err.Error())
+			}
+			continue
+		}
+		if blocked {
+			log.WithField("profile", profileURL).
+				WithField("dnc_kind", dnc.Kind).
+				WithField("dnc_reason", dnc.Reason).
+				Info("profile is on the do-not-contact list, skipping")
+			if dryRun {
+				report.Skip(workflowName, profileURL, "on do-not-contact list: "+string(dnc.Kind)+" "+dnc.Value)
+			}
+			continue
 		}
 
 		already, err := store.HasSentRequest(ctx, profileURL)
 		if err != nil {
 			log.WithError(err).WithField("profile", profileURL).Warn("failed to check if request already sent, skipping")
+			if dryRun {
+				report.Skip(workflowName, profileURL, "check request history: "+err.Error())
+			}
 			continue
 		}
 		if already {
+			if dryRun {
+				report.Skip(workflowName, profileURL, "connection request already recorded")
+			}
 			continue
 		}
 
-		log.WithField("profile", profileURL).Info("visiting profile to send connection request")
-		
-		// Add timeout for navigation
-		navCtx, navCancel := context.WithTimeout(ctx, 30*time.Second)
-		navDone := make(chan error, 1)
-		go func() {
-			if err := page.Navigate(profileURL); err != nil {
-				navDone <- err
-				return
-			}
-			navDone <- page.WaitLoad()
-		}()
-		
-		select {
-		case err := <-navDone:
-			navCancel()
-			if err != nil {
-				log.WithError(err).WithField("profile", profileURL).Warn("failed to navigate to profile, skipping")
-				continue
+		// Render the note before visiting the profile: a note over the
+		// limit would be rejected by the invite dialog anyway.
+		noteText, err := note.RenderWithin(noteVars(p, profileURL), cfg.NoteMaxLength)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("invitation note too long, skipping")
+			if dryRun {
+				report.Skip(workflowName, profileURL, err.Error())
 			}
-		case <-navCtx.Done():
-			navCancel()
-			log.WithError(navCtx.Err()).WithField("profile", profileURL).Warn("navigation timeout, skipping")
 			continue
 		}
 
-		// Attempt to locate a "Connect" button. LinkedIn may change its
-		// markup; this is intentionally heuristic for a PoC.
-		btn, err := page.ElementR("button", "Connect")
-		if err != nil || btn == nil {
-			log.WithField("profile", profileURL).Warn("no Connect button found")
+		if cfg.MaxPerRun > 0 && sentThisRun >= cfg.MaxPerRun {
+			log.WithField("max_per_run", cfg.MaxPerRun).Info("per-run connect cap reached")
+			return nil
+		}
+		var slot *quota.Slot
+		if dryRun {
+			err = planned.Err()
+		} else {
+			slot, err = q.Reserve(ctx, storage.QuotaConnect, limits, time.Now())
+		}
+		if err != nil {
+			var limit *quota.LimitError
+			if !errors.As(err, &limit) {
+				return fmt.Errorf("connect: %w", err)
+			}
+			log.WithField("period", limit.Period).
+				WithField("limit", limit.Limit).
+				WithField("next_free", limit.NextFree.Format(time.RFC3339)).
+				Info("connect limit reached")
+			if dryRun {
+				for _, rest := range profiles[i:] {
+					report.Skip(workflowName, rest.ProfileURL, limit.Error())
+				}
+			}
+			return fmt.Errorf("connect: %w", err)
+		}
+
+		plog := log.WithField("profile", profileURL)
+		if dryRun {
+			plog.Info("visiting profile to preview connection request (dry run)")
+			e, err := previewOne(ctx, drv, guard, baseURL, profileURL, noteText)
+			if err != nil {
+				return err
+			}
+			if err := brk.Check(ctx); err != nil {
+				return fmt.Errorf("connect: %w", err)
+			}
+			report.Add(e)
+			if e.Action == dryrun.ActionConnect {
+				planned.Take()
+				sentThisRun++
+			}
+			stealth.RandomDelay(cfg.ActionDelayMin, cfg.ActionDelayMax)
 			continue
 		}
 
-		if err := btn.Click("left", 1); err != nil {
-			log.WithError(err).WithField("profile", profileURL).Warn("failed to click Connect")
+		if err := store.SetRequestStatus(ctx, profileURL, storage.RequestQueued, "", time.Now()); err != nil {
+			plog.WithError(err).Warn("failed to queue request in storage, skipping")
+			slot.ReleaseOrLog(ctx, plog)
 			continue
 		}
 
-		// Some flows open a dialog with "Add a note".
-		addNote, _ := page.ElementR("button", "Add a note")
-		if addNote != nil {
-			_ = addNote.Click("left", 1)
-			if noteArea, _ := page.Element("textarea"); noteArea != nil {
-				note := renderTemplate(cfg.NoteTemplate, map[string]string{
-					"PROFILE_URL": profileURL,
-				})
-				if err := noteArea.Input(note); err != nil {
-					log.WithError(err).Warn("failed to fill note textarea")
-				}
+		plog.Info("visiting profile to send connection request")
+		status, reason, stop := sendOne(ctx, drv, guard, baseURL, profileURL, noteText)
+		if stop == nil {
+			// A limit dialog usually replaces the invite dialog, so the
+			// warning, not the missing button, is the reason.
+			if stop = brk.Check(ctx); stop != nil && status != storage.RequestSent {
+				status, reason = storage.RequestFailed, stop.Error()
 			}
 		}
 
-		sendBtn, _ := page.ElementR("button", "Send")
-		if sendBtn != nil {
-			_ = sendBtn.Click("left", 1)
+		if err := store.SetRequestStatus(ctx, profileURL, status, reason, time.Now()); err != nil {
+			plog.WithError(err).Warn("failed to record request outcome in storage")
+			// An unrecorded invitation keeps its slot, so it still counts.
+			if status != storage.RequestSent && status != storage.RequestUnconfirmed {
+				slot.ReleaseOrLog(ctx, plog)
+			}
+		} else {
+			slot.ReleaseOrLog(ctx, plog)
+		}
+		if stop != nil {
+			return fmt.Errorf("connect: %w", stop)
 		}
 
-		if err := store.RecordRequest(ctx, profileURL, time.Now()); err != nil {
-			log.WithError(err).WithField("profile", profileURL).Warn("failed to record request in storage, continuing anyway")
-		} else {
-			sentToday++
-			log.WithField("profile", profileURL).Info("connection request sent successfully")
+		entry := plog.WithField("status", status)
+		if reason != "" {
+			entry = entry.WithField("reason", reason)
+		}
+		switch status {
+		case storage.RequestSent:
+			sentThisRun++
+			entry.Info("connection request sent successfully")
+		case storage.RequestUnconfirmed:
+			entry.Warn("invitation not confirmed by the page, counting it as possibly sent")
+		case storage.RequestPending:
+			entry.Info("invitation already pending on profile")
+		default:
+			entry.Warn("connection request not sent")
 		}
 
 		// Think‑time between actions.
 		stealth.RandomDelay(cfg.ActionDelayMin, cfg.ActionDelayMax)
 	}
-	
-	log.WithField("total_sent", sentToday).Info("finished processing connection requests")
+
+	log.WithField("sent_this_run", sentThisRun).WithField("dry_run", dryRun).Info("finished processing connection requests")
 	return nil
 }
 
-// Simple variable replacement in templates like "Hi {{PROFILE_URL}}".
-func renderTemplate(tpl string, vars map[string]string) string {
-	out := tpl
-	for k, v := range vars {
-		out = strings.ReplaceAll(out, "{{"+k+"}}", v)
+// previewOne visits a profile and reports what sendOne would do, without
+// clicking anything. Whether LinkedIn would demand the member's email only
+// shows after clicking Connect, so a preview cannot detect it. err is only
+// set when guard reports a lost session.
+func previewOne(ctx context.Context, drv driver.Driver, guard *auth.Guard, baseURL, profileURL, note string) (dryrun.Entry, error) {
+	e := dryrun.Entry{Workflow: workflowName, Profile: profileURL, Action: dryrun.ActionSkip}
+	navURL, err := profileurl.On(baseURL, profileURL)
+	if err != nil {
+		e.Reason = "build profile URL: " + err.Error()
+		return e, nil
+	}
+	if err := drv.Navigate(ctx, navURL); err != nil {
+		e.Reason = "navigate to profile: " + err.Error()
+		return e, nil
+	}
+	if err := guard.Check(ctx, navURL); err != nil {
+		return e, fmt.Errorf("connect: %w", err)
 	}
-	return out
+	if _, found, _ := drv.FindByText("button", `^\s*Pending\s*$`); found {
+		e.Reason = "profile already shows a pending invitation"
+		return e, nil
+	}
+	if _, found, _ := drv.FindByText("button", "Connect"); !found {
+		e.Reason = "no Connect button on profile"
+		return e, nil
+	}
+	e.Action = dryrun.ActionConnect
+	e.Text = note
+	return e, nil
 }
 
+// sendOne drives the invite dialog for a single profile and returns the
+// resulting state together with a human-readable reason for anything other
+// than a confirmed send. stop is set, with a failed status, when guard
+// reports a lost session; the run must not go on.
+func sendOne(ctx context.Context, drv driver.Driver, guard *auth.Guard, baseURL, profileURL, note string) (status storage.RequestStatus, reason string, stop error) {
+	navURL, err := profileurl.On(baseURL, profileURL)
+	if err != nil {
+		return storage.RequestFailed, "build profile URL: " + err.Error(), nil
+	}
+	if err := drv.Navigate(ctx, navURL); err != nil {
+		return storage.RequestFailed, "navigate to profile: " + err.Error(), nil
+	}
+	if err := guard.Check(ctx, navURL); err != nil {
+		return storage.RequestFailed, err.Error(), err
+	}
+
+	// An invitation may already be outstanding (sent manually, or by a run
+	// that crashed before recording the outcome).
+	if _, found, _ := drv.FindByText("button", `^\s*Pending\s*$`); found {
+		return storage.RequestPending, "profile already shows a pending invitation", nil
+	}
 
+	// Attempt to locate a "Connect" button. LinkedIn may change its
+	// markup; this is intentionally heuristic for a PoC. Driver lookups
+	// do not wait for the element to appear, so a missing button cannot
+	// hang the run.
+	btn, found, err := drv.FindByText("button", "Connect")
+	if err != nil {
+		return storage.RequestFailed, "look up Connect button: " + err.Error(), nil
+	}
+	if !found {
+		return storage.RequestSkippedNoButton, "no Connect button on profile", nil
+	}
+	if err := btn.Click(); err != nil {
+		return storage.RequestFailed, "click Connect: " + err.Error(), nil
+	}
+
+	// LinkedIn sometimes refuses to send unless the member's email address
+	// is supplied; the tool never guesses it.
+	if _, found, _ := drv.Find("input[type=email]"); found {
+		return storage.RequestBlockedEmailRequired, "invite dialog asks for the member's email address", nil
+	}
+
+	// Some flows open a dialog with "Add a note".
+	if addNote, found, _ := drv.FindByText("button", "Add a note"); found {
+		_ = addNote.Click()
+		if noteArea, found, _ := drv.Find("textarea"); found {
+			if err := noteArea.Type(note); err != nil {
+				return storage.RequestFailed, "fill note: " + err.Error(), nil
+			}
+		}
+	}
+
+	sendBtn, found, _ := drv.FindByText("button", "Send")
+	if !found {
+		return storage.RequestFailed, "no Send button in invite dialog", nil
+	}
+	if err := sendBtn.Click(); err != nil {
+		return storage.RequestFailed, "click Send: " + err.Error(), nil
+	}
+
+	// Only trust the invitation once the page says so.
+	_, confirmed, err := driver.WaitForText(ctx, drv, "button", `^\s*Pending\s*$`, confirmTimeout)
+	if err != nil {
+		return storage.RequestFailed, "wait for confirmation: " + err.Error(), nil
+	}
+	if !confirmed {
+		return storage.RequestUnconfirmed, "page did not confirm the invitation", nil
+	}
+	return storage.RequestSent, "", nil
+}
+
+// noteVars are the template variables for a candidate.
+func noteVars(c storage.Candidate, profileURL string) template.Vars {
+	return template.Vars{
+		FullName:   c.Name,
+		Headline:   c.Headline,
+		Company:    c.Company,
+		Keyword:    c.Keyword,
+		ProfileURL: profileURL,
+	}
+}
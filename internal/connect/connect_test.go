@@ -0,0 +1,220 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/dryrun"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+const (
+	testBase    = "https://fake.test"
+	testProfile = "https://www.linkedin.com/in/alice/"
+	testNavURL  = testBase + "/in/alice/"
+	testLogin   = testBase + "/login"
+	testNote    = "Hi Alice"
+)
+
+func button(text string) *driver.FakeElement {
+	return driver.El([]string{"button"}, text, nil)
+}
+
+// inviteDialog makes connectBtn open the elements returned by dialog when
+// clicked, so they are not on the page before.
+func inviteDialog(connectBtn *driver.FakeElement, dialog func(f *driver.Fake) []*driver.FakeElement) *driver.FakeElement {
+	connectBtn.OnClick = func(f *driver.Fake) error {
+		f.Current().Add(dialog(f)...)
+		return nil
+	}
+	return connectBtn
+}
+
+// confirmingSend is a Send button that turns connectBtn into "Pending", as
+// LinkedIn does once the invitation went out.
+func confirmingSend(connectBtn *driver.FakeElement) *driver.FakeElement {
+	send := button("Send")
+	send.OnClick = func(*driver.Fake) error {
+		connectBtn.Text = "Pending"
+		return nil
+	}
+	return send
+}
+
+// profilePage returns the scripted profile page for a test case.
+func profilePage(name string) *driver.FakePage {
+	switch name {
+	case "no button":
+		return &driver.FakePage{Elements: []*driver.FakeElement{button("Message"), button("More")}}
+	case "pending":
+		return &driver.FakePage{Elements: []*driver.FakeElement{button("Pending")}}
+	case "logged out":
+		return &driver.FakePage{RedirectTo: testLogin}
+	case "email required":
+		return &driver.FakePage{Elements: []*driver.FakeElement{
+			inviteDialog(button("Connect"), func(*driver.Fake) []*driver.FakeElement {
+				return []*driver.FakeElement{driver.El([]string{"input[type=email]"}, "", nil), button("Send")}
+			}),
+		}}
+	case "not confirmed":
+		return &driver.FakePage{Elements: []*driver.FakeElement{
+			inviteDialog(button("Connect"), func(*driver.Fake) []*driver.FakeElement {
+				return []*driver.FakeElement{button("Send")}
+			}),
+		}}
+	case "note":
+		connectBtn := button("Connect")
+		return &driver.FakePage{Elements: []*driver.FakeElement{
+			inviteDialog(connectBtn, func(*driver.Fake) []*driver.FakeElement {
+				addNote := button("Add a note")
+				addNote.OnClick = func(f *driver.Fake) error {
+					f.Current().Add(driver.El([]string{"textarea"}, "", nil))
+					return nil
+				}
+				return []*driver.FakeElement{addNote, confirmingSend(connectBtn)}
+			}),
+		}}
+	case "no note":
+		connectBtn := button("Connect")
+		return &driver.FakePage{Elements: []*driver.FakeElement{
+			inviteDialog(connectBtn, func(*driver.Fake) []*driver.FakeElement {
+				return []*driver.FakeElement{confirmingSend(connectBtn)}
+			}),
+		}}
+	}
+	panic("unknown profile page " + name)
+}
+
+func newFake(page string) (*driver.Fake, *auth.Guard) {
+	f := driver.NewFake()
+	f.Pages[testNavURL] = profilePage(page)
+	f.Pages[testLogin] = &driver.FakePage{}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return f, auth.NewGuard(f, nil, nil, log)
+}
+
+func TestSendOne(t *testing.T) {
+	defer func(d time.Duration) { confirmTimeout = d }(confirmTimeout)
+	confirmTimeout = 300 * time.Millisecond
+
+	tests := []struct {
+		page       string
+		wantStatus storage.RequestStatus
+		wantReason string
+		wantStop   error
+		// wantAction must be among the recorded actions, noAction must not.
+		wantAction string
+		noAction   string
+	}{
+		{
+			page:       "no button",
+			wantStatus: storage.RequestSkippedNoButton,
+			wantReason: "no Connect button on profile",
+			noAction:   `click button "Message"`,
+		},
+		{
+			page:       "pending",
+			wantStatus: storage.RequestPending,
+			wantReason: "profile already shows a pending invitation",
+			noAction:   `click button "Pending"`,
+		},
+		{
+			page:       "email required",
+			wantStatus: storage.RequestBlockedEmailRequired,
+			wantReason: "invite dialog asks for the member's email address",
+			wantAction: `click button "Connect"`,
+			noAction:   `click button "Send"`,
+		},
+		{
+			page:       "not confirmed",
+			wantStatus: storage.RequestUnconfirmed,
+			wantReason: "page did not confirm the invitation",
+			wantAction: `click button "Send"`,
+		},
+		{
+			page:       "note",
+			wantStatus: storage.RequestSent,
+			wantAction: "type textarea: " + testNote,
+		},
+		{
+			page:       "no note",
+			wantStatus: storage.RequestSent,
+			wantAction: `click button "Send"`,
+		},
+		{
+			page:       "logged out",
+			wantStatus: storage.RequestFailed,
+			wantStop:   workflow.ErrLoginRequired,
+			noAction:   `click button "Connect"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			f, guard := newFake(tt.page)
+			status, reason, stop := sendOne(context.Background(), f, guard, testBase, testProfile, testNote)
+			if status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if tt.wantReason != "" && reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if tt.wantStop == nil && stop != nil || tt.wantStop != nil && !errors.Is(stop, tt.wantStop) {
+				t.Errorf("stop = %v, want %v", stop, tt.wantStop)
+			}
+			if tt.wantAction != "" && !slices.Contains(f.Actions, tt.wantAction) {
+				t.Errorf("actions %q lack %q", f.Actions, tt.wantAction)
+			}
+			if tt.noAction != "" && slices.Contains(f.Actions, tt.noAction) {
+				t.Errorf("actions %q include %q", f.Actions, tt.noAction)
+			}
+		})
+	}
+}
+
+func TestPreviewOne(t *testing.T) {
+	tests := []struct {
+		page       string
+		wantAction dryrun.Action
+		wantReason string
+		wantText   string
+		wantErr    error
+	}{
+		{page: "no button", wantAction: dryrun.ActionSkip, wantReason: "no Connect button on profile"},
+		{page: "pending", wantAction: dryrun.ActionSkip, wantReason: "profile already shows a pending invitation"},
+		// Only clicking Connect would reveal the email prompt.
+		{page: "email required", wantAction: dryrun.ActionConnect, wantText: testNote},
+		{page: "note", wantAction: dryrun.ActionConnect, wantText: testNote},
+		{page: "logged out", wantAction: dryrun.ActionSkip, wantErr: workflow.ErrLoginRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			f, guard := newFake(tt.page)
+			e, err := previewOne(context.Background(), f, guard, testBase, testProfile, testNote)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if e.Action != tt.wantAction || e.Reason != tt.wantReason || e.Text != tt.wantText {
+				t.Errorf("entry = %+v, want action %s, reason %q, text %q", e, tt.wantAction, tt.wantReason, tt.wantText)
+			}
+			if e.Profile != testProfile || e.Workflow != workflowName {
+				t.Errorf("entry = %+v, want profile %s in workflow %s", e, testProfile, workflowName)
+			}
+			// A preview never clicks or types anything.
+			for _, a := range f.Actions {
+				if a != "navigate "+testNavURL {
+					t.Errorf("preview did %q", a)
+				}
+			}
+		})
+	}
+}
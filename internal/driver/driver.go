@@ -0,0 +1,111 @@
+// Package driver is the small browser abstraction the workflows are written
+// against. Rod backs it in production (NewRod) and Fake backs it when the
+// branching logic of a workflow needs to be exercised without Chrome.
+package driver
+
+import (
+	"context"
+	"time"
+)
+
+// Driver is a single browser tab. Lookups never wait for an element to
+// appear: a missing element is reported as found == false, not as an error,
+// so workflows can branch on optional UI such as an "Add a note" button.
+type Driver interface {
+	// Navigate loads url and waits for the page to finish loading.
+	Navigate(ctx context.Context, url string) error
+	// WaitLoad waits for the current document to finish loading, e.g. after
+	// a click that triggered a navigation.
+	WaitLoad(timeout time.Duration) error
+	// URL returns the URL of the current document.
+	URL() (string, error)
+
+	// Find returns the first element matching a CSS selector.
+	Find(selector string) (Element, bool, error)
+	// FindAll returns every element matching a CSS selector.
+	FindAll(selector string) ([]Element, error)
+	// FindByText returns the first element matching selector whose text
+	// matches the JavaScript regular expression pattern.
+	FindByText(selector, pattern string) (Element, bool, error)
+
+	// Scroll skims the page for roughly d, giving lazy content time to load.
+	Scroll(d time.Duration) error
+	// Screenshot writes a PNG of the current viewport to path.
+	Screenshot(path string) error
+
+	// Cookies returns every cookie known to the browser.
+	Cookies() ([]Cookie, error)
+	// SetCookies installs cookies into the browser.
+	SetCookies(cookies []Cookie) error
+}
+
+// Element is a handle to a node on the current page.
+type Element interface {
+	Click() error
+	// Type replaces the element's value with text.
+	Type(text string) error
+	// Attribute returns the raw value of an HTML attribute; ok is false when
+	// the attribute is absent.
+	Attribute(name string) (value string, ok bool, err error)
+	// Text returns the element's visible text.
+	Text() (string, error)
+	// Find returns the first descendant matching a CSS selector.
+	Find(selector string) (Element, bool, error)
+}
+
+// Cookie is the subset of a browser cookie needed to persist and restore a
+// session. The JSON field names match the DevTools protocol so existing
+// cookie files stay readable.
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+}
+
+// pollInterval is how often the Wait helpers re-check the page.
+const pollInterval = 250 * time.Millisecond
+
+// WaitForText polls FindByText until a matching element appears, the
+// timeout elapses or ctx is canceled. found is false on timeout; only lookup
+// errors and cancellation are returned as err.
+func WaitForText(ctx context.Context, d Driver, selector, pattern string, timeout time.Duration) (Element, bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		el, found, err := d.FindByText(selector, pattern)
+		if err != nil || found {
+			return el, found, err
+		}
+		if time.Now().After(deadline) {
+			return nil, false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// WaitFor polls cond like WaitForText until it reports true, the timeout
+// elapses or ctx is canceled.
+func WaitFor(ctx context.Context, timeout time.Duration, cond func() (bool, error)) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if err != nil || ok {
+			return ok, err
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
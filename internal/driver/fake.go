@@ -0,0 +1,290 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is an in-memory, scripted Driver. Pages are registered up front by
+// URL; elements carry the selectors they answer to and optional click
+// handlers that mutate the fake (open a dialog, change a label, redirect).
+// Every interaction is appended to Actions so callers can assert on exactly
+// what a workflow did.
+type Fake struct {
+	mu sync.Mutex
+
+	// Pages maps a URL to the page served for it.
+	Pages map[string]*FakePage
+	// NavigateErr makes Navigate fail for specific URLs.
+	NavigateErr map[string]error
+	// Actions records navigations, clicks and typed text in order.
+	Actions []string
+
+	url     string
+	current *FakePage
+	cookies []Cookie
+}
+
+// FakePage is the document served for one URL.
+type FakePage struct {
+	// RedirectTo, when set, makes navigating to this page land on another
+	// registered URL instead (e.g. a login or checkpoint page).
+	RedirectTo string
+	Elements   []*FakeElement
+	// OnScroll runs when the page is scrolled. It may mutate the fake, for
+	// example by appending lazy-loaded elements.
+	OnScroll func(f *Fake) error
+}
+
+// FakeElement is a scripted node. It matches a selector when any of the
+// comma-separated parts of the selector appears verbatim in Selectors.
+// Children are only reachable through Element.Find, not page-level lookups.
+type FakeElement struct {
+	Selectors []string
+	Text      string
+	Attrs     map[string]string
+	Children  []*FakeElement
+	// OnClick runs when the element is clicked. It may mutate the fake, for
+	// example by appending elements to the current page.
+	OnClick func(f *Fake) error
+	// Typed holds the last value written with Type.
+	Typed string
+}
+
+// NewFake returns an empty Fake positioned on about:blank.
+func NewFake() *Fake {
+	return &Fake{
+		Pages:       make(map[string]*FakePage),
+		NavigateErr: make(map[string]error),
+		url:         "about:blank",
+		current:     &FakePage{},
+	}
+}
+
+// El is a convenience constructor for a FakeElement.
+func El(selectors []string, text string, attrs map[string]string) *FakeElement {
+	return &FakeElement{Selectors: selectors, Text: text, Attrs: attrs}
+}
+
+// Current returns the page the fake is currently showing.
+func (f *Fake) Current() *FakePage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.current
+}
+
+// Add appends elements to the current page; handy inside OnClick handlers.
+func (p *FakePage) Add(els ...*FakeElement) {
+	p.Elements = append(p.Elements, els...)
+}
+
+// Remove drops an element from the page, e.g. when a dialog closes.
+func (p *FakePage) Remove(el *FakeElement) {
+	for i, e := range p.Elements {
+		if e == el {
+			p.Elements = append(p.Elements[:i], p.Elements[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *Fake) record(format string, args ...any) {
+	f.Actions = append(f.Actions, fmt.Sprintf(format, args...))
+}
+
+func (f *Fake) Navigate(ctx context.Context, url string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("navigate %s", url)
+	if err := f.NavigateErr[url]; err != nil {
+		return err
+	}
+	// Follow at most a handful of redirects to avoid scripted loops.
+	for i := 0; i < 5; i++ {
+		p, ok := f.Pages[url]
+		if !ok {
+			return fmt.Errorf("fake driver: no page registered for %s", url)
+		}
+		if p.RedirectTo == "" {
+			f.url, f.current = url, p
+			return nil
+		}
+		url = p.RedirectTo
+	}
+	return fmt.Errorf("fake driver: too many redirects for %s", url)
+}
+
+func (f *Fake) WaitLoad(time.Duration) error {
+	return nil
+}
+
+func (f *Fake) URL() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.url, nil
+}
+
+func (f *Fake) Find(selector string) (Element, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, el := range f.current.Elements {
+		if el.matches(selector) {
+			return &fakeElement{f: f, el: el}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (f *Fake) FindAll(selector string) ([]Element, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []Element
+	for _, el := range f.current.Elements {
+		if el.matches(selector) {
+			out = append(out, &fakeElement{f: f, el: el})
+		}
+	}
+	return out, nil
+}
+
+func (f *Fake) FindByText(selector, pattern string) (Element, bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, el := range f.current.Elements {
+		if el.matches(selector) && re.MatchString(el.Text) {
+			return &fakeElement{f: f, el: el}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (f *Fake) Scroll(time.Duration) error {
+	f.mu.Lock()
+	f.record("scroll")
+	onScroll := f.current.OnScroll
+	f.mu.Unlock()
+
+	// The handler runs unlocked so it can call back into the fake.
+	if onScroll != nil {
+		return onScroll(f)
+	}
+	return nil
+}
+
+func (f *Fake) Screenshot(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("screenshot %s", path)
+	return nil
+}
+
+func (f *Fake) Cookies() ([]Cookie, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Cookie(nil), f.cookies...), nil
+}
+
+func (f *Fake) SetCookies(cookies []Cookie) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cookies = append([]Cookie(nil), cookies...)
+	return nil
+}
+
+func (el *FakeElement) matches(selector string) bool {
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		for _, s := range el.Selectors {
+			if s == part {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (el *FakeElement) label() string {
+	if len(el.Selectors) == 0 {
+		return el.Text
+	}
+	if el.Text == "" {
+		return el.Selectors[0]
+	}
+	return fmt.Sprintf("%s %q", el.Selectors[0], el.Text)
+}
+
+type fakeElement struct {
+	f  *Fake
+	el *FakeElement
+}
+
+func (e *fakeElement) Click() error {
+	e.f.mu.Lock()
+	e.f.record("click %s", e.el.label())
+	onClick := e.el.OnClick
+	e.f.mu.Unlock()
+
+	// The handler runs unlocked so it can call back into the fake.
+	if onClick != nil {
+		return onClick(e.f)
+	}
+	return nil
+}
+
+func (e *fakeElement) Type(text string) error {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	e.f.record("type %s: %s", e.el.label(), text)
+	e.el.Typed = text
+	return nil
+}
+
+func (e *fakeElement) Attribute(name string) (string, bool, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	v, ok := e.el.Attrs[name]
+	return v, ok, nil
+}
+
+func (e *fakeElement) Text() (string, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	if e.el.Typed != "" {
+		return e.el.Typed, nil
+	}
+	return e.el.Text, nil
+}
+
+func (e *fakeElement) Find(selector string) (Element, bool, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+	if el := findChild(e.el.Children, selector); el != nil {
+		return &fakeElement{f: e.f, el: el}, true, nil
+	}
+	return nil, false, nil
+}
+
+// findChild searches children depth-first.
+func findChild(children []*FakeElement, selector string) *FakeElement {
+	for _, c := range children {
+		if c.matches(selector) {
+			return c
+		}
+		if el := findChild(c.Children, selector); el != nil {
+			return el
+		}
+	}
+	return nil
+}
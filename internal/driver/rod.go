@@ -0,0 +1,169 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation-poc/internal/stealth"
+)
+
+// navigationTimeout bounds a single Navigate call so an unresponsive page
+// cannot stall a workflow.
+const navigationTimeout = 30 * time.Second
+
+// Rod is a Driver backed by one tab of a Rod browser.
+type Rod struct {
+	br   *rod.Browser
+	page *rod.Page
+}
+
+// NewRod opens a blank tab in br and wraps it.
+func NewRod(br *rod.Browser) (*Rod, error) {
+	page, err := br.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return nil, fmt.Errorf("open page: %w", err)
+	}
+	return &Rod{br: br, page: page}, nil
+}
+
+// Page exposes the underlying tab for the few helpers (such as the stealth
+// package) that still need raw Rod access.
+func (d *Rod) Page() *rod.Page {
+	return d.page
+}
+
+func (d *Rod) Navigate(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, navigationTimeout)
+	defer cancel()
+
+	p := d.page.Context(ctx)
+	if err := p.Navigate(url); err != nil {
+		return err
+	}
+	return p.WaitLoad()
+}
+
+func (d *Rod) WaitLoad(timeout time.Duration) error {
+	return d.page.Timeout(timeout).WaitLoad()
+}
+
+func (d *Rod) URL() (string, error) {
+	info, err := d.page.Info()
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
+
+func (d *Rod) Find(selector string) (Element, bool, error) {
+	found, el, err := d.page.Has(selector)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return rodElement{el}, true, nil
+}
+
+func (d *Rod) FindAll(selector string) ([]Element, error) {
+	els, err := d.page.Elements(selector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Element, 0, len(els))
+	for _, el := range els {
+		out = append(out, rodElement{el})
+	}
+	return out, nil
+}
+
+func (d *Rod) FindByText(selector, pattern string) (Element, bool, error) {
+	found, el, err := d.page.HasR(selector, pattern)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return rodElement{el}, true, nil
+}
+
+func (d *Rod) Scroll(dur time.Duration) error {
+	return stealth.ScrollHumanLike(d.page, dur)
+}
+
+func (d *Rod) Screenshot(path string) error {
+	b, err := d.page.Screenshot(false, nil)
+	if err != nil {
+		return fmt.Errorf("capture screenshot: %w", err)
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+func (d *Rod) Cookies() ([]Cookie, error) {
+	resp, err := proto.NetworkGetAllCookies{}.Call(d.br)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Cookie, 0, len(resp.Cookies))
+	for _, c := range resp.Cookies {
+		out = append(out, Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  float64(c.Expires),
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		})
+	}
+	return out, nil
+}
+
+func (d *Rod) SetCookies(cookies []Cookie) error {
+	params := make([]*proto.NetworkCookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		params = append(params, &proto.NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  proto.TimeSinceEpoch(c.Expires),
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		})
+	}
+	return d.br.SetCookies(params)
+}
+
+type rodElement struct {
+	el *rod.Element
+}
+
+func (e rodElement) Click() error {
+	return e.el.Click(proto.InputMouseButtonLeft, 1)
+}
+
+func (e rodElement) Type(text string) error {
+	return e.el.Input(text)
+}
+
+func (e rodElement) Attribute(name string) (string, bool, error) {
+	v, err := e.el.Attribute(name)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	return *v, true, nil
+}
+
+func (e rodElement) Text() (string, error) {
+	return e.el.Text()
+}
+
+func (e rodElement) Find(selector string) (Element, bool, error) {
+	found, el, err := e.el.Has(selector)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return rodElement{el}, true, nil
+}
@@ -0,0 +1,87 @@
+// Package dryrun collects what the workflows would have done when run with
+// --dry-run, so keyword and template changes can be reviewed before anything
+// is sent.
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Action is what a workflow would have done with a profile.
+type Action string
+
+const (
+	// ActionQueue is a search result that would be queued for review.
+	ActionQueue   Action = "queue"
+	ActionConnect Action = "connect"
+	ActionMessage Action = "message"
+	ActionSkip    Action = "skip"
+)
+
+// Entry is one line of the report.
+type Entry struct {
+	// Workflow is the step that produced the entry: search, connect or
+	// followup.
+	Workflow string `json:"workflow"`
+	Profile  string `json:"profile"`
+	Action   Action `json:"action"`
+	// Text is the rendered note or message for connect and message actions,
+	// and the person's name and headline for queue actions.
+	Text string `json:"text,omitempty"`
+	// Reason explains a skip.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report is the ordered list of entries produced by a dry run. Workflows
+// receive a nil *Report when running for real.
+type Report struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Add appends an entry.
+func (r *Report) Add(e Entry) {
+	r.Entries = append(r.Entries, e)
+}
+
+// Skip appends a skip entry.
+func (r *Report) Skip(workflow, profile, reason string) {
+	r.Add(Entry{Workflow: workflow, Profile: profile, Action: ActionSkip, Reason: reason})
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// tableTextWidth caps the rendered-text column so long messages do not wrap
+// the whole table; the JSON report always has the full text.
+const tableTextWidth = 80
+
+// WriteTable writes the report as an aligned, human-readable table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WORKFLOW\tPROFILE\tACTION\tTEXT / SKIP REASON")
+	for _, e := range r.Entries {
+		detail := e.Text
+		if e.Action == ActionSkip {
+			detail = e.Reason
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Workflow, e.Profile, e.Action, oneLine(detail, tableTextWidth))
+	}
+	return tw.Flush()
+}
+
+// oneLine flattens s onto a single line and truncates it to max runes.
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		s = string(r[:max-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testReport() *Report {
+	r := &Report{}
+	r.Add(Entry{Workflow: "search", Profile: "https://www.linkedin.com/in/jane/", Action: ActionQueue, Text: "Jane Doe – Engineer at Acme"})
+	r.Add(Entry{Workflow: "connect", Profile: "https://www.linkedin.com/in/bob/", Action: ActionConnect, Text: "Hi Bob,\n\nlet's connect!"})
+	r.Skip("followup", "https://www.linkedin.com/in/carol/", "on the do-not-contact list (customer)")
+	return r
+}
+
+func TestOneLine(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"Hi Bob,\n\n  let's\tconnect!", 80, "Hi Bob, let's connect!"},
+		{"", 10, ""},
+		{"exactly10!", 10, "exactly10!"},
+		{"eleven chars", 10, "eleven ch…"},
+		// Runes, not bytes, are counted.
+		{"Grüße aus Köln", 6, "Grüße…"},
+	}
+	for _, tt := range tests {
+		if got := oneLine(tt.s, tt.max); got != tt.want {
+			t.Errorf("oneLine(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	r := testReport()
+	long := strings.Repeat("word ", 30)
+	r.Add(Entry{Workflow: "followup", Profile: "https://www.linkedin.com/in/dan/", Action: ActionMessage, Text: long})
+	// A skip shows its reason, not the text.
+	r.Add(Entry{Workflow: "connect", Profile: "https://www.linkedin.com/in/erin/", Action: ActionSkip, Text: "rendered note", Reason: "already invited"})
+
+	var buf bytes.Buffer
+	if err := r.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"WORKFLOW PROFILE ACTION TEXT / SKIP REASON",
+		"search https://www.linkedin.com/in/jane/ queue Jane Doe – Engineer at Acme",
+		"connect https://www.linkedin.com/in/bob/ connect Hi Bob, let's connect!",
+		"followup https://www.linkedin.com/in/carol/ skip on the do-not-contact list (customer)",
+		"followup https://www.linkedin.com/in/dan/ message " + oneLine(long, tableTextWidth),
+		"connect https://www.linkedin.com/in/erin/ skip already invited",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("table =\n%s\nwant lines\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+	if n := len([]rune(oneLine(long, tableTextWidth))); n != tableTextWidth {
+		t.Errorf("long text cut to %d runes, want %d", n, tableTextWidth)
+	}
+
+	// Columns line up.
+	lines := strings.Split(buf.String(), "\n")
+	col := strings.Index(lines[0], "ACTION")
+	for _, line := range lines[1 : len(lines)-1] {
+		if !strings.HasPrefix(line[col:], "queue") && !strings.HasPrefix(line[col:], "connect") &&
+			!strings.HasPrefix(line[col:], "skip") && !strings.HasPrefix(line[col:], "message") {
+			t.Errorf("action not aligned under ACTION in %q", line)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	r := testReport()
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "entries": [
+    {
+      "workflow": "search",
+      "profile": "https://www.linkedin.com/in/jane/",
+      "action": "queue",
+      "text": "Jane Doe – Engineer at Acme"
+    },
+    {
+      "workflow": "connect",
+      "profile": "https://www.linkedin.com/in/bob/",
+      "action": "connect",
+      "text": "Hi Bob,\n\nlet's connect!"
+    },
+    {
+      "workflow": "followup",
+      "profile": "https://www.linkedin.com/in/carol/",
+      "action": "skip",
+      "reason": "on the do-not-contact list (customer)"
+    }
+  ]
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteJSON =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// The JSON keeps the full text and reads back unchanged.
+	var back Report
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&back, r) {
+		t.Errorf("read back %+v, want %+v", back, *r)
+	}
+}
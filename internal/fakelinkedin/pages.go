@@ -0,0 +1,189 @@
+package fakelinkedin
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// render executes tpl and writes it as HTML. Template errors are reported as
+// 500s so a broken fixture shows up loudly in the browser.
+func render(w http.ResponseWriter, tpl *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const layoutHead = `<!doctype html><html><head><meta charset="utf-8"><title>LinkedIn (fake)</title></head><body>
+<nav class="global-nav"><a href="/feed/">Home</a> <a href="/mynetwork/">My Network</a> <a href="/in/me/">Me</a></nav>`
+
+var loginTpl = template.Must(template.New("login").Parse(layoutHead + `
+<main>
+  <h1>Sign in</h1>
+  {{if .Error}}<div id="error-for-password">Wrong email or password.</div>{{end}}
+  <form method="post" action="/login">
+    <input id="username" name="session_key" type="text">
+    <input id="password" name="session_password" type="password">
+    <button type="submit">Sign in</button>
+  </form>
+</main></body></html>`))
+
+var feedTpl = template.Must(template.New("feed").Parse(layoutHead + `
+<main class="scaffold-layout__main"><h1>Feed</h1><p>Nothing new.</p></main></body></html>`))
+
+var searchTpl = template.Must(template.New("search").Parse(layoutHead + `
+<main>
+  <h1>People matching "{{.Keyword}}"</h1>
+  <ul class="reusable-search__entity-result-list">
+  {{range .Results}}
+    <li class="reusable-search__result-container">
+      <div class="entity-result">
+        <span class="entity-result__title-text">
+          <a class="app-aware-link" href="{{$.Base}}/in/{{.ID}}?miniProfileUrn=urn%3Ali%3Afs_miniProfile%3A{{.ID}}"><span aria-hidden="true">{{.Name}}</span></a>
+        </span>
+        <span class="entity-result__badge-text">• {{.Degree}}</span>
+        <div class="entity-result__primary-subtitle">{{.Headline}}</div>
+        <div class="entity-result__secondary-subtitle">{{.Location}}</div>
+      </div>
+    </li>
+  {{end}}
+  </ul>
+  {{if .HasNext}}
+  <button aria-label="Next" class="artdeco-pagination__button--next"
+    onclick="location.href='?keywords={{.Keyword}}&page={{.NextPage}}'">Next</button>
+  {{end}}
+</main>
+<aside class="scaffold-layout__aside">
+  <h2>People also viewed</h2>
+  <a href="{{.Base}}/in/grace-backend/">Grace Green</a>
+</aside></body></html>`))
+
+var profileTpl = template.Must(template.New("profile").Parse(layoutHead + `
+<main class="scaffold-layout__main">
+  <h1 class="text-heading-xlarge">{{.Profile.Name}}</h1>
+  <div class="text-body-medium">{{.Profile.Headline}}</div>
+  <span class="text-body-small">{{.Profile.Location}}</span>
+  <div class="pvs-profile-actions">
+  {{if .Profile.Connected}}
+    <button id="message">Message</button>
+  {{else if .Pending}}
+    <button id="connect" disabled>Pending</button>
+  {{else}}
+    <button id="connect">Connect</button>
+  {{end}}
+  </div>
+  <div id="overlay"></div>
+</main>
+<script>
+const profileId = {{.Profile.ID}};
+const requireEmail = {{.Profile.RequireEmail}};
+const thread = {{.Thread}};
+
+function post(path, body) {
+  return fetch(path, {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify(body)});
+}
+
+// The invite dialog is only created when Connect is clicked, so the "Send"
+// and "Add a note" buttons do not exist on the page beforehand.
+const connectBtn = document.getElementById("connect");
+if (connectBtn && !connectBtn.disabled) {
+  connectBtn.addEventListener("click", () => {
+    const modal = document.createElement("div");
+    modal.setAttribute("role", "dialog");
+    modal.className = "artdeco-modal send-invite";
+    if (requireEmail) {
+      modal.innerHTML = '<p>To verify this member knows you, please enter their email to connect.</p>' +
+        '<input type="email" name="email"><button id="send-invite" disabled>Send</button>';
+      document.getElementById("overlay").appendChild(modal);
+      return;
+    }
+    modal.innerHTML = '<p>You can add a note to personalize your invitation.</p>' +
+      '<button id="add-note">Add a note</button>' +
+      '<button id="send-invite">Send</button>';
+    document.getElementById("overlay").appendChild(modal);
+
+    document.getElementById("add-note").addEventListener("click", () => {
+      const ta = document.createElement("textarea");
+      ta.id = "custom-message";
+      ta.name = "message";
+      modal.insertBefore(ta, document.getElementById("send-invite"));
+    });
+    document.getElementById("send-invite").addEventListener("click", async () => {
+      const ta = document.getElementById("custom-message");
+      const res = await post("/api/invitations", {profileId, note: ta ? ta.value : ""});
+      if (res.ok) {
+        modal.remove();
+        connectBtn.textContent = "Pending";
+        connectBtn.disabled = true;
+      }
+    });
+  });
+}
+
+const messageBtn = document.getElementById("message");
+if (messageBtn) {
+  messageBtn.addEventListener("click", () => {
+    const box = document.createElement("div");
+    box.className = "msg-overlay-conversation-bubble";
+    box.innerHTML = '<ul class="msg-s-message-list"></ul>' +
+      '<textarea class="msg-form__contenteditable"></textarea>' +
+      '<button class="msg-form__send-button">Send</button>';
+    document.getElementById("overlay").appendChild(box);
+
+    // Earlier messages are listed first; the member's own messages are
+    // marked the way LinkedIn marks the other party in a thread.
+    for (const m of thread) {
+      addMessage(box, m.Body, m.Inbound);
+    }
+
+    box.querySelector("button").addEventListener("click", async () => {
+      const ta = box.querySelector("textarea");
+      const body = ta.value;
+      const res = await post("/api/messages", {profileId, body});
+      if (res.ok) {
+        addMessage(box, body, false);
+        ta.value = "";
+      }
+    });
+  });
+}
+
+function addMessage(box, body, inbound) {
+  const li = document.createElement("li");
+  li.className = inbound ? "msg-s-event-listitem msg-s-event-listitem--other" : "msg-s-event-listitem";
+  const p = document.createElement("p");
+  p.className = "msg-s-event-listitem__body";
+  p.textContent = body;
+  li.appendChild(p);
+  box.querySelector("ul").appendChild(li);
+}
+</script></body></html>`))
+
+var myNetworkTpl = template.Must(template.New("mynetwork").Parse(layoutHead + `
+<main>
+  <h1>People you may know</h1>
+  <ul class="mn-pymk-list">
+  {{range .Suggestions}}
+    <li class="discover-entity-type-card">
+      <a href="{{$.Base}}/in/{{.ID}}/">{{.Name}}</a>
+      <span class="discover-person-card__occupation">{{.Headline}}</span>
+    </li>
+  {{end}}
+  </ul>
+</main></body></html>`))
+
+var connectionsTpl = template.Must(template.New("connections").Parse(layoutHead + `
+<main>
+  <h1>{{len .Connections}} Connections</h1>
+  <ul class="mn-connections">
+  {{range .Connections}}
+    <li class="mn-connection-card">
+      <a class="mn-connection-card__link" href="{{$.Base}}/in/{{.ID}}/">
+        <span class="mn-connection-card__name">{{.Name}}</span>
+        <span class="mn-connection-card__occupation">{{.Headline}}</span>
+      </a>
+      <time class="time-badge">Connected recently</time>
+    </li>
+  {{end}}
+  </ul>
+</main></body></html>`))
@@ -0,0 +1,391 @@
+// Package fakelinkedin is a small, in-repo stand-in for the parts of
+// LinkedIn the workflows touch: login, people search, profile pages with the
+// Connect / Add a note / Send dialog, and a messaging overlay. It is built on
+// httptest so it can be started from tests or from cmd/fakelinkedin, and lets
+// the real Rod workflows run end-to-end without a live account.
+//
+// The markup only mimics the selectors and button labels the workflows rely
+// on; it is not a faithful copy of LinkedIn.
+package fakelinkedin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sessionCookie is the cookie name LinkedIn uses for its auth token.
+const sessionCookie = "li_at"
+
+// Profile is a fake member shown in search results and on /in/<ID>/.
+type Profile struct {
+	ID       string
+	Name     string
+	Headline string
+	Location string
+	// Degree is the connection degree badge, e.g. "2nd" or "3rd+".
+	Degree string
+	// Connected marks the profile as already connected to the fake user, so
+	// it shows a Message button instead of Connect.
+	Connected bool
+	// RequireEmail makes the invite dialog ask for the member's email
+	// address, as LinkedIn does for some members.
+	RequireEmail bool
+}
+
+// Options configures a Server. Zero values fall back to sensible defaults.
+type Options struct {
+	// Email and Password are the accepted credentials. When Email is empty
+	// any non-empty credentials are accepted.
+	Email    string
+	Password string
+	// Profiles seeds the member directory. DefaultProfiles is used when nil.
+	Profiles []Profile
+	// PageSize is the number of search results per page (default 5).
+	PageSize int
+	// AutoAccept accepts every invitation as soon as it is received, so the
+	// connection sync and follow-up steps have something to work on.
+	AutoAccept bool
+}
+
+// Invitation is a connection request received by the fake site.
+type Invitation struct {
+	ProfileID string
+	Note      string
+}
+
+// Message is one message in a conversation with a member. Inbound messages
+// were written by the member (see Reply); the rest were delivered through the
+// fake messaging overlay.
+type Message struct {
+	ProfileID string
+	Body      string
+	Inbound   bool
+}
+
+// Server is a running fake LinkedIn. URL (from the embedded httptest.Server)
+// is the value to use as linkedin.base_url.
+type Server struct {
+	*httptest.Server
+
+	opts Options
+
+	mu          sync.Mutex
+	profiles    []Profile
+	invitations []Invitation
+	messages    []Message
+}
+
+// DefaultProfiles returns a small directory spread across a few headlines so
+// that the keywords in config.yaml paginate over more than one page.
+func DefaultProfiles() []Profile {
+	return []Profile{
+		{ID: "alice-golang", Name: "Alice Anders", Headline: "Golang Developer at Acme", Location: "Berlin, Germany", Degree: "2nd"},
+		{ID: "bob-backend", Name: "Bob Brown", Headline: "Backend Engineer at Initech", Location: "London, United Kingdom", Degree: "2nd"},
+		{ID: "carol-go", Name: "Carol Chen", Headline: "Senior Golang Developer at Globex", Location: "Toronto, Canada", Degree: "3rd+"},
+		{ID: "dave-backend", Name: "Dave Diaz", Headline: "Backend Engineer | Go, Kafka", Location: "Madrid, Spain", Degree: "2nd"},
+		{ID: "erin-golang", Name: "Erin Evans", Headline: "Golang Developer and Mentor", Location: "Austin, Texas", Degree: "3rd+"},
+		{ID: "frank-go", Name: "Frank Fischer", Headline: "Golang Developer at Hooli", Location: "Munich, Germany", Degree: "2nd"},
+		{ID: "grace-backend", Name: "Grace Green", Headline: "Staff Backend Engineer at Umbrella", Location: "Dublin, Ireland", Degree: "2nd"},
+		{ID: "ivan-email", Name: "Ivan Ivanov", Headline: "Backend Engineer at Wonka", Location: "Sofia, Bulgaria", Degree: "3rd+", RequireEmail: true},
+		{ID: "heidi-connected", Name: "Heidi Hall", Headline: "Golang Developer at Stark", Location: "Oslo, Norway", Degree: "1st", Connected: true},
+	}
+}
+
+// New starts a fake LinkedIn on a random loopback port.
+func New(opts Options) *Server {
+	s := newServer(opts)
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// NewUnstarted returns a fake LinkedIn that has not been started yet, so the
+// caller can swap the listener (e.g. to bind a fixed address) before calling
+// Start.
+func NewUnstarted(opts Options) *Server {
+	s := newServer(opts)
+	s.Server = httptest.NewUnstartedServer(s.routes())
+	return s
+}
+
+func newServer(opts Options) *Server {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 5
+	}
+	profiles := opts.Profiles
+	if profiles == nil {
+		profiles = DefaultProfiles()
+	}
+	return &Server{
+		opts:     opts,
+		profiles: append([]Profile(nil), profiles...),
+	}
+}
+
+// Invitations returns a copy of every connection request received so far.
+func (s *Server) Invitations() []Invitation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Invitation(nil), s.invitations...)
+}
+
+// Messages returns a copy of every message delivered so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reply adds a message from the member to their conversation thread, as if
+// they had answered a follow-up.
+func (s *Server) Reply(profileID, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, Message{ProfileID: profileID, Body: body, Inbound: true})
+}
+
+// Accept marks a pending invitation as accepted, turning the profile into a
+// first-degree connection.
+func (s *Server) Accept(profileID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.findLocked(profileID); p != nil {
+		p.Connected = true
+		p.Degree = "1st"
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.handleLogin)
+	mux.HandleFunc("/feed/", s.requireSession(s.handleFeed))
+	mux.HandleFunc("/search/results/people/", s.requireSession(s.handleSearch))
+	mux.HandleFunc("/in/", s.requireSession(s.handleProfile))
+	mux.HandleFunc("/mynetwork/", s.requireSession(s.handleMyNetwork))
+	mux.HandleFunc("/mynetwork/invite-connect/connections/", s.requireSession(s.handleConnections))
+	mux.HandleFunc("/api/invitations", s.requireSession(s.handleInvite))
+	mux.HandleFunc("/api/messages", s.requireSession(s.handleMessage))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/feed/", http.StatusFound)
+	})
+	return mux
+}
+
+// requireSession redirects to /login when the session cookie is missing, the
+// same way LinkedIn bounces logged-out visitors.
+func (s *Server) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie(sessionCookie); err != nil || c.Value == "" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		render(w, loginTpl, map[string]any{"Error": r.URL.Query().Get("error")})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	email, password := r.PostForm.Get("session_key"), r.PostForm.Get("session_password")
+	ok := email != "" && password != ""
+	if s.opts.Email != "" {
+		ok = email == s.opts.Email && password == s.opts.Password
+	}
+	if !ok {
+		http.Redirect(w, r, "/login?error=1", http.StatusFound)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "fake-session", Path: "/", HttpOnly: true})
+	http.Redirect(w, r, "/feed/", http.StatusFound)
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	render(w, feedTpl, nil)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	kw := r.URL.Query().Get("keywords")
+	pageNum, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+
+	s.mu.Lock()
+	var matches []Profile
+	for _, p := range s.profiles {
+		if matchesKeyword(p, kw) {
+			matches = append(matches, p)
+		}
+	}
+	s.mu.Unlock()
+
+	start := (pageNum - 1) * s.opts.PageSize
+	end := start + s.opts.PageSize
+	if start > len(matches) {
+		start = len(matches)
+	}
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	render(w, searchTpl, map[string]any{
+		"Base":     baseURL(r),
+		"Keyword":  kw,
+		"Results":  matches[start:end],
+		"Page":     pageNum,
+		"NextPage": pageNum + 1,
+		"HasNext":  end < len(matches),
+	})
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/in/"), "/")
+	s.mu.Lock()
+	p := s.findLocked(id)
+	var profile Profile
+	if p != nil {
+		profile = *p
+	}
+	pending := s.hasInvitationLocked(id)
+	thread := []Message{}
+	for _, m := range s.messages {
+		if m.ProfileID == id {
+			thread = append(thread, m)
+		}
+	}
+	s.mu.Unlock()
+
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	render(w, profileTpl, map[string]any{
+		"Profile": profile,
+		"Pending": pending,
+		"Thread":  thread,
+	})
+}
+
+func (s *Server) handleMyNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var suggestions []Profile
+	for _, p := range s.profiles {
+		if !p.Connected {
+			suggestions = append(suggestions, p)
+		}
+	}
+	s.mu.Unlock()
+	render(w, myNetworkTpl, map[string]any{
+		"Base":        baseURL(r),
+		"Suggestions": suggestions,
+	})
+}
+
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var connections []Profile
+	for _, p := range s.profiles {
+		if p.Connected {
+			connections = append(connections, p)
+		}
+	}
+	s.mu.Unlock()
+	render(w, connectionsTpl, map[string]any{
+		"Base":        baseURL(r),
+		"Connections": connections,
+	})
+}
+
+func (s *Server) handleInvite(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ProfileID string `json:"profileId"`
+		Note      string `json:"note"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&in) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findLocked(in.ProfileID) == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.hasInvitationLocked(in.ProfileID) {
+		s.invitations = append(s.invitations, Invitation{ProfileID: in.ProfileID, Note: in.Note})
+	}
+	if s.opts.AutoAccept {
+		p := s.findLocked(in.ProfileID)
+		p.Connected, p.Degree = true, "1st"
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ProfileID string `json:"profileId"`
+		Body      string `json:"body"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&in) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findLocked(in.ProfileID)
+	if p == nil || !p.Connected {
+		http.Error(w, "not connected", http.StatusForbidden)
+		return
+	}
+	s.messages = append(s.messages, Message{ProfileID: in.ProfileID, Body: in.Body})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) findLocked(id string) *Profile {
+	for i := range s.profiles {
+		if s.profiles[i].ID == id {
+			return &s.profiles[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) hasInvitationLocked(id string) bool {
+	for _, inv := range s.invitations {
+		if inv.ProfileID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesKeyword is a deliberately loose search: a profile matches when any
+// word of the keyword appears in its name or headline.
+func matchesKeyword(p Profile, kw string) bool {
+	hay := strings.ToLower(p.Name + " " + p.Headline)
+	for _, w := range strings.Fields(strings.ToLower(kw)) {
+		if strings.Contains(hay, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseURL reconstructs the absolute site root so result links are absolute,
+// matching what LinkedIn serves.
+func baseURL(r *http.Request) string {
+	return "http://" + r.Host
+}
@@ -0,0 +1,302 @@
+package fakelinkedin_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/connect"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/fakelinkedin"
+	"linkedin-automation-poc/internal/messaging"
+	"linkedin-automation-poc/internal/network"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/quota"
+	"linkedin-automation-poc/internal/search"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+const (
+	testEmail    = "me@example.com"
+	testPassword = "secret"
+)
+
+// env is one browser tab, database and fake LinkedIn for a test.
+type env struct {
+	srv   *fakelinkedin.Server
+	drv   *driver.Rod
+	store *storage.Storage
+	guard *auth.Guard
+	quota *quota.Quota
+	log   *logrus.Logger
+}
+
+// newEnv starts a fake LinkedIn with opts and a headless browser. The test
+// is skipped with -short or when no Chrome or Chromium is installed;
+// CHROME_BIN names one outside the usual locations.
+func newEnv(t *testing.T, opts fakelinkedin.Options) *env {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts a browser")
+	}
+	bin := os.Getenv("CHROME_BIN")
+	if bin == "" {
+		var found bool
+		if bin, found = launcher.LookPath(); !found {
+			t.Skip("no browser found; set CHROME_BIN to run the integration tests")
+		}
+	}
+	u, err := launcher.New().Bin(bin).Leakless(false).Headless(true).Launch()
+	if err != nil {
+		t.Fatalf("launch browser: %v", err)
+	}
+	br := rod.New().ControlURL(u)
+	if err := br.Connect(); err != nil {
+		t.Fatalf("connect to browser: %v", err)
+	}
+	t.Cleanup(func() { br.Close() })
+	drv, err := driver.NewRod(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := fakelinkedin.New(opts)
+	t.Cleanup(srv.Close)
+	// The fake's pages link to profiles on its own host.
+	if err := profileurl.UseBaseURL(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { profileurl.UseBaseURL("") })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	dir := t.TempDir()
+	store, err := storage.New("file:"+filepath.Join(dir, "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	q, err := quota.New(store, config.QuotaConfig{Timezone: "UTC", Window: "calendar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := auth.NewSessionStore(config.SessionConfig{CookieFile: filepath.Join(dir, "cookies.enc")})
+	return &env{
+		srv:   srv,
+		drv:   drv,
+		store: store,
+		guard: auth.NewGuard(drv, sessions, nil, log),
+		quota: q,
+		log:   log,
+	}
+}
+
+func (e *env) login(t *testing.T, ctx context.Context) {
+	t.Helper()
+	if err := auth.Login(ctx, e.drv, e.guard, e.srv.URL, testEmail, testPassword, e.log); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+}
+
+func profileURL(id string) string {
+	return "https://www.linkedin.com/in/" + id + "/"
+}
+
+func approved(ids ...string) []storage.Candidate {
+	out := make([]storage.Candidate, len(ids))
+	for i, id := range ids {
+		out[i] = storage.Candidate{ProfileURL: profileURL(id), Status: storage.CandidateApproved}
+	}
+	return out
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	e := newEnv(t, fakelinkedin.Options{Email: testEmail, Password: testPassword})
+
+	err := auth.Login(ctx, e.drv, e.guard, e.srv.URL, testEmail, "wrong", e.log)
+	if !errors.Is(err, workflow.ErrLoginRequired) {
+		t.Fatalf("Login with a wrong password = %v, want ErrLoginRequired", err)
+	}
+
+	e.login(t, ctx)
+	u, err := e.drv.URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, e.srv.URL+"/feed") {
+		t.Errorf("after Login the browser is on %s, want the feed", u)
+	}
+}
+
+func TestSearchProfiles(t *testing.T) {
+	ctx := context.Background()
+	e := newEnv(t, fakelinkedin.Options{Email: testEmail, Password: testPassword, PageSize: 2})
+	e.login(t, ctx)
+
+	cfg := config.SearchConfig{Keywords: []string{"golang developer"}, MaxPages: 5}
+	results, stats, err := search.SearchProfiles(ctx, e.drv, e.store, e.guard, e.srv.URL, cfg, nil, e.log)
+	if err != nil {
+		t.Fatalf("SearchProfiles: %v", err)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.ProfileURL)
+	}
+	want := []string{
+		profileURL("alice-golang"),
+		profileURL("carol-go"),
+		profileURL("erin-golang"),
+		profileURL("frank-go"),
+		profileURL("heidi-connected"),
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if len(stats) != 3 {
+		t.Errorf("read %d pages, want 3", len(stats))
+	}
+	if len(results) > 0 && (results[0].Name != "Alice Anders" || results[0].Company != "Acme") {
+		t.Errorf("first result = %+v, want Alice Anders at Acme", results[0])
+	}
+}
+
+func TestSendConnectionRequests(t *testing.T) {
+	ctx := context.Background()
+	e := newEnv(t, fakelinkedin.Options{Email: testEmail, Password: testPassword})
+	e.login(t, ctx)
+
+	cfg := config.ConnectConfig{DailyLimit: 10, NoteTemplate: "Hi {{FIRST_NAME|there}}, let's connect.", NoteMaxLength: 300}
+	profiles := approved("alice-golang", "ivan-email")
+	profiles[0].Name = "Alice Anders"
+	if err := connect.SendConnectionRequests(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, cfg, profiles, nil, e.log); err != nil {
+		t.Fatalf("SendConnectionRequests: %v", err)
+	}
+
+	invs := e.srv.Invitations()
+	if len(invs) != 1 || invs[0].ProfileID != "alice-golang" || invs[0].Note != "Hi Alice, let's connect." {
+		t.Errorf("invitations = %+v, want one to alice-golang with the rendered note", invs)
+	}
+	for id, want := range map[string]storage.RequestStatus{
+		"alice-golang": storage.RequestSent,
+		"ivan-email":   storage.RequestBlockedEmailRequired,
+	} {
+		got, _, err := e.store.RequestStatusFor(ctx, profileURL(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("status of %s = %s, want %s", id, got, want)
+		}
+	}
+
+	// A second run finds the request recorded and sends nothing.
+	if err := connect.SendConnectionRequests(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, cfg, profiles, nil, e.log); err != nil {
+		t.Fatalf("second SendConnectionRequests: %v", err)
+	}
+	if n := len(e.srv.Invitations()); n != 1 {
+		t.Errorf("after a second run there are %d invitations, want 1", n)
+	}
+}
+
+func TestSendFollowUps(t *testing.T) {
+	ctx := context.Background()
+	e := newEnv(t, fakelinkedin.Options{Email: testEmail, Password: testPassword, AutoAccept: true})
+	e.login(t, ctx)
+
+	ccfg := config.ConnectConfig{DailyLimit: 10, NoteMaxLength: 300}
+	if err := connect.SendConnectionRequests(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, ccfg, approved("alice-golang"), nil, e.log); err != nil {
+		t.Fatalf("SendConnectionRequests: %v", err)
+	}
+	if _, err := network.SyncConnections(ctx, e.drv, e.store, e.guard, e.srv.URL, e.log); err != nil {
+		t.Fatalf("SyncConnections: %v", err)
+	}
+
+	mcfg := config.MessagingConfig{Templates: []string{"Thanks for connecting, {{FIRST_NAME|there}}!"}, DailyLimit: 10}
+	for i := 0; i < 2; i++ {
+		if err := messaging.SendFollowUps(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, mcfg, nil, nil, e.log); err != nil {
+			t.Fatalf("SendFollowUps run %d: %v", i+1, err)
+		}
+	}
+
+	// The default re-contact policy is "never", so the second run sends
+	// nothing.
+	msgs := e.srv.Messages()
+	if len(msgs) != 1 || msgs[0].ProfileID != "alice-golang" || msgs[0].Body != "Thanks for connecting, Alice!" || msgs[0].Inbound {
+		t.Errorf("messages = %+v, want one follow-up to alice-golang", msgs)
+	}
+
+	// With re-contact allowed the same text goes out again; the earlier
+	// copy in the thread must not be taken as its confirmation, but the new
+	// one is.
+	mcfg.Recontact = map[string]string{"followup": "always"}
+	if err := messaging.SendFollowUps(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, mcfg, nil, nil, e.log); err != nil {
+		t.Fatalf("SendFollowUps with re-contact: %v", err)
+	}
+	if n := len(e.srv.Messages()); n != 2 {
+		t.Errorf("after re-contact there are %d messages, want 2", n)
+	}
+	recs, err := e.store.Messages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("recorded %d messages, want 2", len(recs))
+	}
+	for _, r := range recs {
+		if r.ProfileURL != profileURL("alice-golang") || r.Status != storage.MessageSent {
+			t.Errorf("recorded %+v, want a sent follow-up to alice-golang", r)
+		}
+	}
+}
+
+func TestScanOptOuts(t *testing.T) {
+	ctx := context.Background()
+	e := newEnv(t, fakelinkedin.Options{Email: testEmail, Password: testPassword, AutoAccept: true})
+	e.login(t, ctx)
+
+	ccfg := config.ConnectConfig{DailyLimit: 10, NoteMaxLength: 300}
+	if err := connect.SendConnectionRequests(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, ccfg, approved("alice-golang"), nil, e.log); err != nil {
+		t.Fatalf("SendConnectionRequests: %v", err)
+	}
+	if _, err := network.SyncConnections(ctx, e.drv, e.store, e.guard, e.srv.URL, e.log); err != nil {
+		t.Fatalf("SyncConnections: %v", err)
+	}
+	mcfg := config.MessagingConfig{Templates: []string{"Thanks for connecting!"}, DailyLimit: 10}
+	if err := messaging.SendFollowUps(ctx, e.drv, e.store, e.guard, nil, e.quota, e.srv.URL, mcfg, nil, nil, e.log); err != nil {
+		t.Fatalf("SendFollowUps: %v", err)
+	}
+	if err := e.store.RecordMessage(ctx, profileURL("heidi-connected"), "followup", storage.MessageSent, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	e.srv.Reply("alice-golang", "Please stop messaging me.")
+	e.srv.Reply("heidi-connected", "Thanks, likewise!")
+
+	// With the default "never" policy neither thread is opened by a
+	// follow-up, so only the scan can see the reply.
+	added, err := messaging.ScanOptOuts(ctx, e.drv, e.store, e.guard, e.srv.URL, e.log)
+	if err != nil {
+		t.Fatalf("ScanOptOuts: %v", err)
+	}
+	if added != 1 {
+		t.Errorf("ScanOptOuts added %d entries, want 1", added)
+	}
+	for id, want := range map[string]bool{"alice-golang": true, "heidi-connected": false} {
+		if _, blocked, err := e.store.MatchDoNotContact(ctx, profileURL(id), ""); err != nil || blocked != want {
+			t.Errorf("%s on do-not-contact list = %v (err %v), want %v", id, blocked, err, want)
+		}
+	}
+}
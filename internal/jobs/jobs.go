@@ -0,0 +1,191 @@
+// Package jobs works through the persisted plan of a run one job at a time,
+// in order. Every change of a job's status is stored before and after it
+// runs, so a run that crashes or is interrupted resumes at the job it was
+// on, and a job that fails is retried with a growing backoff until it runs
+// out of attempts.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// Handler performs one job. An error wrapping workflow.ErrLimitReached
+// skips the job and the rest of its kind; one for which workflow.Stops is
+// true ends the run and leaves the job pending; one made by Skip skips just
+// the job; any other error is a failed attempt.
+type Handler func(ctx context.Context, job storage.Job) error
+
+// skipError is returned by a handler for a job that turned out not to be
+// needed.
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string { return e.reason }
+
+// Skip returns the error a handler gives for a job it did not need to do,
+// with the reason stored on the job.
+func Skip(reason string) error {
+	return &skipError{reason: reason}
+}
+
+// Worker runs the jobs of a plan.
+type Worker struct {
+	store    *storage.Storage
+	cfg      config.JobsConfig
+	handlers map[storage.JobKind]Handler
+	log      *logrus.Logger
+}
+
+// NewWorker returns a worker running each kind of job with its handler.
+func NewWorker(store *storage.Storage, cfg config.JobsConfig, handlers map[storage.JobKind]Handler, log *logrus.Logger) *Worker {
+	return &Worker{store: store, cfg: cfg, handlers: handlers, log: log}
+}
+
+// Run works through the pending jobs of plan planID in seq order, waiting
+// for retries that are not due yet, until none are left. Handlers may
+// append jobs to the plan as they go. It returns the error that stopped the
+// run, an error if this run gave any job up, or else the first limit that
+// was reached. When ctx is canceled it returns ctx.Err() and leaves the rest
+// of the plan for the next run.
+func (w *Worker) Run(ctx context.Context, planID int64) error {
+	started := time.Now()
+	log := w.log.WithField("plan", planID)
+	if n, err := w.store.ResetRunningJobs(ctx, planID, time.Now()); err != nil {
+		return err
+	} else if n > 0 {
+		log.WithField("jobs", n).Warn("resuming jobs that were cut short by a crash")
+	}
+
+	var limitErr error
+	for {
+		if ctx.Err() != nil {
+			log.Warn("run interrupted; the next run resumes the plan")
+			return ctx.Err()
+		}
+		plan, err := w.store.Jobs(ctx, planID)
+		if err != nil {
+			return err
+		}
+		job, wait := next(plan, time.Now())
+		if job == nil {
+			if wait.IsZero() {
+				if n := gaveUp(plan, started); n > 0 {
+					return fmt.Errorf("%d jobs failed after %d attempts; see \"app jobs\"", n, w.cfg.MaxAttempts)
+				}
+				return limitErr
+			}
+			log.WithField("until", wait.Format(time.RFC3339)).Info("waiting to retry failed jobs")
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Until(wait)):
+			}
+			continue
+		}
+		if err := w.runJob(ctx, *job); err != nil {
+			if !errors.Is(err, workflow.ErrLimitReached) {
+				return err
+			}
+			if limitErr == nil {
+				limitErr = err
+			}
+		}
+	}
+}
+
+// next returns the first pending job that is due, or else when the earliest
+// pending one will be; both are zero when nothing is pending.
+func next(plan []storage.Job, now time.Time) (*storage.Job, time.Time) {
+	var wait time.Time
+	for i, j := range plan {
+		if j.Status != storage.JobPending {
+			continue
+		}
+		if !j.NotBefore.After(now) {
+			return &plan[i], time.Time{}
+		}
+		if wait.IsZero() || j.NotBefore.Before(wait) {
+			wait = j.NotBefore
+		}
+	}
+	return nil, wait
+}
+
+// gaveUp counts the jobs of plan given up since started.
+func gaveUp(plan []storage.Job, started time.Time) int {
+	n := 0
+	for _, j := range plan {
+		if j.Status == storage.JobFailed && !j.UpdatedAt.Before(started.Truncate(time.Second)) {
+			n++
+		}
+	}
+	return n
+}
+
+// runJob runs one job and stores its outcome. It returns the error of a
+// job that stops the run or reached a limit.
+func (w *Worker) runJob(ctx context.Context, job storage.Job) error {
+	log := w.log.WithField("plan", job.PlanID).
+		WithField("job", job.Seq).
+		WithField("kind", job.Kind).
+		WithField("target", job.Target)
+	if job.Page > 0 {
+		log = log.WithField("page", job.Page)
+	}
+
+	handler, ok := w.handlers[job.Kind]
+	if !ok {
+		log.Error("no handler for job kind, giving it up")
+		return w.store.FinishJob(ctx, job.ID, storage.JobFailed, "unknown job kind", time.Now(), time.Now())
+	}
+	if err := w.store.StartJob(ctx, job.ID, time.Now()); err != nil {
+		return err
+	}
+	job.Attempts++
+	err := handler(ctx, job)
+
+	// The outcome is stored even if ctx was canceled meanwhile.
+	bg := context.Background()
+	now := time.Now()
+	switch {
+	case ctx.Err() != nil:
+		return w.store.ReturnJob(bg, job.ID, "interrupted", now)
+	case err == nil:
+		log.Debug("job done")
+		return w.store.FinishJob(bg, job.ID, storage.JobDone, "", now, now)
+	case errors.As(err, new(*skipError)):
+		log.WithField("reason", err.Error()).Debug("job skipped")
+		return w.store.FinishJob(bg, job.ID, storage.JobSkipped, err.Error(), now, now)
+	case workflow.Stops(err):
+		if rerr := w.store.ReturnJob(bg, job.ID, err.Error(), now); rerr != nil {
+			log.WithError(rerr).Warn("failed to return job to the plan")
+		}
+		return err
+	case errors.Is(err, workflow.ErrLimitReached):
+		if err := w.store.FinishJob(bg, job.ID, storage.JobSkipped, err.Error(), now, now); err != nil {
+			return err
+		}
+		n, serr := w.store.SkipJobs(bg, job.PlanID, job.Kind, "", err.Error(), now)
+		if serr != nil {
+			return serr
+		}
+		log.WithError(err).WithField("skipped", n+1).Info("limit reached, skipping the remaining jobs of this kind")
+		return err
+	case job.Attempts >= w.cfg.MaxAttempts:
+		log.WithError(err).WithField("attempts", job.Attempts).Error("job failed, giving up")
+		return w.store.FinishJob(bg, job.ID, storage.JobFailed, err.Error(), now, now)
+	default:
+		delay := w.cfg.RetryBackoff << (job.Attempts - 1)
+		log.WithError(err).WithField("attempts", job.Attempts).WithField("retry_in", delay).Warn("job failed, will retry")
+		return w.store.FinishJob(bg, job.ID, storage.JobPending, err.Error(), now.Add(delay), now)
+	}
+}
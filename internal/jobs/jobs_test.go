@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// newPlan stores jobs as a new plan in a fresh database.
+func newPlan(t *testing.T, jobs ...storage.Job) (*storage.Storage, int64) {
+	t.Helper()
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	planID, err := store.CreatePlan(context.Background(), jobs, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, planID
+}
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+var testConfig = config.JobsConfig{MaxAttempts: 3, RetryBackoff: 10 * time.Millisecond}
+
+// outcomes returns "target:status:attempts" for every job of the plan.
+func outcomes(t *testing.T, store *storage.Storage, planID int64) []string {
+	t.Helper()
+	plan, err := store.Jobs(context.Background(), planID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(plan))
+	for i, j := range plan {
+		out[i] = fmt.Sprintf("%s:%s:%d", j.Target, j.Status, j.Attempts)
+	}
+	return out
+}
+
+func checkOutcomes(t *testing.T, store *storage.Storage, planID int64, want ...string) {
+	t.Helper()
+	got := outcomes(t, store, planID)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("jobs = %q, want %q", got, want)
+	}
+}
+
+func TestWorkerRetries(t *testing.T) {
+	store, planID := newPlan(t,
+		storage.Job{Kind: storage.JobConnect, Target: "flaky"},
+		storage.Job{Kind: storage.JobConnect, Target: "broken"},
+		storage.Job{Kind: storage.JobConnect, Target: "fine"},
+	)
+	calls := make(map[string]int)
+	handler := func(ctx context.Context, job storage.Job) error {
+		calls[job.Target]++
+		if job.Attempts != calls[job.Target] {
+			t.Errorf("%s: attempt %d on call %d", job.Target, job.Attempts, calls[job.Target])
+		}
+		switch {
+		case job.Target == "broken", job.Target == "flaky" && job.Attempts == 1:
+			return errors.New("element not found")
+		}
+		return nil
+	}
+
+	w := NewWorker(store, testConfig, map[storage.JobKind]Handler{storage.JobConnect: handler}, testLogger())
+	err := w.Run(context.Background(), planID)
+	if err == nil || !strings.Contains(err.Error(), "1 jobs failed after 3 attempts") {
+		t.Errorf("Run = %v, want one job given up", err)
+	}
+	checkOutcomes(t, store, planID, "flaky:done:2", "broken:failed:3", "fine:done:1")
+}
+
+func TestWorkerSkips(t *testing.T) {
+	store, planID := newPlan(t,
+		storage.Job{Kind: storage.JobConnect, Target: "invited"},
+		storage.Job{Kind: storage.JobConnect, Target: "alice"},
+		storage.Job{Kind: storage.JobConnect, Target: "bob"},
+		storage.Job{Kind: storage.JobFollowUp, Target: "carol"},
+		storage.Job{Kind: storage.JobScanOptOuts},
+	)
+	limit := fmt.Errorf("connect: %w", workflow.ErrLimitReached)
+	w := NewWorker(store, testConfig, map[storage.JobKind]Handler{
+		storage.JobConnect: func(ctx context.Context, job storage.Job) error {
+			if job.Target == "invited" {
+				return Skip("already invited")
+			}
+			return limit
+		},
+		storage.JobFollowUp: func(ctx context.Context, job storage.Job) error { return nil },
+	}, testLogger())
+
+	// The limit only skips connect jobs; the follow-up still runs, and a
+	// kind without a handler is given up, which Run reports over the limit.
+	err := w.Run(context.Background(), planID)
+	if err == nil || !strings.Contains(err.Error(), "1 jobs failed") {
+		t.Errorf("Run = %v, want the job given up", err)
+	}
+	checkOutcomes(t, store, planID, "invited:skipped:1", "alice:skipped:1", "bob:skipped:0", "carol:done:1", ":failed:0")
+
+	plan, err := store.Jobs(context.Background(), planID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan[0].LastError != "already invited" || plan[2].LastError != limit.Error() {
+		t.Errorf("skip reasons = %q, %q", plan[0].LastError, plan[2].LastError)
+	}
+}
+
+func TestWorkerLimitReached(t *testing.T) {
+	store, planID := newPlan(t,
+		storage.Job{Kind: storage.JobConnect, Target: "alice"},
+		storage.Job{Kind: storage.JobConnect, Target: "bob"},
+	)
+	limit := fmt.Errorf("connect: %w", workflow.ErrLimitReached)
+	w := NewWorker(store, testConfig, map[storage.JobKind]Handler{
+		storage.JobConnect: func(ctx context.Context, job storage.Job) error { return limit },
+	}, testLogger())
+	if err := w.Run(context.Background(), planID); err != limit {
+		t.Errorf("Run = %v, want the limit", err)
+	}
+	checkOutcomes(t, store, planID, "alice:skipped:1", "bob:skipped:0")
+}
+
+func TestWorkerStopsAndResumes(t *testing.T) {
+	store, planID := newPlan(t,
+		storage.Job{Kind: storage.JobConnect, Target: "alice"},
+		storage.Job{Kind: storage.JobConnect, Target: "bob"},
+	)
+	ctx := context.Background()
+	checkpoint := true
+	w := NewWorker(store, testConfig, map[storage.JobKind]Handler{
+		storage.JobConnect: func(ctx context.Context, job storage.Job) error {
+			if checkpoint {
+				return fmt.Errorf("visit: %w", workflow.ErrCheckpoint)
+			}
+			return nil
+		},
+	}, testLogger())
+
+	// A checkpoint ends the run and gives the attempt back.
+	if err := w.Run(ctx, planID); !errors.Is(err, workflow.ErrCheckpoint) {
+		t.Fatalf("Run = %v, want the checkpoint", err)
+	}
+	checkOutcomes(t, store, planID, "alice:pending:0", "bob:pending:0")
+
+	// A job left running by a crash is run again, its attempt counted.
+	plan, err := store.Jobs(ctx, planID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StartJob(ctx, plan[0].ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint = false
+	if err := w.Run(ctx, planID); err != nil {
+		t.Fatalf("resumed Run: %v", err)
+	}
+	checkOutcomes(t, store, planID, "alice:done:2", "bob:done:1")
+}
+
+func TestWorkerInterrupted(t *testing.T) {
+	store, planID := newPlan(t,
+		storage.Job{Kind: storage.JobConnect, Target: "alice"},
+		storage.Job{Kind: storage.JobConnect, Target: "bob"},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewWorker(store, testConfig, map[storage.JobKind]Handler{
+		storage.JobConnect: func(ctx context.Context, job storage.Job) error {
+			cancel()
+			return ctx.Err()
+		},
+	}, testLogger())
+	if err := w.Run(ctx, planID); !errors.Is(err, context.Canceled) {
+		t.Errorf("interrupted Run = %v, want context.Canceled", err)
+	}
+	// The cut-short attempt is given back.
+	checkOutcomes(t, store, planID, "alice:pending:0", "bob:pending:0")
+}
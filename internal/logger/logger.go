@@ -27,5 +27,21 @@ func New() *logrus.Logger {
 	return log
 }
 
+// AddField makes every entry log writes carry key=value, such as the run ID
+// that ties a run's log lines to its records.
+func AddField(log *logrus.Logger, key string, value interface{}) {
+	log.AddHook(fieldHook{key: key, value: value})
+}
+
+// fieldHook adds one field to every entry.
+type fieldHook struct {
+	key   string
+	value interface{}
+}
 
+func (fieldHook) Levels() []logrus.Level { return logrus.AllLevels }
 
+func (h fieldHook) Fire(e *logrus.Entry) error {
+	e.Data[h.key] = h.value
+	return nil
+}
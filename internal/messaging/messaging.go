@@ -2,150 +2,420 @@ package messaging
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
 	"strings"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/sirupsen/logrus"
 
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/breaker"
 	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/dryrun"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/quota"
+	"linkedin-automation-poc/internal/search"
 	"linkedin-automation-poc/internal/stealth"
 	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/template"
 )
 
-// SendFollowUps is a high‑level demo that navigates to the "My Network"
-// area, identifies recently accepted connections (heuristically) and sends
-// them a follow‑up message using simple templates.
+// FollowUpType is the message_type recorded for follow-ups, and the quota
+// action they count against.
+const FollowUpType = "followup"
+
+// workflowName labels this workflow's dry-run report entries.
+const workflowName = "followup"
+
+// deliveryTimeout is how long to wait for a sent message to show up in the
+// conversation thread before treating the send as unconfirmed. Tests
+// shorten it.
+var deliveryTimeout = 10 * time.Second
+
+// SendFollowUps sends a follow‑up message, using simple templates, to
+// people who accepted an invitation this tool sent. Acceptance is recorded
+// by network.SyncConnections, which should run first. Profiles are visited
+// on baseURL. People on the do-not-contact list are skipped, and anyone whose
+// thread already contains an opt-out reply is added to it. A non-empty only
+// restricts follow-ups to those profiles. q enforces the daily and weekly
+// limits: a slot is reserved just before Send is clicked and released once
+// the message is recorded, as unconfirmed when it does not show up in the
+// thread. Stopping at a limit with people left to message returns a
+// *quota.LimitError, which wraps workflow.ErrLimitReached. guard is checked
+// after every profile visit; a lost session stops the run with its
+// *auth.SessionError. brk refuses to start during a cooldown and is checked
+// after every profile; a platform warning stops the run with an error
+// wrapping workflow.ErrCooldown. A canceled ctx stops it with an error
+// wrapping ctx.Err().
+//
+// With a non-nil report the run is a dry run: profiles are visited and
+// messages rendered, but the Message button is never clicked, nothing is
+// stored and every decision is added to the report instead. Opt-out replies
+// cannot be seen without opening the thread, so a dry run does not check them.
 func SendFollowUps(
 	ctx context.Context,
-	br *rod.Browser,
+	drv driver.Driver,
 	store *storage.Storage,
+	guard *auth.Guard,
+	brk *breaker.Breaker,
+	q *quota.Quota,
+	baseURL string,
 	cfg config.MessagingConfig,
+	only []string,
+	report *dryrun.Report,
 	log *logrus.Logger,
 ) error {
+	dryRun := report != nil
+	skip := func(profileURL, reason string) {
+		if dryRun {
+			report.Skip(workflowName, profileURL, reason)
+		}
+	}
+
 	if len(cfg.Templates) == 0 {
 		log.Warn("no messaging templates configured – skipping follow‑ups")
 		return nil
 	}
-
-	page, err := br.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	templates, err := cfg.ParsedTemplates()
 	if err != nil {
 		return err
 	}
 
-	if err := page.Navigate("https://www.linkedin.com/mynetwork/"); err != nil {
+	profileURLs, err := store.AcceptedInvitations(ctx)
+	if err != nil {
 		return err
 	}
-	if err := page.WaitLoad(); err != nil {
-		return err
+	if len(only) > 0 {
+		profileURLs = restrictTo(profileURLs, only)
+	}
+	if len(profileURLs) == 0 {
+		log.Info("no accepted invitations to follow up on")
+		return nil
 	}
+	policy := cfg.RecontactPolicyFor(FollowUpType)
 
-	// This PoC does not implement a full "newly accepted only" detector.
-	// Instead it demonstrates how one might iterate over a small number of
-	// connection cards and open the message UI.
-	connections, _ := page.Elements("a")
+	if err := brk.Allow(ctx, time.Now()); err != nil {
+		if !dryRun {
+			return fmt.Errorf("followup: %w", err)
+		}
+		log.WithError(err).Warn("cooldown in force; a real run would send nothing")
+	}
 
-	today := time.Now().Truncate(24 * time.Hour)
-	sentToday, err := store.CountMessagesSince(ctx, "followup", today)
-	if err != nil {
-		return err
+	limits := quota.Limits{Daily: cfg.DailyLimit, Weekly: cfg.WeeklyLimit}
+	var usage quota.Usage
+	if dryRun {
+		if usage, err = q.Usage(ctx, FollowUpType, limits, time.Now()); err != nil {
+			return err
+		}
 	}
+	sentThisRun := 0
 
-	for _, c := range connections {
+	for i, profileURL := range profileURLs {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
 			log.WithError(ctx.Err()).Warn("context canceled, stopping messaging")
-			return nil
+			return fmt.Errorf("followup: %w", ctx.Err())
 		default:
 		}
 
-		if sentToday >= cfg.DailyLimit {
-			log.WithField("limit", cfg.DailyLimit).Info("daily messaging limit reached")
-			return nil
+		// The previous profile's page is still open, with any warning
+		// LinkedIn showed in answer to the last message.
+		if i > 0 {
+			if err := brk.Check(ctx); err != nil {
+				return fmt.Errorf("followup: %w", err)
+			}
+		}
+
+		// Idempotency: consult the messages table before touching the
+		// profile so repeated runs do not message the same people again.
+		allowed, reason, err := recontactAllowed(ctx, store, profileURL, FollowUpType, policy)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to check message history, skipping")
+			skip(profileURL, "check message history: "+err.Error())
+			continue
+		}
+		if !allowed {
+			log.WithField("profile", profileURL).WithField("reason", reason).Debug("skipping follow-up")
+			skip(profileURL, reason)
+			continue
 		}
 
-		href, _ := c.Attribute("href")
-		if href == nil || !strings.Contains(*href, "/in/") {
+		if dnc, blocked, err := store.MatchDoNotContact(ctx, profileURL, ""); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to check do-not-contact list, skipping")
+			skip(profileURL, "check do-not-contact list: "+err.Error())
+			continue
+		} else if blocked {
+			log.WithField("profile", profileURL).
+				WithField("dnc_kind", dnc.Kind).
+				WithField("dnc_reason", dnc.Reason).
+				Info("profile is on the do-not-contact list, skipping follow-up")
+			skip(profileURL, "on do-not-contact list: "+string(dnc.Kind)+" "+dnc.Value)
 			continue
 		}
-		profileURL := *href
 
-		// Add timeout for navigation
-		navCtx, navCancel := context.WithTimeout(ctx, 30*time.Second)
-		navDone := make(chan error, 1)
-		go func() {
-			if err := page.Navigate(profileURL); err != nil {
-				navDone <- err
-				return
+		if cfg.MaxPerRun > 0 && sentThisRun >= cfg.MaxPerRun {
+			log.WithField("max_per_run", cfg.MaxPerRun).Info("per-run messaging cap reached")
+			return nil
+		}
+		if !dryRun {
+			if usage, err = q.Usage(ctx, FollowUpType, limits, time.Now()); err != nil {
+				return fmt.Errorf("followup: %w", err)
 			}
-			navDone <- page.WaitLoad()
-		}()
-		
-		select {
-		case err := <-navDone:
-			navCancel()
-			if err != nil {
-				log.WithError(err).WithField("profile", profileURL).Warn("failed to navigate to profile, skipping")
+		}
+		if err := usage.Err(); err != nil {
+			var limit *quota.LimitError
+			if !errors.As(err, &limit) {
+				return fmt.Errorf("followup: %w", err)
+			}
+			log.WithField("period", limit.Period).
+				WithField("limit", limit.Limit).
+				WithField("next_free", limit.NextFree.Format(time.RFC3339)).
+				Info("messaging limit reached")
+			for _, rest := range profileURLs[i:] {
+				skip(rest, limit.Error())
+			}
+			return fmt.Errorf("followup: %w", err)
+		}
+
+		navURL, err := profileurl.On(baseURL, profileURL)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("not a profile URL, skipping")
+			skip(profileURL, "not a profile URL")
+			continue
+		}
+		if err := drv.Navigate(ctx, navURL); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to navigate to profile, skipping")
+			skip(profileURL, "navigate to profile: "+err.Error())
+			continue
+		}
+		if err := guard.Check(ctx, navURL); err != nil {
+			return fmt.Errorf("followup: %w", err)
+		}
+
+		vars := profileVars(ctx, drv, store, profileURL)
+
+		// The company comes from the person's current headline, so company
+		// entries can only be checked once the profile is open.
+		if vars.Company != "" {
+			if dnc, blocked, err := store.MatchDoNotContact(ctx, profileURL, vars.Company); err != nil {
+				log.WithError(err).WithField("profile", profileURL).Warn("failed to check do-not-contact list, skipping")
+				skip(profileURL, "check do-not-contact list: "+err.Error())
+				continue
+			} else if blocked {
+				log.WithField("profile", profileURL).
+					WithField("dnc_kind", dnc.Kind).
+					WithField("dnc_reason", dnc.Reason).
+					Info("company is on the do-not-contact list, skipping follow-up")
+				skip(profileURL, "on do-not-contact list: "+string(dnc.Kind)+" "+dnc.Value)
 				continue
 			}
-		case <-navCtx.Done():
-			navCancel()
-			log.WithError(navCtx.Err()).WithField("profile", profileURL).Warn("navigation timeout, skipping")
+		}
+
+		msgBtn, found, err := drv.FindByText("button", "Message")
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to look up Message button, skipping")
+			skip(profileURL, "look up Message button: "+err.Error())
+			continue
+		}
+		if !found {
+			log.WithField("profile", profileURL).Warn("no Message button on profile, skipping")
+			skip(profileURL, "no Message button on profile")
 			continue
 		}
 
-		msgBtn, _ := page.ElementR("button", "Message")
-		if msgBtn == nil {
+		body := templates[rand.Intn(len(templates))].Render(vars)
+
+		if dryRun {
+			report.Add(dryrun.Entry{Workflow: workflowName, Profile: profileURL, Action: dryrun.ActionMessage, Text: body})
+			usage.Take()
+			sentThisRun++
+			stealth.RandomDelay(cfg.ActionDelayMin, cfg.ActionDelayMax)
 			continue
 		}
-		if err := msgBtn.Click("left", 1); err != nil {
+
+		if err := msgBtn.Click(); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to click Message, skipping")
+			continue
+		}
+
+		// Someone who already asked us to stop is added to the
+		// do-not-contact list instead of being messaged again.
+		if reply, optedOut, err := findOptOutReply(drv); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to read conversation thread")
+		} else if optedOut {
+			if err := store.AddDoNotContact(ctx, storage.DoNotContactProfile, profileURL, "opt-out reply: "+snippet(reply), time.Now()); err != nil {
+				log.WithError(err).WithField("profile", profileURL).Warn("failed to add opt-out to do-not-contact list")
+			} else {
+				log.WithField("profile", profileURL).Info("opt-out reply found, added to do-not-contact list")
+			}
 			continue
 		}
 
 		// Locate the message textarea / editor – highly simplified.
-		editor, _ := page.Element("div[role=textbox], textarea")
-		if editor == nil {
+		editor, found, err := drv.Find("div[role=textbox], textarea")
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to look up message editor, skipping")
+			continue
+		}
+		if !found {
+			log.WithField("profile", profileURL).Warn("no message editor in overlay, skipping")
+			continue
+		}
+
+		if err := editor.Type(body); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to type message, skipping")
 			continue
 		}
 
-		tpl := cfg.Templates[rand.Intn(len(cfg.Templates))]
-		body := renderTemplate(tpl, map[string]string{
-			"PROFILE_URL": profileURL,
-		})
-		if err := editor.Input(body); err != nil {
+		sendBtn, found, _ := drv.FindByText("button", "Send")
+		if !found {
+			log.WithField("profile", profileURL).Warn("no Send button in message overlay")
+			continue
+		}
+		// Messages already in the thread, such as an earlier identical
+		// follow-up, must not count as confirmation.
+		before, err := countOutbound(drv)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to read conversation thread, skipping")
+			continue
+		}
+		slot, err := q.Reserve(ctx, FollowUpType, limits, time.Now())
+		if err != nil {
+			return fmt.Errorf("followup: %w", err)
+		}
+		if err := sendBtn.Click(); err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to click Send")
+			slot.ReleaseOrLog(ctx, log)
 			continue
 		}
 
-		sendBtn, _ := page.ElementR("button", "Send")
-		if sendBtn != nil {
-			_ = sendBtn.Click("left", 1)
+		// A message that does not show up in the thread may still have gone
+		// out, so it is recorded as unconfirmed rather than sent again by
+		// the next run.
+		status := storage.MessageSent
+		if delivered, err := verifyDelivered(ctx, drv, body, before); err != nil || !delivered {
+			log.WithError(err).WithField("profile", profileURL).Warn("message not confirmed in thread, recording it as unconfirmed")
+			status = storage.MessageUnconfirmed
 		}
 
-		if err := store.RecordMessage(ctx, profileURL, "followup", time.Now()); err != nil {
+		if err := store.RecordMessage(ctx, profileURL, FollowUpType, status, time.Now()); err != nil {
+			// An unrecorded message keeps its slot, so it still counts.
 			log.WithError(err).WithField("profile", profileURL).Warn("failed to record message in storage, continuing anyway")
 		} else {
-			sentToday++
-			log.WithField("profile", profileURL).Info("follow-up message sent successfully")
+			slot.ReleaseOrLog(ctx, log)
+			sentThisRun++
+			if status == storage.MessageSent {
+				log.WithField("profile", profileURL).Info("follow-up message sent successfully")
+			}
 		}
 
 		stealth.RandomDelay(cfg.ActionDelayMin, cfg.ActionDelayMax)
 	}
+	if err := brk.Check(ctx); err != nil {
+		return fmt.Errorf("followup: %w", err)
+	}
 	
-	log.WithField("total_sent", sentToday).Info("finished processing follow-up messages")
+	log.WithField("sent_this_run", sentThisRun).WithField("dry_run", dryRun).Info("finished processing follow-up messages")
 	return nil
 }
 
-// shared simple templating helper
-func renderTemplate(tpl string, vars map[string]string) string {
-	out := tpl
-	for k, v := range vars {
-		out = strings.ReplaceAll(out, "{{"+k+"}}", v)
+// restrictTo keeps the profiles in urls that also appear, in any URL form,
+// in only.
+func restrictTo(urls, only []string) []string {
+	want := make(map[string]bool, len(only))
+	for _, ref := range only {
+		if u, err := profileurl.Canonical(ref); err == nil {
+			want[u] = true
+		}
+	}
+	var out []string
+	for _, u := range urls {
+		if want[u] {
+			out = append(out, u)
+		}
 	}
 	return out
 }
 
+// recontactAllowed applies a re-contact policy to a profile's message
+// history. reason explains a refusal for logging.
+func recontactAllowed(ctx context.Context, store *storage.Storage, profileURL, msgType string, policy config.RecontactPolicy) (bool, string, error) {
+	last, found, err := store.LastMessageAt(ctx, profileURL, msgType)
+	if err != nil {
+		return false, "", err
+	}
+	if !found {
+		return true, "", nil
+	}
+	if policy.Never {
+		return false, "already messaged (policy: never)", nil
+	}
+	if since := time.Since(last); since < policy.MinGap {
+		return false, fmt.Sprintf("messaged %s ago (policy: wait %s)", since.Round(time.Hour), policy.MinGap), nil
+	}
+	return true, "", nil
+}
+
+// countOutbound returns how many of our own messages the open conversation
+// thread shows.
+func countOutbound(drv driver.Driver) (int, error) {
+	els, err := drv.FindAll(outboundMessageSelector)
+	return len(els), err
+}
+
+// verifyDelivered waits for a message starting like body to appear in the
+// open conversation thread after the first before of our own messages.
+func verifyDelivered(ctx context.Context, drv driver.Driver, body string, before int) (bool, error) {
+	want := snippet(body)
+	return driver.WaitFor(ctx, deliveryTimeout, func() (bool, error) {
+		els, err := drv.FindAll(outboundMessageSelector)
+		if err != nil || len(els) <= before {
+			return false, err
+		}
+		for _, el := range els[before:] {
+			if text, err := el.Text(); err == nil && strings.HasPrefix(strings.TrimSpace(text), want) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
 
+// snippet returns at most the first 60 characters of a message.
+func snippet(body string) string {
+	s := strings.TrimSpace(body)
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:60])
+	}
+	return s
+}
 
+// profileVars collects the template variables for a profile: what search
+// stored about the candidate, completed from the open profile page.
+func profileVars(ctx context.Context, drv driver.Driver, store *storage.Storage, profileURL string) template.Vars {
+	v := template.Vars{ProfileURL: profileURL}
+	if c, found, err := store.CandidateFor(ctx, profileURL); err == nil && found {
+		v.FullName, v.Headline, v.Company, v.Keyword = c.Name, c.Headline, c.Company, c.Keyword
+	}
+	if v.FullName == "" {
+		if el, found, _ := drv.Find("h1"); found {
+			v.FullName, _ = el.Text()
+			v.FullName = strings.TrimSpace(v.FullName)
+		}
+	}
+	// The page shows the current headline, which may be newer than the
+	// one found by search.
+	if el, found, _ := drv.Find(".text-body-medium"); found {
+		if text, _ := el.Text(); strings.TrimSpace(text) != "" {
+			v.Headline = strings.TrimSpace(text)
+			if company := search.CompanyFromHeadline(v.Headline); company != "" {
+				v.Company = company
+			}
+		}
+	}
+	return v
+}
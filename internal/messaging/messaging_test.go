@@ -0,0 +1,72 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"linkedin-automation-poc/internal/driver"
+)
+
+func TestVerifyDelivered(t *testing.T) {
+	defer func(d time.Duration) { deliveryTimeout = d }(deliveryTimeout)
+	deliveryTimeout = 300 * time.Millisecond
+
+	const body = "Thanks for connecting, Alice!"
+	outbound := func(text string) *driver.FakeElement {
+		return driver.El([]string{outboundMessageSelector}, text, nil)
+	}
+	inbound := func(text string) *driver.FakeElement {
+		return driver.El([]string{inboundMessageSelector}, text, nil)
+	}
+
+	tests := []struct {
+		name string
+		// thread is on the page before Send; sent is added by Send.
+		thread, sent []*driver.FakeElement
+		want         bool
+	}{
+		{
+			name: "new message",
+			sent: []*driver.FakeElement{outbound(body)},
+			want: true,
+		},
+		{
+			name:   "identical earlier message",
+			thread: []*driver.FakeElement{outbound(body)},
+			sent:   []*driver.FakeElement{outbound(body)},
+			want:   true,
+		},
+		{
+			name:   "only the earlier message",
+			thread: []*driver.FakeElement{outbound(body)},
+		},
+		{
+			name:   "quoted in a reply",
+			thread: []*driver.FakeElement{outbound("Hello")},
+			sent:   []*driver.FakeElement{inbound(body)},
+		},
+		{
+			name: "other text",
+			sent: []*driver.FakeElement{outbound("Something else")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := driver.NewFake()
+			f.Current().Add(tt.thread...)
+			before, err := countOutbound(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Current().Add(tt.sent...)
+			got, err := verifyDelivered(context.Background(), f, body, before)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("verifyDelivered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// optOutScanWindow is how far back ScanOptOuts looks for people messaged;
+// replies to older messages are still caught when a follow-up is due.
+const optOutScanWindow = 30 * 24 * time.Hour
+
+// inboundMessageSelector matches the bodies of messages written by the other
+// person in an open conversation thread.
+const inboundMessageSelector = ".msg-s-event-listitem--other .msg-s-event-listitem__body"
+
+// outboundMessageSelector matches the bodies of our own messages in an open
+// conversation thread.
+const outboundMessageSelector = ".msg-s-event-listitem:not(.msg-s-event-listitem--other) .msg-s-event-listitem__body"
+
+// optOutRe recognizes replies asking not to be contacted again. It is
+// deliberately broad: a false positive only costs one follow-up.
+var optOutRe = regexp.MustCompile(`(?i)\b(` + strings.Join([]string{
+	`unsubscribe`,
+	`opt[- ]?out`,
+	`please stop`,
+	`stop (messaging|contacting|emailing|sending)`,
+	`(do not|don'?t|dont) (contact|message|email) me`,
+	`remove me`,
+	`not interested`,
+	`leave me alone`,
+	`no more messages`,
+}, "|") + `)\b`)
+
+// negatedRe matches a negation just before an optOutRe match, as in "don't
+// stop messaging me".
+var negatedRe = regexp.MustCompile(`(?i)\b(do not|don'?t|dont|never)\s+$`)
+
+// IsOptOutReply reports whether a message asks not to be contacted again.
+func IsOptOutReply(text string) bool {
+	for _, m := range optOutRe.FindAllStringIndex(text, -1) {
+		if !negatedRe.MatchString(text[:m[0]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// findOptOutReply returns the first message from the other person in the open
+// thread that asks not to be contacted again.
+func findOptOutReply(drv driver.Driver) (string, bool, error) {
+	els, err := drv.FindAll(inboundMessageSelector)
+	if err != nil {
+		return "", false, err
+	}
+	for _, el := range els {
+		text, err := el.Text()
+		if err != nil {
+			continue
+		}
+		if IsOptOutReply(text) {
+			return strings.TrimSpace(text), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// ScanOptOuts opens the conversation with everyone messaged in the last
+// optOutScanWindow who is not yet on the do-not-contact list, and adds
+// those who replied asking not to be contacted again. It does not depend on
+// the re-contact policy, which would otherwise keep the threads of people
+// already messaged closed. Profiles are visited on baseURL. A lost session
+// stops the scan with its *auth.SessionError and a canceled ctx with
+// ctx.Err(); other failures only skip the profile. added counts the new do-not-contact entries.
+func ScanOptOuts(ctx context.Context, drv driver.Driver, store *storage.Storage, guard *auth.Guard, baseURL string, log *logrus.Logger) (added int, err error) {
+	profileURLs, err := store.MessagedProfiles(ctx, time.Now().Add(-optOutScanWindow))
+	if err != nil {
+		return 0, err
+	}
+	for _, profileURL := range profileURLs {
+		select {
+		case <-ctx.Done():
+			log.WithError(ctx.Err()).Warn("context canceled, stopping opt-out scan")
+			return added, ctx.Err()
+		default:
+		}
+
+		plog := log.WithField("profile", profileURL)
+		navURL, err := profileurl.On(baseURL, profileURL)
+		if err != nil {
+			plog.WithError(err).Warn("not a profile URL, skipping")
+			continue
+		}
+		if err := drv.Navigate(ctx, navURL); err != nil {
+			plog.WithError(err).Warn("failed to navigate to profile, skipping")
+			continue
+		}
+		if err := guard.Check(ctx, navURL); err != nil {
+			return added, fmt.Errorf("scan opt-outs: %w", err)
+		}
+		msgBtn, found, _ := drv.FindByText("button", "Message")
+		if !found {
+			plog.Debug("no Message button on profile, skipping opt-out check")
+			continue
+		}
+		if err := msgBtn.Click(); err != nil {
+			plog.WithError(err).Warn("failed to open conversation thread")
+			continue
+		}
+		reply, optedOut, err := findOptOutReply(drv)
+		if err != nil {
+			plog.WithError(err).Warn("failed to read conversation thread")
+			continue
+		}
+		if !optedOut {
+			continue
+		}
+		if err := store.AddDoNotContact(ctx, storage.DoNotContactProfile, profileURL, "opt-out reply: "+snippet(reply), time.Now()); err != nil {
+			plog.WithError(err).Warn("failed to add opt-out to do-not-contact list")
+			continue
+		}
+		added++
+		plog.Info("opt-out reply found, added to do-not-contact list")
+	}
+	log.WithField("checked", len(profileURLs)).WithField("added", added).Info("finished scanning for opt-out replies")
+	return added, nil
+}
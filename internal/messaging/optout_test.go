@@ -0,0 +1,41 @@
+package messaging
+
+import "testing"
+
+func TestIsOptOutReply(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Please stop.", true},
+		{"please STOP messaging me", true},
+		{"Stop contacting me, thanks", true},
+		{"Don't contact me again", true},
+		{"dont message me", true},
+		{"Do not email me", true},
+		{"How do I unsubscribe?", true},
+		{"I'd like to opt-out", true},
+		{"Please remove me from your list", true},
+		{"Thanks, but not interested.", true},
+		{"Just leave me alone", true},
+		{"No more messages please", true},
+		// Negated requests keep the conversation going.
+		{"Don't stop!", false},
+		{"Never stop messaging me, I love it", false},
+		{"Do not unsubscribe me", false},
+		// A negated phrase does not hide a later request.
+		{"Don't get me wrong, but please stop", true},
+		{"Sure, let's talk next week", false},
+		{"Thanks for reaching out!", false},
+		{"The bus stop near the office", false},
+		{"I stopped by your booth", false},
+		{"We are interested", false},
+		{"optimistic about Go", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsOptOutReply(tt.text); got != tt.want {
+			t.Errorf("IsOptOutReply(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
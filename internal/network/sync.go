@@ -0,0 +1,144 @@
+// Package network reads the logged-in user's "My Network" area. Its sync
+// step is what tells the rest of the tool who actually accepted an
+// invitation, as opposed to who merely appears as a suggestion.
+package network
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// connectionsPath is the user's own first-degree connections list.
+const connectionsPath = "/mynetwork/invite-connect/connections/"
+
+// connectionCardLink matches the profile link of each card in the list.
+const connectionCardLink = "a.mn-connection-card__link"
+
+const (
+	// scrollStep is how long each skim of the list lasts; it also gives the
+	// next batch of cards time to load.
+	scrollStep = 2 * time.Second
+	// maxScrolls bounds the skimming of a very long list.
+	maxScrolls = 30
+)
+
+// SyncResult summarises a connection sync.
+type SyncResult struct {
+	// Seen is the number of connections read from the list.
+	Seen int
+	// New is how many of them had not been seen before.
+	New int
+	// Accepted is how many sent_requests rows were moved to accepted.
+	Accepted int
+}
+
+// SyncConnections reads the user's connections list, records every
+// connection with the date it was first seen and marks matching
+// sent_requests rows as accepted. The list is scrolled until no more cards
+// load, at most maxScrolls times. A lost session (see auth.Guard) is
+// returned as an error rather than read as an empty list, and a canceled ctx
+// stops the scrolling with ctx.Err().
+func SyncConnections(ctx context.Context, drv driver.Driver, store *storage.Storage, guard *auth.Guard, baseURL string, log *logrus.Logger) (SyncResult, error) {
+	var res SyncResult
+
+	listURL := baseURL + connectionsPath
+	if err := drv.Navigate(ctx, listURL); err != nil {
+		return res, err
+	}
+	if err := guard.Check(ctx, listURL); err != nil {
+		return res, err
+	}
+
+	links, err := loadConnectionCards(ctx, drv, log)
+	if err != nil {
+		return res, err
+	}
+
+	now := time.Now()
+	for _, a := range links {
+		href, ok, _ := a.Attribute("href")
+		if !ok {
+			continue
+		}
+		profileURL, err := profileurl.Canonical(href)
+		if err != nil {
+			continue
+		}
+		res.Seen++
+
+		text, _ := a.Text()
+		isNew, err := store.RecordConnection(ctx, profileURL, firstLine(text), now)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to record connection")
+			continue
+		}
+		if isNew {
+			res.New++
+		}
+
+		accepted, err := store.MarkRequestAccepted(ctx, profileURL, now)
+		if err != nil {
+			log.WithError(err).WithField("profile", profileURL).Warn("failed to mark request accepted")
+			continue
+		}
+		if accepted {
+			res.Accepted++
+			log.WithField("profile", profileURL).Info("invitation accepted")
+		}
+	}
+
+	log.WithField("seen", res.Seen).
+		WithField("new", res.New).
+		WithField("accepted", res.Accepted).
+		Info("connection sync completed")
+	return res, nil
+}
+
+// loadConnectionCards scrolls the lazy-loaded list until the number of cards
+// stops growing and returns their links.
+func loadConnectionCards(ctx context.Context, drv driver.Driver, log *logrus.Logger) ([]driver.Element, error) {
+	links, err := drv.FindAll(connectionCardLink)
+	if err != nil {
+		return nil, err
+	}
+	for scrolls := 0; ; scrolls++ {
+		if scrolls == maxScrolls {
+			log.WithField("cards", len(links)).Warn("connections list still growing, reading the cards loaded so far")
+			return links, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := drv.Scroll(scrollStep); err != nil {
+			log.WithError(err).Warn("failed to scroll connections list")
+			return links, nil
+		}
+		more, err := drv.FindAll(connectionCardLink)
+		if err != nil {
+			return nil, err
+		}
+		grew := len(more) > len(links)
+		links = more
+		if !grew {
+			log.WithField("cards", len(links)).WithField("scrolls", scrolls+1).Debug("connections list loaded")
+			return links, nil
+		}
+	}
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
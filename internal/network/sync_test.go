@@ -0,0 +1,188 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/auth"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/storage"
+)
+
+const testBaseURL = "https://www.linkedin.com"
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func newTestStorage(t *testing.T) *storage.Storage {
+	t.Helper()
+	s, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// connectionCard is the profile link of one card in the connections list.
+func connectionCard(href, name string) *driver.FakeElement {
+	return driver.El([]string{connectionCardLink}, "\n  "+name+"\n  Engineer\n", map[string]string{"href": href})
+}
+
+// newConnectionsPage serves the connections list with cards, plus the ones
+// loaded tells it to add on each scroll.
+func newConnectionsPage(f *driver.Fake, cards []*driver.FakeElement, loaded func(scroll int) []*driver.FakeElement) {
+	scrolls := 0
+	f.Pages[testBaseURL+connectionsPath] = &driver.FakePage{
+		Elements: cards,
+		OnScroll: func(f *driver.Fake) error {
+			scrolls++
+			f.Current().Add(loaded(scrolls)...)
+			return nil
+		},
+	}
+}
+
+func scrollCount(f *driver.Fake) int {
+	n := 0
+	for _, a := range f.Actions {
+		if a == "scroll" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSyncConnections(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	profile := func(id string) string { return "https://www.linkedin.com/in/" + id + "/" }
+	for id, status := range map[string]storage.RequestStatus{"alice": storage.RequestSent, "bob": storage.RequestUnconfirmed, "dan": storage.RequestSent} {
+		if err := store.SetRequestStatus(ctx, profile(id), storage.RequestQueued, "", at); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetRequestStatus(ctx, profile(id), status, "", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Carol was already synced, and connected without the tool.
+	if _, err := store.RecordConnection(ctx, profile("carol"), "Carol", at); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two more batches of cards load while the list is scrolled.
+	f := driver.NewFake()
+	newConnectionsPage(f, []*driver.FakeElement{
+		connectionCard("/in/alice/", "Alice Doe"),
+		connectionCard("https://www.linkedin.com/company/acme/", "Acme"),
+	}, func(scroll int) []*driver.FakeElement {
+		switch scroll {
+		case 1:
+			return []*driver.FakeElement{connectionCard("https://de.linkedin.com/in/Bob?miniProfileUrn=x", "Bob Stone")}
+		case 2:
+			return []*driver.FakeElement{connectionCard("/in/carol/", "Carol")}
+		}
+		return nil
+	})
+
+	res, err := SyncConnections(ctx, f, store, auth.NewGuard(f, nil, nil, testLogger()), testBaseURL, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SyncResult{Seen: 3, New: 2, Accepted: 2}); res != want {
+		t.Errorf("SyncConnections = %+v, want %+v", res, want)
+	}
+	// Two scrolls load cards, the third finds no more.
+	if n := scrollCount(f); n != 3 {
+		t.Errorf("scrolled %d times, want 3", n)
+	}
+
+	conns, err := store.Connections(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range conns {
+		names = append(names, c.Name+" "+c.ProfileURL)
+	}
+	want := []string{"Carol " + profile("carol"), "Alice Doe " + profile("alice"), "Bob Stone " + profile("bob")}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("connections = %q, want %q", names, want)
+	}
+	for id, want := range map[string]storage.RequestStatus{"alice": storage.RequestAccepted, "bob": storage.RequestAccepted, "dan": storage.RequestSent} {
+		if got, _, err := store.RequestStatusFor(ctx, profile(id)); err != nil || got != want {
+			t.Errorf("%s request = %q, %v; want %q", id, got, err, want)
+		}
+	}
+
+	// A second sync finds nothing new.
+	f.Actions = nil
+	newConnectionsPage(f, f.Current().Elements, func(int) []*driver.FakeElement { return nil })
+	if res, err := SyncConnections(ctx, f, store, nil, testBaseURL, testLogger()); err != nil || res != (SyncResult{Seen: 3}) {
+		t.Errorf("second SyncConnections = %+v, %v; want 3 seen, nothing new", res, err)
+	}
+}
+
+func TestSyncConnectionsScrollLimit(t *testing.T) {
+	f := driver.NewFake()
+	// An endless list keeps loading cards.
+	newConnectionsPage(f, []*driver.FakeElement{connectionCard("/in/user-0/", "User 0")}, func(scroll int) []*driver.FakeElement {
+		return []*driver.FakeElement{connectionCard(fmt.Sprintf("/in/user-%d/", scroll), fmt.Sprintf("User %d", scroll))}
+	})
+
+	res, err := SyncConnections(context.Background(), f, newTestStorage(t), nil, testBaseURL, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := scrollCount(f); n != maxScrolls {
+		t.Errorf("scrolled %d times, want %d", n, maxScrolls)
+	}
+	if res.Seen != maxScrolls+1 {
+		t.Errorf("saw %d connections, want %d", res.Seen, maxScrolls+1)
+	}
+}
+
+func TestSyncConnectionsStops(t *testing.T) {
+	t.Run("session lost", func(t *testing.T) {
+		store := newTestStorage(t)
+		f := driver.NewFake()
+		f.Pages[testBaseURL+connectionsPath] = &driver.FakePage{RedirectTo: testBaseURL + "/login"}
+		f.Pages[testBaseURL+"/login"] = &driver.FakePage{Elements: []*driver.FakeElement{connectionCard("/in/alice/", "Alice")}}
+
+		_, err := SyncConnections(context.Background(), f, store, auth.NewGuard(f, nil, nil, testLogger()), testBaseURL, testLogger())
+		var lost *auth.SessionError
+		if !errors.As(err, &lost) {
+			t.Errorf("SyncConnections = %v, want a *auth.SessionError", err)
+		}
+		if conns, err := store.Connections(context.Background()); err != nil || len(conns) != 0 {
+			t.Errorf("connections after a lost session = %v, %v; want none", conns, err)
+		}
+	})
+	t.Run("interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := driver.NewFake()
+		newConnectionsPage(f, nil, func(scroll int) []*driver.FakeElement {
+			cancel()
+			return []*driver.FakeElement{connectionCard("/in/alice/", "Alice")}
+		})
+		_, err := SyncConnections(ctx, f, newTestStorage(t), nil, testBaseURL, testLogger())
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("SyncConnections = %v, want context.Canceled", err)
+		}
+		if n := scrollCount(f); n != 1 {
+			t.Errorf("scrolled %d times after the interrupt, want 1", n)
+		}
+	})
+}
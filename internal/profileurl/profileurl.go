@@ -0,0 +1,116 @@
+// Package profileurl turns the many ways LinkedIn links to a member
+// (relative hrefs, locale subdomains, trailing slashes, /overlay/ suffixes,
+// tracking queries, URL-encoded vanity names) into one canonical form, so
+// storage keys and duplicate checks agree on who a profile is.
+package profileurl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// canonicalHost is the host used in canonical URLs regardless of which
+// LinkedIn host (or local fixture) the reference came from.
+const canonicalHost = "www.linkedin.com"
+
+// ErrNotProfile is returned for references that do not point at a member's
+// /in/ page.
+var ErrNotProfile = errors.New("not a LinkedIn profile URL")
+
+// baseHost is the host of the configured base URL; see UseBaseURL.
+var baseHost atomic.Value // string
+
+// UseBaseURL makes absolute references on baseURL's host count as profiles
+// alongside LinkedIn's own hosts, so links on a local fixture resolve. The
+// app calls it with linkedin.base_url at startup; "" forgets the host.
+func UseBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL %q: %w", baseURL, err)
+	}
+	baseHost.Store(strings.ToLower(u.Host))
+	return nil
+}
+
+// profileHost reports whether an absolute reference u can point at a
+// member: it is on a linkedin.com host, or on the configured base URL's.
+func profileHost(u *url.URL) bool {
+	if name := strings.ToLower(u.Hostname()); name == "linkedin.com" || strings.HasSuffix(name, ".linkedin.com") {
+		return true
+	}
+	base, _ := baseHost.Load().(string)
+	return base != "" && strings.ToLower(u.Host) == base
+}
+
+// PublicID extracts the lower-cased, URL-decoded public identifier (vanity
+// name) from a profile reference, e.g. "jane-doe" from
+// "https://de.linkedin.com/in/Jane-Doe/overlay/contact-info/?trk=x".
+// Relative references such as "/in/jane-doe" are accepted; absolute ones
+// must be on a LinkedIn host or the one set with UseBaseURL.
+func PublicID(ref string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("parse %q: %w", ref, err)
+	}
+	if u.Host != "" && !profileHost(u) {
+		return "", fmt.Errorf("%w: %q", ErrNotProfile, ref)
+	}
+
+	// u.Path is already percent-decoded.
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != "in" {
+		return "", fmt.Errorf("%w: %q", ErrNotProfile, ref)
+	}
+	id := strings.TrimSpace(segments[1])
+	if id == "" {
+		return "", fmt.Errorf("%w: %q", ErrNotProfile, ref)
+	}
+	// Vanity names are case-insensitive, but the opaque member IDs search
+	// sometimes links to ("ACoAA...") are not.
+	if !isMemberID(id) {
+		id = strings.ToLower(id)
+	}
+	return id, nil
+}
+
+// Canonical returns the canonical URL for a profile reference:
+// "https://www.linkedin.com/in/<public-id>/". It is the form stored in the
+// database and compared for duplicates.
+func Canonical(ref string) (string, error) {
+	id, err := PublicID(ref)
+	if err != nil {
+		return "", err
+	}
+	return FromID(id), nil
+}
+
+// FromID builds the canonical URL for a public identifier.
+func FromID(id string) string {
+	u := url.URL{Scheme: "https", Host: canonicalHost, Path: "/in/" + id + "/"}
+	return u.String()
+}
+
+// On rebases a profile reference onto baseURL, producing the URL to
+// actually navigate to. With the default base it equals Canonical; against
+// a local fixture it points at the fixture's /in/ page.
+func On(baseURL, ref string) (string, error) {
+	id, err := PublicID(ref)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL %q: %w", baseURL, err)
+	}
+	u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/in/" + id + "/"}
+	return u.String(), nil
+}
+
+// isMemberID reports whether id looks like an opaque member identifier
+// rather than a vanity name.
+func isMemberID(id string) bool {
+	return len(id) > 20 && strings.HasPrefix(id, "ACoAA")
+}
@@ -0,0 +1,85 @@
+package profileurl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		ref, want string
+	}{
+		{"https://www.linkedin.com/in/jane-doe/", "https://www.linkedin.com/in/jane-doe/"},
+		{"https://www.linkedin.com/in/jane-doe", "https://www.linkedin.com/in/jane-doe/"},
+		{"http://linkedin.com/in/jane-doe", "https://www.linkedin.com/in/jane-doe/"},
+		{"https://de.linkedin.com/in/Jane-Doe/overlay/contact-info/?trk=x", "https://www.linkedin.com/in/jane-doe/"},
+		{"https://www.linkedin.com/in/jane-doe?miniProfileUrn=urn%3Ali%3Afs_miniProfile%3Ax#top", "https://www.linkedin.com/in/jane-doe/"},
+		{"/in/jane-doe", "https://www.linkedin.com/in/jane-doe/"},
+		{"  https://www.linkedin.com/in/jane-doe/  ", "https://www.linkedin.com/in/jane-doe/"},
+		{"https://www.linkedin.com/in/J%C3%BCrgen-M/", "https://www.linkedin.com/in/j%C3%BCrgen-m/"},
+		// Opaque member IDs are case-sensitive.
+		{"https://www.linkedin.com/in/ACoAABcdEfGhIjKlMnOpQr", "https://www.linkedin.com/in/ACoAABcdEfGhIjKlMnOpQr/"},
+	}
+	for _, tt := range tests {
+		got, err := Canonical(tt.ref)
+		if err != nil {
+			t.Errorf("Canonical(%q): %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalNotProfile(t *testing.T) {
+	for _, ref := range []string{
+		"",
+		"https://www.linkedin.com/feed/",
+		"https://www.linkedin.com/in/",
+		"https://www.linkedin.com/company/acme/",
+		"https://www.linkedin.com/search/results/people/?keywords=in",
+		"https://example.com/in/x",
+		"https://www.linkedin.com/company/acme/in/about",
+		"https://linkedin.com.example.com/in/x",
+		"http://127.0.0.1:8099/in/jane-doe/",
+	} {
+		if got, err := Canonical(ref); !errors.Is(err, ErrNotProfile) {
+			t.Errorf("Canonical(%q) = %q, %v; want ErrNotProfile", ref, got, err)
+		}
+	}
+}
+
+func TestCanonicalOnBaseURL(t *testing.T) {
+	if err := UseBaseURL("http://127.0.0.1:8099"); err != nil {
+		t.Fatal(err)
+	}
+	defer UseBaseURL("")
+
+	tests := []struct {
+		ref     string
+		want    string
+		profile bool
+	}{
+		{"http://127.0.0.1:8099/in/jane-doe/", "https://www.linkedin.com/in/jane-doe/", true},
+		{"https://de.linkedin.com/in/jane-doe/", "https://www.linkedin.com/in/jane-doe/", true},
+		{"http://127.0.0.1:8100/in/jane-doe/", "", false},
+		{"https://example.com/in/jane-doe/", "", false},
+	}
+	for _, tt := range tests {
+		got, err := Canonical(tt.ref)
+		if tt.profile && (err != nil || got != tt.want) || !tt.profile && !errors.Is(err, ErrNotProfile) {
+			t.Errorf("Canonical(%q) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestOn(t *testing.T) {
+	got, err := On("http://127.0.0.1:8099", "https://www.linkedin.com/in/Jane-Doe/?trk=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://127.0.0.1:8099/in/jane-doe/"; got != want {
+		t.Errorf("On = %q, want %q", got, want)
+	}
+}
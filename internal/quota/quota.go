@@ -0,0 +1,236 @@
+// Package quota enforces the daily and weekly limits of each outbound
+// action. Days and weeks are counted in the operator's timezone, either as
+// calendar periods or as rolling windows, and a slot is reserved in storage
+// before every action, so a crash between acting and recording cannot let a
+// later run go over a limit.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+// Window is how days and weeks are counted.
+type Window string
+
+const (
+	// Calendar limits reset at midnight and at the start of Monday.
+	Calendar Window = "calendar"
+	// Rolling limits cover the last 24 hours and the last 7 days.
+	Rolling Window = "rolling"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Limits caps one action. A Weekly of 0 means no weekly limit.
+type Limits struct {
+	Daily  int
+	Weekly int
+}
+
+// LimitError says a limit is used up. It wraps workflow.ErrLimitReached.
+type LimitError struct {
+	Action string
+	// Period is "daily" or "weekly".
+	Period string
+	Limit  int
+	// NextFree is when a slot frees up; zero when the limit is 0.
+	NextFree time.Time
+}
+
+func (e *LimitError) Error() string {
+	if e.NextFree.IsZero() {
+		return fmt.Sprintf("%s %s limit is %d", e.Action, e.Period, e.Limit)
+	}
+	return fmt.Sprintf("%s %s limit of %d reached; next slot in %s (%s)", e.Action, e.Period, e.Limit,
+		time.Until(e.NextFree).Round(time.Minute), e.NextFree.Format("2006-01-02 15:04 MST"))
+}
+
+func (e *LimitError) Unwrap() error {
+	return workflow.ErrLimitReached
+}
+
+// Quota counts actions against their limits.
+type Quota struct {
+	store  *storage.Storage
+	loc    *time.Location
+	window Window
+}
+
+// New returns a Quota counting in cfg's timezone and window.
+func New(store *storage.Storage, cfg config.QuotaConfig) (*Quota, error) {
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, fmt.Errorf("quota.timezone: %w", err)
+	}
+	return &Quota{store: store, loc: loc, window: Window(cfg.Window)}, nil
+}
+
+// Usage is how much of an action's limits is taken at some moment.
+type Usage struct {
+	Action   string
+	Limits   Limits
+	Today    int
+	ThisWeek int
+
+	now    time.Time
+	loc    *time.Location
+	window Window
+	// times are the counted actions, oldest first.
+	times []time.Time
+	// dayEnd and weekEnd end the calendar periods; unused when rolling.
+	dayEnd, weekEnd time.Time
+}
+
+// Usage counts action as of now. action is storage.QuotaConnect or a
+// message type.
+func (q *Quota) Usage(ctx context.Context, action string, limits Limits, now time.Time) (Usage, error) {
+	_, weekStart := q.periodStarts(now)
+	times, err := q.store.QuotaTimes(ctx, action, weekStart)
+	if err != nil {
+		return Usage{}, fmt.Errorf("count %s quota: %w", action, err)
+	}
+	return q.usage(action, limits, now, times), nil
+}
+
+// usage counts times, the actions since the start of the week containing
+// now, oldest first.
+func (q *Quota) usage(action string, limits Limits, now time.Time, times []time.Time) Usage {
+	dayStart, weekStart := q.periodStarts(now)
+	u := Usage{Action: action, Limits: limits, ThisWeek: len(times), now: now, loc: q.loc, window: q.window, times: times}
+	for _, t := range times {
+		if !t.Before(dayStart) {
+			u.Today++
+		}
+	}
+	if q.window == Calendar {
+		u.dayEnd = dayStart.AddDate(0, 0, 1)
+		u.weekEnd = weekStart.AddDate(0, 0, 7)
+	}
+	return u
+}
+
+// periodStarts returns the start of the day and week containing now.
+func (q *Quota) periodStarts(now time.Time) (dayStart, weekStart time.Time) {
+	if q.window == Rolling {
+		return now.Add(-day), now.Add(-week)
+	}
+	local := now.In(q.loc)
+	dayStart = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, q.loc)
+	weekStart = dayStart.AddDate(0, 0, -(int(dayStart.Weekday())+6)%7)
+	return dayStart, weekStart
+}
+
+// Remaining is how many more actions the limits allow now.
+func (u Usage) Remaining() int {
+	n := u.Limits.Daily - u.Today
+	if u.Limits.Weekly > 0 {
+		n = min(n, u.Limits.Weekly-u.ThisWeek)
+	}
+	return max(n, 0)
+}
+
+// Err returns a *LimitError when no action is allowed now. When both limits
+// are used up it names the one that frees up last.
+func (u Usage) Err() error {
+	if u.Remaining() > 0 {
+		return nil
+	}
+	var err *LimitError
+	if u.Today >= u.Limits.Daily {
+		err = &LimitError{Action: u.Action, Period: "daily", Limit: u.Limits.Daily,
+			NextFree: u.freeAt(u.Today, u.Limits.Daily, day, u.dayEnd)}
+	}
+	if u.Limits.Weekly > 0 && u.ThisWeek >= u.Limits.Weekly {
+		weekly := &LimitError{Action: u.Action, Period: "weekly", Limit: u.Limits.Weekly,
+			NextFree: u.freeAt(u.ThisWeek, u.Limits.Weekly, week, u.weekEnd)}
+		if err == nil || (!err.NextFree.IsZero() && weekly.NextFree.After(err.NextFree)) {
+			err = weekly
+		}
+	}
+	return err
+}
+
+// NextFree is when the next action will be allowed: now when one is allowed
+// already, and zero when a limit is 0 so none ever will be.
+func (u Usage) NextFree() time.Time {
+	var limit *LimitError
+	if errors.As(u.Err(), &limit) {
+		return limit.NextFree
+	}
+	return u.now
+}
+
+// freeAt is when one of the count actions in a period of length span stops
+// counting, given the period's limit. Calendar periods free up when they end;
+// rolling ones when the action count-limit places from the oldest ages out.
+func (u Usage) freeAt(count, limit int, span time.Duration, periodEnd time.Time) time.Time {
+	if limit <= 0 {
+		return time.Time{}
+	}
+	if u.window == Calendar {
+		return periodEnd
+	}
+	// times covers the week; the day is its tail.
+	inPeriod := u.times[len(u.times)-count:]
+	return inPeriod[count-limit].Add(span).In(u.loc)
+}
+
+// Take counts one more action at now without storing anything, so a dry run
+// can plan against the limits.
+func (u *Usage) Take() {
+	u.Today++
+	u.ThisWeek++
+	u.times = append(u.times, u.now)
+}
+
+// Slot is a reserved quota slot. Release it once the action has been
+// recorded, or when it did not happen; a slot that is never released keeps
+// counting until it ages out of the limits.
+type Slot struct {
+	store *storage.Storage
+	id    int64
+}
+
+// Reserve takes a slot for one action at now, or returns a *LimitError when
+// the limits are used up. Counting and reserving happen in one transaction,
+// so concurrent runs cannot both take the last slot.
+func (q *Quota) Reserve(ctx context.Context, action string, limits Limits, now time.Time) (*Slot, error) {
+	_, weekStart := q.periodStarts(now)
+	id, err := q.store.ReserveQuota(ctx, action, weekStart, now, func(times []time.Time) error {
+		return q.usage(action, limits, now, times).Err()
+	})
+	var limit *LimitError
+	if errors.As(err, &limit) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reserve %s quota: %w", action, err)
+	}
+	return &Slot{store: q.store, id: id}, nil
+}
+
+// Release gives the slot back.
+func (s *Slot) Release(ctx context.Context) error {
+	return s.store.ReleaseQuota(ctx, s.id)
+}
+
+// ReleaseOrLog gives the slot back and only logs a failure: a slot that
+// cannot be released keeps counting until it ages out, which errs on the
+// safe side.
+func (s *Slot) ReleaseOrLog(ctx context.Context, log logrus.FieldLogger) {
+	if err := s.Release(ctx); err != nil {
+		log.WithError(err).Warn("failed to release quota slot")
+	}
+}
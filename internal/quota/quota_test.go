@@ -0,0 +1,168 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/storage"
+	"linkedin-automation-poc/internal/workflow"
+)
+
+func TestUsageErr(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Wednesday afternoon.
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, berlin)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, berlin)
+
+	tests := []struct {
+		name   string
+		window Window
+		limits Limits
+		times  []time.Time
+		// period is "" when an action is allowed.
+		period   string
+		nextFree time.Time
+	}{
+		{
+			name:   "room left",
+			window: Calendar,
+			limits: Limits{Daily: 3, Weekly: 10},
+			times:  []time.Time{monday, ago(2 * time.Hour)},
+		},
+		{
+			name:     "calendar day used up",
+			window:   Calendar,
+			limits:   Limits{Daily: 2, Weekly: 10},
+			times:    []time.Time{monday, ago(2 * time.Hour), ago(time.Hour)},
+			period:   "daily",
+			nextFree: time.Date(2026, 3, 5, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "calendar week frees up last",
+			window:   Calendar,
+			limits:   Limits{Daily: 2, Weekly: 3},
+			times:    []time.Time{monday, ago(2 * time.Hour), ago(time.Hour)},
+			period:   "weekly",
+			nextFree: time.Date(2026, 3, 9, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:   "weekly limit of 0 means none",
+			window: Calendar,
+			limits: Limits{Daily: 5},
+			times:  []time.Time{monday, monday, monday, ago(time.Hour)},
+		},
+		{
+			name:     "rolling day",
+			window:   Rolling,
+			limits:   Limits{Daily: 2, Weekly: 10},
+			times:    []time.Time{ago(30 * time.Hour), ago(20 * time.Hour), ago(10 * time.Hour), ago(time.Hour)},
+			period:   "daily",
+			nextFree: ago(10 * time.Hour).Add(24 * time.Hour),
+		},
+		{
+			name:     "rolling week",
+			window:   Rolling,
+			limits:   Limits{Daily: 5, Weekly: 3},
+			times:    []time.Time{ago(6 * day), ago(5 * day), ago(2 * day), ago(time.Hour)},
+			period:   "weekly",
+			nextFree: ago(5 * day).Add(week),
+		},
+		{
+			name:   "limit of 0",
+			window: Rolling,
+			limits: Limits{Daily: 0, Weekly: 10},
+			period: "daily",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Quota{loc: berlin, window: tt.window}
+			_, weekStart := q.periodStarts(now)
+			var times []time.Time
+			for _, at := range tt.times {
+				if !at.Before(weekStart) {
+					times = append(times, at)
+				}
+			}
+			u := q.usage(storage.QuotaConnect, tt.limits, now, times)
+
+			err := u.Err()
+			if tt.period == "" {
+				if err != nil || u.Remaining() == 0 || !u.NextFree().Equal(now) {
+					t.Errorf("Err = %v, Remaining = %d, NextFree = %s; want an action allowed now", err, u.Remaining(), u.NextFree())
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) || !errors.Is(err, workflow.ErrLimitReached) {
+				t.Fatalf("Err = %v, want a *LimitError wrapping ErrLimitReached", err)
+			}
+			if limit.Period != tt.period || !limit.NextFree.Equal(tt.nextFree) {
+				t.Errorf("Err = %s limit freeing at %s, want %s at %s", limit.Period, limit.NextFree, tt.period, tt.nextFree)
+			}
+			if !u.NextFree().Equal(tt.nextFree) || u.Remaining() != 0 {
+				t.Errorf("NextFree = %s, Remaining = %d", u.NextFree(), u.Remaining())
+			}
+		})
+	}
+}
+
+func TestUsageTake(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	q := &Quota{loc: time.UTC, window: Rolling}
+	u := q.usage("followup", Limits{Daily: 2, Weekly: 2}, now, []time.Time{now.Add(-2 * day)})
+	u.Take()
+	if u.Today != 1 || u.ThisWeek != 2 {
+		t.Fatalf("after Take: today %d, week %d", u.Today, u.ThisWeek)
+	}
+	var limit *LimitError
+	if !errors.As(u.Err(), &limit) || limit.Period != "weekly" || !limit.NextFree.Equal(now.Add(5*day)) {
+		t.Errorf("Err after Take = %v, want the weekly limit freeing in 5 days", u.Err())
+	}
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	q, err := New(store, config.QuotaConfig{Timezone: "UTC", Window: "calendar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	limits := Limits{Daily: 1}
+	slot, err := q.Reserve(ctx, storage.QuotaConnect, limits, now)
+	if err != nil {
+		t.Fatalf("first Reserve: %v", err)
+	}
+	if _, err := q.Reserve(ctx, storage.QuotaConnect, limits, now); !errors.Is(err, workflow.ErrLimitReached) {
+		t.Fatalf("second Reserve = %v, want the daily limit", err)
+	}
+	if err := slot.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if slot, err = q.Reserve(ctx, storage.QuotaConnect, limits, now); err != nil {
+		t.Fatalf("Reserve after Release: %v", err)
+	}
+	slot.ReleaseOrLog(ctx, log)
+	if _, err := q.Reserve(ctx, storage.QuotaConnect, limits, now); err != nil {
+		t.Errorf("Reserve after ReleaseOrLog: %v", err)
+	}
+}
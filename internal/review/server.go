@@ -0,0 +1,183 @@
+// Package review serves a small local web page for approving or rejecting
+// search candidates before the connect workflow contacts them.
+package review
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+// Handler renders the review queue and records decisions. Every form carries
+// a token generated when the handler is created, so other pages open in the
+// same browser cannot post decisions to it.
+type Handler struct {
+	store *storage.Storage
+	log   *logrus.Logger
+	token string
+	mux   *http.ServeMux
+}
+
+// NewHandler returns the review page backed by store.
+func NewHandler(store *storage.Storage, log *logrus.Logger) (*Handler, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	h := &Handler{store: store, log: log, token: hex.EncodeToString(b), mux: http.NewServeMux()}
+	h.mux.HandleFunc("/", h.handleList)
+	h.mux.HandleFunc("/decide", h.handleDecide)
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the review page on addr until ctx is canceled. The
+// page has no login, so addr must be a loopback address such as
+// 127.0.0.1:8088.
+func ListenAndServe(ctx context.Context, addr string, h *Handler) error {
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// checkLoopback returns an error unless addr is a host:port on the loopback
+// interface; an empty host would listen on every interface.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("review page address: %w", err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("review page address %q is not a loopback address such as 127.0.0.1", addr)
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	filter := r.URL.Query().Get("status")
+	if filter == "" {
+		filter = string(storage.CandidatePending)
+	}
+	status := storage.CandidateStatus("")
+	if filter != "all" {
+		st, err := storage.ParseCandidateStatus(filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status = st
+	}
+
+	candidates, err := h.store.Candidates(r.Context(), status)
+	if err != nil {
+		h.log.WithError(err).Error("failed to list candidates")
+		http.Error(w, "failed to list candidates", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := listTpl.Execute(w, map[string]any{
+		"Filter":     filter,
+		"Filters":    []string{"pending", "approved", "rejected", "all"},
+		"Candidates": candidates,
+		"Token":      h.token,
+	}); err != nil {
+		h.log.WithError(err).Warn("failed to render review page")
+	}
+}
+
+func (h *Handler) handleDecide(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(h.token)) != 1 {
+		http.Error(w, "invalid form token", http.StatusForbidden)
+		return
+	}
+	status, err := storage.ParseCandidateStatus(r.FormValue("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	profileURL := r.FormValue("profile_url")
+	updated, err := h.store.SetCandidateStatus(r.Context(), profileURL, status, time.Now())
+	if err != nil {
+		h.log.WithError(err).WithField("profile", profileURL).Error("failed to record review decision")
+		http.Error(w, "failed to record decision", http.StatusInternalServerError)
+		return
+	}
+	if !updated {
+		http.Error(w, "no such candidate", http.StatusNotFound)
+		return
+	}
+	h.log.WithField("profile", profileURL).WithField("status", status).Info("candidate reviewed")
+	http.Redirect(w, r, "/?status="+url.QueryEscape(r.FormValue("filter")), http.StatusSeeOther)
+}
+
+var listTpl = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Candidate review</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .4em .6em; border-bottom: 1px solid #ddd; vertical-align: top; }
+form { display: inline; }
+.status-approved { color: #1a7f37; } .status-rejected { color: #cf222e; }
+</style></head>
+<body>
+<h1>Candidate review</h1>
+<p>{{range .Filters}}{{if eq . $.Filter}}<strong>{{.}}</strong>{{else}}<a href="/?status={{.}}">{{.}}</a>{{end}} {{end}}</p>
+{{if .Candidates}}
+<table>
+<tr><th>Name</th><th>Headline</th><th>Keyword</th><th>Found</th><th>Status</th><th></th></tr>
+{{range .Candidates}}
+<tr>
+  <td><a href="{{.ProfileURL}}" target="_blank" rel="noopener">{{if .Name}}{{.Name}}{{else}}{{.ProfileURL}}{{end}}</a></td>
+  <td>{{.Headline}}</td>
+  <td>{{.Keyword}}</td>
+  <td>{{.FoundAt.Format "2006-01-02"}}</td>
+  <td class="status-{{.Status}}">{{.Status}}</td>
+  <td>
+    {{$url := .ProfileURL}}
+    {{if ne .Status "approved"}}<form method="post" action="/decide"><input type="hidden" name="token" value="{{$.Token}}"><input type="hidden" name="filter" value="{{$.Filter}}"><input type="hidden" name="profile_url" value="{{$url}}"><input type="hidden" name="status" value="approved"><button>Approve</button></form>{{end}}
+    {{if ne .Status "rejected"}}<form method="post" action="/decide"><input type="hidden" name="token" value="{{$.Token}}"><input type="hidden" name="filter" value="{{$.Filter}}"><input type="hidden" name="profile_url" value="{{$url}}"><input type="hidden" name="status" value="rejected"><button>Reject</button></form>{{end}}
+  </td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No {{if ne .Filter "all"}}{{.Filter}} {{end}}candidates.</p>
+{{end}}
+</body></html>`))
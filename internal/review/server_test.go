@@ -0,0 +1,243 @@
+package review
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+const (
+	jane = "https://www.linkedin.com/in/jane-doe/"
+	bob  = "https://www.linkedin.com/in/bob/"
+)
+
+// newTestServer serves a review page over two pending candidates and returns
+// the form token it renders.
+func newTestServer(t *testing.T) (*httptest.Server, *storage.Storage, string) {
+	t.Helper()
+	ctx := context.Background()
+	store, err := storage.New("file:"+filepath.Join(t.TempDir(), "test.db"), testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, c := range []storage.Candidate{
+		{ProfileURL: jane, Name: "Jane <Doe>", Headline: "Engineer at Acme", Keyword: "golang", Status: storage.CandidatePending},
+		{ProfileURL: bob, Name: "Bob", Keyword: "golang", Status: storage.CandidatePending},
+	} {
+		if _, err := store.SaveCandidate(ctx, c, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := NewHandler(store, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	page := get(t, srv.URL+"/")
+	m := regexp.MustCompile(`name="token" value="([0-9a-f]+)"`).FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("review page has no form token:\n%s", page)
+	}
+	return srv, store, m[1]
+}
+
+func get(t *testing.T, u string) string {
+	t.Helper()
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %s", u, resp.Status)
+	}
+	return string(body)
+}
+
+// decide posts a review decision without following the redirect.
+func decide(t *testing.T, srv *httptest.Server, form url.Values) *http.Response {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.PostForm(srv.URL+"/decide", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func statusOf(t *testing.T, store *storage.Storage, profile string) storage.CandidateStatus {
+	t.Helper()
+	c, found, err := store.CandidateFor(context.Background(), profile)
+	if err != nil || !found {
+		t.Fatalf("CandidateFor(%s) = %v, %v", profile, found, err)
+	}
+	return c.Status
+}
+
+func TestReviewList(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	page := get(t, srv.URL+"/")
+	for _, want := range []string{"Jane &lt;Doe&gt;", "Engineer at Acme", `href="` + bob + `"`, "<strong>pending</strong>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("review page lacks %q", want)
+		}
+	}
+	if page := get(t, srv.URL+"/?status=approved"); !strings.Contains(page, "No approved candidates.") {
+		t.Errorf("approved page lists candidates:\n%s", page)
+	}
+
+	for path, want := range map[string]int{"/?status=bogus": http.StatusBadRequest, "/missing": http.StatusNotFound} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestReviewDecide(t *testing.T) {
+	srv, store, token := newTestServer(t)
+
+	resp := decide(t, srv, url.Values{"token": {token}, "profile_url": {jane}, "status": {"approved"}, "filter": {"pending"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/?status=pending" {
+		t.Errorf("approve = %d to %q, want a redirect back to the pending list", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got := statusOf(t, store, jane); got != storage.CandidateApproved {
+		t.Errorf("jane is %s after approval, want approved", got)
+	}
+
+	// Profiles are matched in any form LinkedIn links them.
+	resp = decide(t, srv, url.Values{"token": {token}, "profile_url": {"https://de.linkedin.com/in/Bob?trk=x"}, "status": {"rejected"}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("reject = %d, want %d", resp.StatusCode, http.StatusSeeOther)
+	}
+	if got := statusOf(t, store, bob); got != storage.CandidateRejected {
+		t.Errorf("bob is %s after rejection, want rejected", got)
+	}
+	if page := get(t, srv.URL+"/?status=pending"); !strings.Contains(page, "No pending candidates.") {
+		t.Errorf("pending page still lists candidates:\n%s", page)
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want int
+	}{
+		{"unknown candidate", url.Values{"token": {token}, "profile_url": {"https://www.linkedin.com/in/carol/"}, "status": {"approved"}}, http.StatusNotFound},
+		{"bad status", url.Values{"token": {token}, "profile_url": {jane}, "status": {"maybe"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := decide(t, srv, tt.form); resp.StatusCode != tt.want {
+			t.Errorf("%s: POST /decide = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	resp, err := http.Get(srv.URL + "/decide?token=" + token + "&profile_url=" + url.QueryEscape(jane) + "&status=rejected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || statusOf(t, store, jane) != storage.CandidateApproved {
+		t.Errorf("GET /decide = %d and changed the decision, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestReviewDecideRejectsBadToken(t *testing.T) {
+	srv, store, token := newTestServer(t)
+	// Another handler's token is no good either.
+	_, _, other := newTestServer(t)
+	if other == token {
+		t.Fatal("two handlers share a form token")
+	}
+
+	for name, form := range map[string]url.Values{
+		"no token":      {"profile_url": {jane}, "status": {"approved"}},
+		"empty token":   {"token": {""}, "profile_url": {jane}, "status": {"approved"}},
+		"wrong token":   {"token": {strings.Repeat("0", len(token))}, "profile_url": {jane}, "status": {"approved"}},
+		"other handler": {"token": {other}, "profile_url": {jane}, "status": {"approved"}},
+		"token prefix":  {"token": {token[:len(token)-1]}, "profile_url": {jane}, "status": {"approved"}},
+	} {
+		if resp := decide(t, srv, form); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: POST /decide = %d, want %d", name, resp.StatusCode, http.StatusForbidden)
+		}
+	}
+	if got := statusOf(t, store, jane); got != storage.CandidatePending {
+		t.Errorf("jane is %s after forged decisions, want pending", got)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{"127.0.0.1:8088", true},
+		{"127.0.0.2:8088", true},
+		{"localhost:8088", true},
+		{"[::1]:8088", true},
+		{":8088", false},
+		{"0.0.0.0:8088", false},
+		{"[::]:8088", false},
+		{"192.168.1.10:8088", false},
+		{"example.com:8088", false},
+		{"127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if err := checkLoopback(tt.addr); (err == nil) != tt.ok {
+			t.Errorf("checkLoopback(%q) = %v, want ok %v", tt.addr, err, tt.ok)
+		}
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	h, err := NewHandler(nil, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []string{":0", "0.0.0.0:0"} {
+		if err := ListenAndServe(context.Background(), addr, h); err == nil || !strings.Contains(err.Error(), "loopback") {
+			t.Errorf("ListenAndServe(%q) = %v, want a loopback error", addr, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ListenAndServe(ctx, "127.0.0.1:0", h) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServe after cancel = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ListenAndServe did not stop when ctx was canceled")
+	}
+}
@@ -0,0 +1,110 @@
+// Package runlock keeps two copies of the app, such as overlapping cron
+// jobs, from driving LinkedIn at the same time. The lock lives in the
+// database, so it covers every process using the same file, and its holder
+// refreshes a heartbeat while it runs, so a lock left behind by a crash goes
+// stale and is taken over instead of blocking every later run.
+package runlock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+const (
+	// Heartbeat is how often the holder refreshes the lock.
+	Heartbeat = 30 * time.Second
+	// StaleAfter is how long without a heartbeat before a lock is stale.
+	StaleAfter = 4 * Heartbeat
+)
+
+// heartbeatEvery is Heartbeat, shortened by tests.
+var heartbeatEvery = Heartbeat
+
+// Lock is a held run lock.
+type Lock struct {
+	store *storage.Storage
+	token string
+	log   *logrus.Logger
+
+	stop     chan struct{}
+	done     chan struct{}
+	lost     chan struct{}
+	stopOnce sync.Once
+}
+
+// Acquire takes the run lock for command. While another live process holds
+// it, Acquire returns a *storage.RunLockedError.
+func Acquire(ctx context.Context, store *storage.Storage, command string, log *logrus.Logger) (*Lock, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	now := time.Now()
+	me := storage.RunLockHolder{PID: os.Getpid(), Host: host, Command: command, AcquiredAt: now, HeartbeatAt: now}
+
+	l := &Lock{
+		store: store,
+		token: hex.EncodeToString(buf),
+		log:   log,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+	stale, err := store.AcquireRunLock(ctx, l.token, me, now.Add(-StaleAfter))
+	if err != nil {
+		return nil, err
+	}
+	if stale != nil {
+		log.WithField("holder", stale.String()).
+			WithField("last_heartbeat", stale.HeartbeatAt.Format(time.RFC3339)).
+			Warn("taking over a stale run lock; the previous run probably crashed")
+	}
+	go l.heartbeat()
+	return l, nil
+}
+
+// Lost is closed when the lock turns out to have been taken over, after
+// which the holder must stop.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *Lock) heartbeat() {
+	defer close(l.done)
+	t := time.NewTicker(heartbeatEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-t.C:
+			held, err := l.store.HeartbeatRunLock(context.Background(), l.token, now)
+			if err != nil {
+				// A missed beat is harmless until the lock goes stale.
+				l.log.WithError(err).Warn("failed to refresh run lock")
+				continue
+			}
+			if !held {
+				l.log.Error("run lock was taken over by another process")
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+// Release stops the heartbeat and gives up the lock.
+func (l *Lock) Release() error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+	return l.store.ReleaseRunLock(context.Background(), l.token)
+}
@@ -0,0 +1,134 @@
+package runlock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"linkedin-automation-poc/internal/storage"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// openTwice opens the same database file as two Storage values, standing in
+// for two processes.
+func openTwice(t *testing.T) (*storage.Storage, *storage.Storage) {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db")
+	var stores [2]*storage.Storage
+	for i := range stores {
+		s, err := storage.New(dsn, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		stores[i] = s
+	}
+	return stores[0], stores[1]
+}
+
+func TestAcquireLocked(t *testing.T) {
+	ctx := context.Background()
+	a, b := openTwice(t)
+
+	first, err := Acquire(ctx, a, "connect", testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Acquire(ctx, b, "followup", testLogger())
+	var locked *storage.RunLockedError
+	if !errors.As(err, &locked) || locked.Holder.Command != "connect" {
+		t.Fatalf("second Acquire = %v, want a *storage.RunLockedError held by connect", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Acquire(ctx, b, "followup", testLogger())
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	defer second.Release()
+	if holder, found, err := a.RunLock(ctx); err != nil || !found || holder.Command != "followup" {
+		t.Errorf("RunLock = %+v, %v, %v; want followup holding it", holder, found, err)
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	ctx := context.Background()
+	a, b := openTwice(t)
+
+	// A run that crashed and stopped refreshing the lock long ago.
+	crashed := time.Now().Add(-StaleAfter - time.Minute)
+	holder := storage.RunLockHolder{PID: 1, Host: "old", Command: "run", AcquiredAt: crashed, HeartbeatAt: crashed}
+	if _, err := a.AcquireRunLock(ctx, "crashed", holder, crashed.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(ctx, b, "connect", testLogger())
+	if err != nil {
+		t.Fatalf("Acquire over a stale lock: %v", err)
+	}
+	defer l.Release()
+	if got, _, err := a.RunLock(ctx); err != nil || got.Command != "connect" {
+		t.Errorf("RunLock = %+v, %v; want connect taking over", got, err)
+	}
+	// The crashed run's token no longer holds anything.
+	if held, err := a.HeartbeatRunLock(ctx, "crashed", time.Now()); err != nil || held {
+		t.Errorf("stale heartbeat = %v, %v; want not held", held, err)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	defer func(d time.Duration) { heartbeatEvery = d }(heartbeatEvery)
+	heartbeatEvery = 10 * time.Millisecond
+
+	ctx := context.Background()
+	a, b := openTwice(t)
+	l, err := Acquire(ctx, a, "connect", testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _, err := b.RunLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if beat, _, err := b.RunLock(ctx); err != nil || !beat.HeartbeatAt.After(start.HeartbeatAt) {
+		t.Errorf("heartbeat %s not refreshed from %s (%v)", beat.HeartbeatAt, start.HeartbeatAt, err)
+	}
+	select {
+	case <-l.Lost():
+		t.Fatal("lock reported lost while held")
+	default:
+	}
+
+	// Another process decides the lock is stale and takes it over.
+	now := time.Now()
+	other := storage.RunLockHolder{PID: 2, Host: "other", Command: "run", AcquiredAt: now, HeartbeatAt: now}
+	if _, err := b.AcquireRunLock(ctx, "other", other, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lost not closed after the takeover")
+	}
+
+	// Releasing a lost lock leaves the new holder alone.
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if holder, found, err := b.RunLock(ctx); err != nil || !found || holder.Host != "other" {
+		t.Errorf("RunLock after Release = %+v, %v, %v; want the new holder", holder, found, err)
+	}
+}
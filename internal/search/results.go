@@ -0,0 +1,206 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/profileurl"
+	"linkedin-automation-poc/internal/storage"
+)
+
+// SearchResult is one person parsed from a search result card.
+type SearchResult struct {
+	// ProfileURL is the canonical profile URL (see package profileurl).
+	ProfileURL string
+	Name       string
+	Headline   string
+	// Company is taken from the headline (see CompanyFromHeadline) and is
+	// empty when the headline does not name one.
+	Company  string
+	Location string
+	// Degree is the connection degree badge: "1st", "2nd", "3rd+" or ""
+	// when the card shows none.
+	Degree string
+	// Keyword and Page record where the person was first found.
+	Keyword string
+	Page    int
+}
+
+// Candidate converts a result into a pending entry for the review queue.
+func (r SearchResult) Candidate() storage.Candidate {
+	return storage.Candidate{
+		ProfileURL: r.ProfileURL,
+		Name:       r.Name,
+		Headline:   r.Headline,
+		Company:    r.Company,
+		Location:   r.Location,
+		Degree:     r.Degree,
+		Keyword:    r.Keyword,
+		Status:     storage.CandidatePending,
+	}
+}
+
+// PageStats describes the extraction of one results page. A page with
+// Cards == 0 usually means LinkedIn changed its markup (or served an
+// interstitial) rather than that the search had no matches.
+type PageStats struct {
+	Keyword string
+	Page    int
+	// Cards is the number of result cards found on the page.
+	Cards int
+	// Parsed is the number of cards that yielded a profile.
+	Parsed int
+	// Duplicates counts parsed cards already found earlier in the run.
+	Duplicates int
+	// Excluded counts parsed cards dropped by the do-not-contact list.
+	Excluded int
+	// Last is set by SearchPage when the page has no "Next" button.
+	Last bool
+}
+
+// Selectors are tried in order; the first one that matches anything wins.
+// LinkedIn ships several result layouts at once, so each field has a few
+// known variants.
+var (
+	cardSelectors = []string{
+		"li.reusable-search__result-container",
+		"div[data-chameleon-result-urn]",
+		"li.search-result",
+	}
+	linkSelectors = []string{
+		"span.entity-result__title-text a",
+		"a.app-aware-link[href*='/in/']",
+		"a[href*='/in/']",
+	}
+	nameSelectors     = []string{"span[aria-hidden=true]"}
+	headlineSelectors = []string{".entity-result__primary-subtitle", ".search-result__subtitle"}
+	locationSelectors = []string{".entity-result__secondary-subtitle", ".search-result__location"}
+	degreeSelectors   = []string{".entity-result__badge-text", ".dist-value"}
+)
+
+var (
+	degreeRe = regexp.MustCompile(`(1st|2nd|3rd\+?)`)
+	// companyRe finds the employer in headlines such as "Engineer at Acme"
+	// or "Engineer @ Acme | Go, Kafka". After "at" the name must start with
+	// a capital letter or digit, which rules out "Looking at new
+	// opportunities".
+	companyRe = regexp.MustCompile(`(?:\s(?i:at)\s+([\p{Lu}\d][^|,•·]*)|@\s*([^\s|,•·][^|,•·]*))`)
+	// companySuffixRe cuts a tagline off the company: "Acme - we are hiring".
+	companySuffixRe = regexp.MustCompile(`\s+[-–—]\s.*$`)
+)
+
+// notCompanies are words that follow "at" in title-cased headlines without
+// naming an employer: "Data Scientist At Heart", "Best At Scale".
+var notCompanies = map[string]bool{
+	"heart": true, "home": true, "large": true, "scale": true, "work": true,
+	"new": true, "night": true, "best": true, "least": true, "present": true,
+}
+
+// CompanyFromHeadline returns the company named in a LinkedIn headline, or
+// "" when it names none.
+func CompanyFromHeadline(headline string) string {
+	for _, m := range companyRe.FindAllStringSubmatch(headline, -1) {
+		company := m[1] + m[2]
+		company = strings.TrimSpace(companySuffixRe.ReplaceAllString(company, ""))
+		first, _, _ := strings.Cut(company, " ")
+		if company == "" || notCompanies[strings.ToLower(first)] {
+			continue
+		}
+		return company
+	}
+	return ""
+}
+
+// extractResults parses every result card on the current page. Only cards
+// are considered, so navigation links, the user's own profile and "people
+// also viewed" sidebars are ignored.
+func extractResults(drv driver.Driver, keyword string, page int) ([]SearchResult, int, error) {
+	var cards []driver.Element
+	for _, sel := range cardSelectors {
+		els, err := drv.FindAll(sel)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(els) > 0 {
+			cards = els
+			break
+		}
+	}
+
+	var out []SearchResult
+	for _, card := range cards {
+		r, ok := parseCard(card)
+		if !ok {
+			continue
+		}
+		r.Keyword, r.Page = keyword, page
+		out = append(out, r)
+	}
+	return out, len(cards), nil
+}
+
+func parseCard(card driver.Element) (SearchResult, bool) {
+	var r SearchResult
+
+	link := firstChild(card, linkSelectors)
+	if link == nil {
+		return r, false
+	}
+	href, ok, _ := link.Attribute("href")
+	if !ok {
+		return r, false
+	}
+	u, err := profileurl.Canonical(href)
+	if err != nil {
+		return r, false
+	}
+	r.ProfileURL = u
+
+	// The visible name sits in an aria-hidden span next to a screen-reader
+	// variant ("View Jane's profile"); fall back to the link text.
+	if el := firstChild(link, nameSelectors); el != nil {
+		r.Name = childText(el)
+	}
+	if r.Name == "" {
+		r.Name = firstLine(childText(link))
+	}
+	r.Headline = textOf(card, headlineSelectors)
+	r.Company = CompanyFromHeadline(r.Headline)
+	r.Location = textOf(card, locationSelectors)
+	r.Degree = degreeRe.FindString(textOf(card, degreeSelectors))
+	return r, true
+}
+
+func firstChild(el driver.Element, selectors []string) driver.Element {
+	for _, sel := range selectors {
+		if child, found, err := el.Find(sel); err == nil && found {
+			return child
+		}
+	}
+	return nil
+}
+
+func textOf(el driver.Element, selectors []string) string {
+	if child := firstChild(el, selectors); child != nil {
+		return childText(child)
+	}
+	return ""
+}
+
+func childText(el driver.Element) string {
+	t, err := el.Text()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(t)
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"linkedin-automation-poc/internal/driver"
+)
+
+func TestCompanyFromHeadline(t *testing.T) {
+	tests := []struct {
+		headline, want string
+	}{
+		{"Senior Engineer at Acme", "Acme"},
+		{"Engineer @ Acme | Go, Kafka", "Acme"},
+		{"Engineer @Acme", "Acme"},
+		{"Backend Developer at 3M • Berlin", "3M"},
+		{"Staff Engineer at Acme Corp - we are hiring", "Acme Corp"},
+		{"Coach at Coca-Cola", "Coca-Cola"},
+		{"Looking at new opportunities", ""},
+		{"Data scientist at heart", ""},
+		{"Data Scientist At Heart", ""},
+		{"Looking at new opportunities | ex-Engineer at Globex", "Globex"},
+		{"Freelance Go developer", ""},
+		{"Founder | Speaker", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CompanyFromHeadline(tt.headline); got != tt.want {
+			t.Errorf("CompanyFromHeadline(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
+
+// card builds a search result card the way the current LinkedIn layout
+// nests it.
+func card(href, name, headline, location, degree string) *driver.FakeElement {
+	link := driver.El([]string{"span.entity-result__title-text a"}, name+"\nView "+name+"'s profile", map[string]string{"href": href})
+	link.Children = []*driver.FakeElement{driver.El([]string{"span[aria-hidden=true]"}, name, nil)}
+	c := driver.El([]string{"li.reusable-search__result-container"}, "", nil)
+	c.Children = []*driver.FakeElement{
+		link,
+		driver.El([]string{".entity-result__primary-subtitle"}, headline, nil),
+		driver.El([]string{".entity-result__secondary-subtitle"}, location, nil),
+		driver.El([]string{".entity-result__badge-text"}, "• "+degree, nil),
+	}
+	return c
+}
+
+func TestParseCard(t *testing.T) {
+	// An older layout: no aria-hidden name, so the link text is used.
+	legacy := driver.El([]string{"li.search-result"}, "", nil)
+	legacy.Children = []*driver.FakeElement{
+		driver.El([]string{"a[href*='/in/']"}, "\n  Bob Stone\n  Go developer", map[string]string{"href": "/in/Bob-Stone/"}),
+		driver.El([]string{".search-result__subtitle"}, "Looking at new opportunities", nil),
+		driver.El([]string{".dist-value"}, "3rd+", nil),
+	}
+	noLink := driver.El([]string{"li.search-result"}, "LinkedIn Member", nil)
+	company := driver.El([]string{"li.search-result"}, "", nil)
+	company.Children = []*driver.FakeElement{
+		driver.El([]string{"a[href*='/in/']"}, "Acme", map[string]string{"href": "https://www.linkedin.com/company/acme/"}),
+	}
+
+	tests := []struct {
+		name string
+		el   *driver.FakeElement
+		want SearchResult
+		ok   bool
+	}{
+		{
+			name: "current layout",
+			el:   card("https://de.linkedin.com/in/Jane-Doe?miniProfileUrn=x", "Jane Doe", "Engineer at Acme | Go", "Berlin", "2nd"),
+			want: SearchResult{
+				ProfileURL: "https://www.linkedin.com/in/jane-doe/",
+				Name:       "Jane Doe",
+				Headline:   "Engineer at Acme | Go",
+				Company:    "Acme",
+				Location:   "Berlin",
+				Degree:     "2nd",
+			},
+			ok: true,
+		},
+		{
+			name: "legacy layout",
+			el:   legacy,
+			want: SearchResult{
+				ProfileURL: "https://www.linkedin.com/in/bob-stone/",
+				Name:       "Bob Stone",
+				Headline:   "Looking at new opportunities",
+				Degree:     "3rd+",
+			},
+			ok: true,
+		},
+		{name: "out of network member", el: noLink},
+		{name: "company link", el: company},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := driver.NewFake()
+			f.Current().Add(tt.el)
+			el, found, err := f.Find(tt.el.Selectors[0])
+			if err != nil || !found {
+				t.Fatalf("Find = %v, %v", found, err)
+			}
+			got, ok := parseCard(el)
+			if ok != tt.ok || ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCard = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtractResults(t *testing.T) {
+	f := driver.NewFake()
+	f.Current().Add(
+		card("/in/jane-doe/", "Jane Doe", "Engineer at Acme", "Berlin", "2nd"),
+		card("/in/ann-lee/", "Ann Lee", "Data scientist at heart", "Paris", "3rd"),
+		driver.El([]string{"li.reusable-search__result-container"}, "LinkedIn Member", nil),
+		// Links outside the cards, such as the sidebar, are ignored.
+		driver.El([]string{"a[href*='/in/']"}, "Grace Green", map[string]string{"href": "/in/grace-backend/"}),
+	)
+
+	results, cards, err := extractResults(f, "golang", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cards != 3 {
+		t.Errorf("cards = %d, want 3", cards)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.ProfileURL+" "+r.Company)
+		if r.Keyword != "golang" || r.Page != 2 {
+			t.Errorf("%s found by %q on page %d, want golang on page 2", r.ProfileURL, r.Keyword, r.Page)
+		}
+	}
+	want := []string{"https://www.linkedin.com/in/jane-doe/ Acme", "https://www.linkedin.com/in/ann-lee/ "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+
+	// Without cards in any known layout nothing is extracted.
+	empty := driver.NewFake()
+	if results, cards, err := extractResults(empty, "golang", 1); err != nil || cards != 0 || len(results) != 0 {
+		t.Errorf("empty page: %d results from %d cards, %v", len(results), cards, err)
+	}
+}
@@ -4,92 +4,58 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"strings"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/sirupsen/logrus"
 
+	"linkedin-automation-poc/internal/auth"
 	"linkedin-automation-poc/internal/config"
+	"linkedin-automation-poc/internal/driver"
+	"linkedin-automation-poc/internal/dryrun"
 	"linkedin-automation-poc/internal/stealth"
+	"linkedin-automation-poc/internal/storage"
 )
 
 // SearchProfiles performs a simple LinkedIn people search for the configured
-// keywords, walking through a few pages of results and returning unique
-// profile URLs.
-func SearchProfiles(ctx context.Context, br *rod.Browser, cfg config.SearchConfig, log *logrus.Logger) ([]string, error) {
-	page, err := br.Page(proto.TargetCreateTarget{URL: "about:blank"})
-	if err != nil {
-		return nil, fmt.Errorf("open page: %w", err)
-	}
-
-	unique := make(map[string]struct{})
+// keywords, walking through a few pages of results and returning the unique
+// people parsed from the result cards, in the order they were found, along
+// with per-page extraction stats. baseURL is the site root the search URL is
+// built from. People on the do-not-contact list are left out of the results;
+// in a dry run (non-nil report) each of them is also added to the report.
+// A lost session or a checkpoint that is not resolved in time (see
+// auth.Guard) stops the search, and so does a canceled ctx, with an error
+// wrapping ctx.Err(); results found so far are still returned.
+func SearchProfiles(ctx context.Context, drv driver.Driver, store *storage.Storage, guard *auth.Guard, baseURL string, cfg config.SearchConfig, report *dryrun.Report, log *logrus.Logger) ([]SearchResult, []PageStats, error) {
+	var (
+		results []SearchResult
+		stats   []PageStats
+	)
+	seen := make(map[string]bool)
 keywordLoop:
 	for _, kw := range cfg.Keywords {
 		// Check if context was canceled
 		select {
 		case <-ctx.Done():
 			log.WithError(ctx.Err()).Warn("context canceled, stopping search")
-			return make([]string, 0), nil
+			return results, stats, fmt.Errorf("search: %w", ctx.Err())
 		default:
 		}
 
 		log.WithField("keyword", kw).Info("running LinkedIn search")
 
-		searchURL := fmt.Sprintf("https://www.linkedin.com/search/results/people/?keywords=%s", url.QueryEscape(kw))
+		searchURL := fmt.Sprintf("%s/search/results/people/?keywords=%s", baseURL, url.QueryEscape(kw))
 
-		// Add timeout for navigation
-		navCtx, navCancel := context.WithTimeout(ctx, 45*time.Second)
-		navDone := make(chan error, 1)
-		go func() {
-			if err := page.Navigate(searchURL); err != nil {
-				navDone <- err
-				return
-			}
-			if err := page.WaitLoad(); err != nil {
-				navDone <- err
-				return
-			}
-			// Wait a bit for content to render
-			time.Sleep(2 * time.Second)
-			navDone <- nil
-		}()
-
-		select {
-		case err := <-navDone:
-			navCancel()
-			if err != nil {
-				log.WithError(err).WithField("keyword", kw).Warn("failed to navigate/search, skipping keyword")
-				continue
-			}
-		case <-navCtx.Done():
-			navCancel()
-			log.WithError(navCtx.Err()).WithField("keyword", kw).Warn("search navigation timeout, skipping keyword")
+		if err := drv.Navigate(ctx, searchURL); err != nil {
+			log.WithError(err).WithField("keyword", kw).Warn("failed to navigate/search, skipping keyword")
 			continue
 		}
+		// Wait a bit for content to render
+		time.Sleep(2 * time.Second)
 
-		// Check if we're on a checkpoint/challenge page
-		info, err := page.Info()
-		if err == nil && info.URL != "" {
-			if strings.Contains(info.URL, "checkpoint") || strings.Contains(info.URL, "challenge") {
-				log.WithField("url", info.URL).WithField("keyword", kw).Warn("⚠️  CHECKPOINT DETECTED - Please complete verification in the browser window, then wait 30 seconds")
-				log.Warn("Waiting 30 seconds for you to complete the checkpoint...")
-
-				// Wait for user to complete checkpoint
-				checkpointCtx, checkpointCancel := context.WithTimeout(ctx, 30*time.Second)
-				<-checkpointCtx.Done()
-				checkpointCancel()
-
-				// Check again if still on checkpoint
-				if info2, err2 := page.Info(); err2 == nil && info2.URL != "" {
-					if strings.Contains(info2.URL, "checkpoint") || strings.Contains(info2.URL, "challenge") {
-						log.WithField("url", info2.URL).Warn("Still on checkpoint page - skipping this search keyword")
-						continue
-					}
-					log.Info("Checkpoint appears resolved, continuing with search")
-				}
-			}
+		// A checkpoint pauses here until a human resolves it (see
+		// auth.CheckpointHandler).
+		if err := guard.Check(ctx, searchURL); err != nil {
+			return results, stats, fmt.Errorf("search %q: %w", kw, err)
 		}
 
 		for pageIdx := 0; pageIdx < cfg.MaxPages; pageIdx++ {
@@ -97,91 +63,126 @@ keywordLoop:
 			select {
 			case <-ctx.Done():
 				log.WithError(ctx.Err()).Warn("context canceled during pagination")
-				break keywordLoop
+				return results, stats, fmt.Errorf("search %q: %w", kw, ctx.Err())
 			default:
 			}
 
-			// Let content load and scroll a bit to trigger lazy loading.
-			stealth.RandomDelay(cfg.PageDelayMin, cfg.PageDelayMax)
-
-			// Scroll to trigger lazy loading of profile cards
-			if err := stealth.ScrollHumanLike(page, 3*time.Second); err != nil {
-				log.WithError(err).Warn("failed to scroll page")
-			}
-
-			// Wait a bit more for content to load after scrolling
-			time.Sleep(1 * time.Second)
-
-			urls, err := extractProfileURLs(page)
-			if err != nil {
-				log.WithError(err).Warn("failed to extract some profile URLs")
-			}
-			if len(urls) == 0 && pageIdx == 0 {
-				log.WithField("keyword", kw).Warn("no profile URLs found on first page - page may not have loaded correctly")
-			}
-			for _, u := range urls {
-				unique[u] = struct{}{}
-			}
-			log.WithField("keyword", kw).WithField("page", pageIdx+1).WithField("found", len(urls)).Debug("extracted profile URLs from page")
+			parsed, st := readPage(ctx, drv, store, cfg, kw, pageIdx+1, seen, report, log)
+			results = append(results, parsed...)
+			stats = append(stats, st)
 
 			// Attempt to go to the "next" page if available.
-			nextBtn, err := page.ElementR("button, a", "Next")
-			if err != nil || nextBtn == nil {
+			nextBtn, found, err := drv.FindByText("button, a", "Next")
+			if err != nil || !found {
 				break
 			}
-			if err := nextBtn.Click("left", 1); err != nil {
+			if err := nextBtn.Click(); err != nil {
 				log.WithError(err).Warn("failed to click next button")
 				break
 			}
 
-			// Add timeout for page load
-			loadCtx, loadCancel := context.WithTimeout(ctx, 15*time.Second)
-			loadDone := make(chan error, 1)
-			go func() {
-				loadDone <- page.WaitLoad()
-			}()
-
-			select {
-			case err := <-loadDone:
-				loadCancel()
-				if err != nil {
-					log.WithError(err).Warn("failed to wait for next page load")
-					break
-				}
-			case <-loadCtx.Done():
-				loadCancel()
-				log.WithError(loadCtx.Err()).Warn("page load timeout")
+			if err := drv.WaitLoad(15 * time.Second); err != nil {
+				log.WithError(err).Warn("failed to wait for next page load")
 				break keywordLoop
 			}
+			if err := guard.Check(ctx, ""); err != nil {
+				return results, stats, fmt.Errorf("search %q: %w", kw, err)
+			}
 		}
 	}
 
-	out := make([]string, 0, len(unique))
-	for u := range unique {
-		out = append(out, u)
+	log.WithField("total_profiles", len(results)).Info("search completed")
+	return results, stats, nil
+}
+
+// SearchPage reads results page page (counting from 1) of the search for
+// kw, going to it directly rather than through the "Next" button, so a
+// resumed run can start at any page. It filters like SearchProfiles, and
+// seen, if not nil, carries the profiles already found by earlier pages.
+// Failing to load the page is returned as an error; a page without a "Next"
+// button is marked Last.
+func SearchPage(ctx context.Context, drv driver.Driver, store *storage.Storage, guard *auth.Guard, baseURL string, cfg config.SearchConfig, kw string, page int, seen map[string]bool, report *dryrun.Report, log *logrus.Logger) ([]SearchResult, PageStats, error) {
+	searchURL := fmt.Sprintf("%s/search/results/people/?keywords=%s", baseURL, url.QueryEscape(kw))
+	if page > 1 {
+		searchURL += fmt.Sprintf("&page=%d", page)
+	}
+	log.WithField("keyword", kw).WithField("page", page).Info("running LinkedIn search")
+	if err := drv.Navigate(ctx, searchURL); err != nil {
+		return nil, PageStats{}, fmt.Errorf("search %q page %d: %w", kw, page, err)
 	}
-	log.WithField("total_profiles", len(out)).Info("search completed")
-	return out, nil
+	time.Sleep(2 * time.Second)
+	if err := guard.Check(ctx, searchURL); err != nil {
+		return nil, PageStats{}, fmt.Errorf("search %q page %d: %w", kw, page, err)
+	}
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	results, st := readPage(ctx, drv, store, cfg, kw, page, seen, report, log)
+	if _, found, err := drv.FindByText("button, a", "Next"); err == nil && !found {
+		st.Last = true
+	}
+	return results, st, nil
 }
 
-func extractProfileURLs(page *rod.Page) ([]string, error) {
-	links, err := page.Elements("a")
+// readPage extracts the results on the current page, leaving out people in
+// seen and on the do-not-contact list, and adds the rest to seen.
+func readPage(ctx context.Context, drv driver.Driver, store *storage.Storage, cfg config.SearchConfig, kw string, page int, seen map[string]bool, report *dryrun.Report, log *logrus.Logger) ([]SearchResult, PageStats) {
+	var results []SearchResult
+
+	// Let content load and scroll a bit to trigger lazy loading.
+	stealth.RandomDelay(cfg.PageDelayMin, cfg.PageDelayMax)
+
+	// Scroll to trigger lazy loading of profile cards
+	if err := drv.Scroll(3 * time.Second); err != nil {
+		log.WithError(err).Warn("failed to scroll page")
+	}
+
+	// Wait a bit more for content to load after scrolling
+	time.Sleep(1 * time.Second)
+
+	parsed, cards, err := extractResults(drv, kw, page)
 	if err != nil {
-		return nil, err
+		log.WithError(err).Warn("failed to extract search results")
 	}
-	var profiles []string
-	for _, a := range links {
-		href, err := a.Attribute("href")
-		if err != nil || href == nil {
+	st := PageStats{Keyword: kw, Page: page, Cards: cards, Parsed: len(parsed)}
+	for _, r := range parsed {
+		if seen[r.ProfileURL] {
+			st.Duplicates++
 			continue
 		}
-		u := *href
-		if strings.Contains(u, "/in/") { // basic heuristic for profile URLs
-			if idx := strings.Index(u, "?"); idx > 0 {
-				u = u[:idx]
+		seen[r.ProfileURL] = true
+		dnc, blocked, err := store.MatchDoNotContact(ctx, r.ProfileURL, r.Company)
+		if err != nil {
+			log.WithError(err).WithField("profile", r.ProfileURL).Warn("failed to check do-not-contact list, leaving profile out")
+			st.Excluded++
+			continue
+		}
+		if blocked {
+			log.WithField("profile", r.ProfileURL).
+				WithField("dnc_kind", dnc.Kind).
+				WithField("dnc_reason", dnc.Reason).
+				Info("profile is on the do-not-contact list, leaving it out of results")
+			if report != nil {
+				report.Skip("search", r.ProfileURL, "on do-not-contact list: "+string(dnc.Kind)+" "+dnc.Value)
 			}
-			profiles = append(profiles, u)
+			st.Excluded++
+			continue
 		}
+		results = append(results, r)
+	}
+
+	plog := log.WithField("keyword", kw).WithField("page", st.Page)
+	switch {
+	case st.Cards == 0:
+		plog.Warn("no result cards found - page may not have loaded or the markup changed")
+	case st.Parsed == 0:
+		plog.WithField("cards", st.Cards).Warn("result cards found but none could be parsed - the markup may have changed")
+	default:
+		plog.WithField("cards", st.Cards).
+			WithField("parsed", st.Parsed).
+			WithField("duplicates", st.Duplicates).
+			WithField("excluded", st.Excluded).
+			Debug("extracted search results from page")
 	}
-	return profiles, nil
+	return results, st
 }
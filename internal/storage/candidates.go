@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CandidateStatus is where a search result stands in the review queue.
+type CandidateStatus string
+
+const (
+	CandidatePending  CandidateStatus = "pending"
+	CandidateApproved CandidateStatus = "approved"
+	CandidateRejected CandidateStatus = "rejected"
+)
+
+// ParseCandidateStatus validates a status given on the command line.
+func ParseCandidateStatus(s string) (CandidateStatus, error) {
+	switch st := CandidateStatus(s); st {
+	case CandidatePending, CandidateApproved, CandidateRejected:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown candidate status %q (want pending, approved or rejected)", s)
+	}
+}
+
+// Candidate is a person found by search together with their review status.
+type Candidate struct {
+	ProfileURL string
+	Name       string
+	Headline   string
+	Company    string
+	Location   string
+	Degree     string
+	// Keyword is the search keyword that first found the person.
+	Keyword string
+	Status  CandidateStatus
+	FoundAt time.Time
+	// ReviewedAt is zero until someone approves or rejects the candidate.
+	ReviewedAt time.Time
+}
+
+// SaveCandidate adds a search result to the review queue as pending. A
+// person already in the queue keeps their status and original keyword; only
+// the profile details are refreshed (blank fields keep the stored value).
+// isNew reports whether the row was added.
+func (s *Storage) SaveCandidate(ctx context.Context, c Candidate, when time.Time) (isNew bool, err error) {
+	c.ProfileURL = profileKey(c.ProfileURL)
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO candidates (profile_url, name, headline, company, location, degree, keyword, status, found_at, run_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(profile_url) DO NOTHING`,
+		c.ProfileURL, c.Name, c.Headline, c.Company, c.Location, c.Degree, c.Keyword, string(CandidatePending), when.UTC(), nullRunID(ctx),
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE candidates SET
+			name = COALESCE(NULLIF(?, ''), name),
+			headline = COALESCE(NULLIF(?, ''), headline),
+			company = COALESCE(NULLIF(?, ''), company),
+			location = COALESCE(NULLIF(?, ''), location),
+			degree = COALESCE(NULLIF(?, ''), degree)
+		 WHERE profile_url = ?`,
+		c.Name, c.Headline, c.Company, c.Location, c.Degree, c.ProfileURL,
+	)
+	return false, err
+}
+
+// SetCandidateStatus records a review decision. updated is false when the
+// profile is not in the queue.
+func (s *Storage) SetCandidateStatus(ctx context.Context, profileURL string, status CandidateStatus, when time.Time) (updated bool, err error) {
+	if _, err := ParseCandidateStatus(string(status)); err != nil {
+		return false, err
+	}
+	var reviewedAt any
+	if status != CandidatePending {
+		reviewedAt = when.UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE candidates SET status = ?, reviewed_at = ? WHERE profile_url = ?`,
+		string(status), reviewedAt, profileKey(profileURL),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const candidateColumns = `profile_url, name, headline, company, location, degree, keyword, status, found_at, reviewed_at`
+
+func scanCandidate(row interface{ Scan(...any) error }) (Candidate, error) {
+	var c Candidate
+	var reviewedAt sql.NullTime
+	err := row.Scan(&c.ProfileURL, &c.Name, &c.Headline, &c.Company, &c.Location, &c.Degree, &c.Keyword, &c.Status, &c.FoundAt, &reviewedAt)
+	if reviewedAt.Valid {
+		c.ReviewedAt = reviewedAt.Time
+	}
+	return c, err
+}
+
+// Candidates returns the queue in the order people were found. An empty
+// status returns every candidate.
+func (s *Storage) Candidates(ctx context.Context, status CandidateStatus) ([]Candidate, error) {
+	query := `SELECT ` + candidateColumns + ` FROM candidates`
+	var args []any
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, string(status))
+	}
+	rows, err := s.db.QueryContext(ctx, query+` ORDER BY found_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Candidate
+	for rows.Next() {
+		c, err := scanCandidate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// CandidateFor returns the queue entry for one profile.
+func (s *Storage) CandidateFor(ctx context.Context, profileURL string) (Candidate, bool, error) {
+	c, err := scanCandidate(s.db.QueryRowContext(ctx,
+		`SELECT `+candidateColumns+` FROM candidates WHERE profile_url = ?`, profileKey(profileURL)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Candidate{}, false, nil
+	}
+	if err != nil {
+		return Candidate{}, false, err
+	}
+	return c, true, nil
+}
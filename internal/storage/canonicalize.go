@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"linkedin-automation-poc/internal/profileurl"
+)
+
+// profileKey canonicalizes a profile reference for use as a storage key.
+// References that are not profile URLs are stored as given.
+func profileKey(ref string) string {
+	if c, err := profileurl.Canonical(ref); err == nil {
+		return c
+	}
+	return ref
+}
+
+// requestStatusRank orders states by how much they tell us, so that merging
+// duplicates keeps the most advanced one.
+var requestStatusRank = map[RequestStatus]int{
+	RequestQueued:               0,
+	RequestSkippedNoButton:      1,
+	RequestFailed:               2,
+	RequestUnconfirmed:          3,
+	RequestBlockedEmailRequired: 4,
+	RequestPending:              5,
+	RequestSent:                 6,
+	RequestWithdrawn:            7,
+	RequestAccepted:             8,
+}
+
+// MergeReport describes what the profile URL canonicalization changed.
+type MergeReport struct {
+	// RequestsMerged and ConnectionsMerged count rows deleted because they
+	// duplicated another row once canonicalized.
+	RequestsMerged    int
+	ConnectionsMerged int
+	// Rewritten counts rows whose profile_url was rewritten in place.
+	Rewritten int
+}
+
+// Changed reports whether the merge touched anything.
+func (r MergeReport) Changed() bool {
+	return r.RequestsMerged+r.ConnectionsMerged+r.Rewritten > 0
+}
+
+// mergeDuplicateProfiles rewrites every stored profile_url into canonical
+// form and merges rows that turn out to describe the same person. It is
+// idempotent; callers provide the transaction.
+func mergeDuplicateProfiles(ctx context.Context, tx *sql.Tx) (MergeReport, error) {
+	var rep MergeReport
+	if err := mergeRequests(ctx, tx, &rep); err != nil {
+		return rep, err
+	}
+	if err := mergeConnections(ctx, tx, &rep); err != nil {
+		return rep, err
+	}
+	if err := rewriteMessages(ctx, tx, &rep); err != nil {
+		return rep, err
+	}
+	return rep, nil
+}
+
+type requestRow struct {
+	id          int64
+	url         string
+	sentAt      time.Time
+	status      RequestStatus
+	reason      string
+	confirmedAt sql.NullTime
+	updatedAt   sql.NullTime
+}
+
+func mergeRequests(ctx context.Context, tx *sql.Tx, rep *MergeReport) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, profile_url, sent_at, status, reason, confirmed_at, updated_at FROM sent_requests ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]requestRow)
+	var order []string
+	for rows.Next() {
+		var r requestRow
+		if err := rows.Scan(&r.id, &r.url, &r.sentAt, &r.status, &r.reason, &r.confirmedAt, &r.updatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		key := profileKey(r.url)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 && group[0].url == key {
+			continue
+		}
+
+		win := group[0]
+		merged := win
+		for _, r := range group[1:] {
+			if requestStatusRank[r.status] > requestStatusRank[win.status] {
+				win = r
+			}
+			if r.sentAt.Before(merged.sentAt) {
+				merged.sentAt = r.sentAt
+			}
+			if r.confirmedAt.Valid && (!merged.confirmedAt.Valid || r.confirmedAt.Time.Before(merged.confirmedAt.Time)) {
+				merged.confirmedAt = r.confirmedAt
+			}
+			if r.updatedAt.Valid && (!merged.updatedAt.Valid || r.updatedAt.Time.After(merged.updatedAt.Time)) {
+				merged.updatedAt = r.updatedAt
+			}
+		}
+
+		for _, r := range group {
+			if r.id == win.id {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM sent_requests WHERE id = ?`, r.id); err != nil {
+				return err
+			}
+			rep.RequestsMerged++
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE sent_requests SET profile_url = ?, sent_at = ?, status = ?, reason = ?, confirmed_at = ?, updated_at = ? WHERE id = ?`,
+			key, merged.sentAt, string(win.status), win.reason, merged.confirmedAt, merged.updatedAt, win.id,
+		); err != nil {
+			return err
+		}
+		if win.url != key {
+			rep.Rewritten++
+		}
+	}
+	return nil
+}
+
+func mergeConnections(ctx context.Context, tx *sql.Tx, rep *MergeReport) error {
+	type connRow struct {
+		id        int64
+		url, name string
+		firstSeen time.Time
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT id, profile_url, name, first_seen_at FROM connections ORDER BY first_seen_at, id`)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]connRow)
+	var order []string
+	for rows.Next() {
+		var r connRow
+		if err := rows.Scan(&r.id, &r.url, &r.name, &r.firstSeen); err != nil {
+			rows.Close()
+			return err
+		}
+		key := profileKey(r.url)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 && group[0].url == key {
+			continue
+		}
+		// Rows are ordered by first_seen_at, so the first one is kept.
+		keep := group[0]
+		for _, r := range group[1:] {
+			if keep.name == "" {
+				keep.name = r.name
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM connections WHERE id = ?`, r.id); err != nil {
+				return err
+			}
+			rep.ConnectionsMerged++
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE connections SET profile_url = ?, name = ? WHERE id = ?`, key, keep.name, keep.id,
+		); err != nil {
+			return err
+		}
+		if keep.url != key {
+			rep.Rewritten++
+		}
+	}
+	return nil
+}
+
+// rewriteMessages canonicalizes messages.profile_url in place; messages have
+// no uniqueness constraint, so nothing needs merging.
+func rewriteMessages(ctx context.Context, tx *sql.Tx, rep *MergeReport) error {
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT profile_url FROM messages`)
+	if err != nil {
+		return err
+	}
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			rows.Close()
+			return err
+		}
+		urls = append(urls, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, u := range urls {
+		key := profileKey(u)
+		if key == u {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE messages SET profile_url = ? WHERE profile_url = ?`, key, u)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		rep.Rewritten += int(n)
+	}
+	return nil
+}
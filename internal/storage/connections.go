@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// RecordConnection stores a first-degree connection seen on the user's
+// connections list. The first sighting wins, so first_seen_at approximates
+// when the connection was made; isNew reports whether this was it.
+func (s *Storage) RecordConnection(ctx context.Context, profileURL, name string, seenAt time.Time) (isNew bool, err error) {
+	profileURL = profileKey(profileURL)
+	res, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO connections (profile_url, name, first_seen_at) VALUES (?, ?, ?)`,
+		profileURL, name, seenAt.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkRequestAccepted moves the profile's request to accepted when this
+// tool has a row for it. updated is false when there is no row (the
+// connection was made outside the tool) or it is already accepted.
+func (s *Storage) MarkRequestAccepted(ctx context.Context, profileURL string, when time.Time) (updated bool, err error) {
+	current, found, err := s.RequestStatusFor(ctx, profileURL)
+	if err != nil || !found || current == RequestAccepted {
+		return false, err
+	}
+	if err := s.SetRequestStatus(ctx, profileURL, RequestAccepted, "seen in connections list", when); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AcceptedInvitations returns the profiles that accepted an invitation this
+// tool confirmed as sent, oldest acceptance first.
+func (s *Storage) AcceptedInvitations(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT profile_url FROM sent_requests
+		 WHERE status = ? AND confirmed_at IS NOT NULL
+		 ORDER BY updated_at`,
+		string(RequestAccepted),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMarkRequestAccepted(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	profile := func(id string) string { return "https://www.linkedin.com/in/" + id + "/" }
+
+	for id, status := range map[string]RequestStatus{
+		"sent":        RequestSent,
+		"unconfirmed": RequestUnconfirmed,
+		"failed":      RequestFailed,
+		"withdrawn":   RequestWithdrawn,
+	} {
+		if err := s.SetRequestStatus(ctx, profile(id), RequestQueued, "", at); err != nil {
+			t.Fatal(err)
+		}
+		if status == RequestWithdrawn {
+			if err := s.SetRequestStatus(ctx, profile(id), RequestSent, "", at); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SetRequestStatus(ctx, profile(id), status, "", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		profile     string
+		wantUpdated bool
+		// wantStatus is empty when there is no row.
+		wantStatus RequestStatus
+	}{
+		{profile("sent"), true, RequestAccepted},
+		// The connections list links to profiles in other forms.
+		{"https://de.linkedin.com/in/Unconfirmed?miniProfileUrn=x", true, RequestAccepted},
+		{profile("failed"), true, RequestAccepted},
+		{profile("withdrawn"), true, RequestAccepted},
+		// Accepting twice changes nothing.
+		{profile("sent"), false, RequestAccepted},
+		// A connection made outside the tool gets no row.
+		{profile("stranger"), false, ""},
+	}
+	for _, tt := range tests {
+		updated, err := s.MarkRequestAccepted(ctx, tt.profile, at.Add(time.Hour))
+		if err != nil || updated != tt.wantUpdated {
+			t.Errorf("MarkRequestAccepted(%s) = %v, %v; want %v", tt.profile, updated, err, tt.wantUpdated)
+		}
+		status, found, err := s.RequestStatusFor(ctx, tt.profile)
+		if err != nil || found != (tt.wantStatus != "") || status != tt.wantStatus {
+			t.Errorf("RequestStatusFor(%s) = %q, %v, %v; want %q", tt.profile, status, found, err, tt.wantStatus)
+		}
+	}
+
+	// Only invitations the tool confirmed as sent are followed up.
+	accepted, err := s.AcceptedInvitations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(accepted)
+	if want := []string{profile("sent"), profile("withdrawn")}; !reflect.DeepEqual(accepted, want) {
+		t.Errorf("AcceptedInvitations = %q, want %q", accepted, want)
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"linkedin-automation-poc/internal/profileurl"
+)
+
+// DoNotContactKind says what a do-not-contact entry is keyed by.
+type DoNotContactKind string
+
+const (
+	DoNotContactProfile DoNotContactKind = "profile"
+	DoNotContactCompany DoNotContactKind = "company"
+)
+
+// DoNotContact is one entry of the do-not-contact list.
+type DoNotContact struct {
+	Kind    DoNotContactKind
+	Value   string
+	Reason  string
+	AddedAt time.Time
+}
+
+// NormalizeCompany lower-cases a company name and collapses whitespace so
+// "Acme  Corp" and "acme corp" are the same entry.
+func NormalizeCompany(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// normalizeDoNotContact validates kind and returns the stored form of value.
+func normalizeDoNotContact(kind DoNotContactKind, value string) (string, error) {
+	switch kind {
+	case DoNotContactProfile:
+		return profileurl.Canonical(value)
+	case DoNotContactCompany:
+		v := NormalizeCompany(value)
+		if v == "" {
+			return "", errors.New("empty company name")
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown do-not-contact kind %q", kind)
+	}
+}
+
+// AddDoNotContact adds (or updates the reason of) an entry. The original
+// added_at is kept when the entry already exists.
+func (s *Storage) AddDoNotContact(ctx context.Context, kind DoNotContactKind, value, reason string, when time.Time) error {
+	v, err := normalizeDoNotContact(kind, value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO do_not_contact (kind, value, reason, added_at) VALUES (?, ?, ?, ?)
+ON CONFLICT(kind, value) DO UPDATE SET reason = excluded.reason`,
+		string(kind), v, reason, when.UTC(),
+	)
+	return err
+}
+
+// RemoveDoNotContact deletes an entry; removed is false when none matched.
+func (s *Storage) RemoveDoNotContact(ctx context.Context, kind DoNotContactKind, value string) (removed bool, err error) {
+	v, err := normalizeDoNotContact(kind, value)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM do_not_contact WHERE kind = ? AND value = ?`, string(kind), v)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListDoNotContact returns every entry, oldest first.
+func (s *Storage) ListDoNotContact(ctx context.Context) ([]DoNotContact, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, value, reason, added_at FROM do_not_contact ORDER BY added_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DoNotContact
+	for rows.Next() {
+		var e DoNotContact
+		if err := rows.Scan(&e.Kind, &e.Value, &e.Reason, &e.AddedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// MatchDoNotContact returns the entry that forbids contacting a profile,
+// checking the profile itself and then its company (which may be empty when
+// unknown).
+func (s *Storage) MatchDoNotContact(ctx context.Context, profileURL, company string) (DoNotContact, bool, error) {
+	if e, found, err := s.lookupDoNotContact(ctx, DoNotContactProfile, profileKey(profileURL)); err != nil || found {
+		return e, found, err
+	}
+	if c := NormalizeCompany(company); c != "" {
+		return s.lookupDoNotContact(ctx, DoNotContactCompany, c)
+	}
+	return DoNotContact{}, false, nil
+}
+
+func (s *Storage) lookupDoNotContact(ctx context.Context, kind DoNotContactKind, value string) (DoNotContact, bool, error) {
+	e := DoNotContact{Kind: kind, Value: value}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT reason, added_at FROM do_not_contact WHERE kind = ? AND value = ?`, string(kind), value,
+	).Scan(&e.Reason, &e.AddedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DoNotContact{}, false, nil
+	}
+	if err != nil {
+		return DoNotContact{}, false, err
+	}
+	return e, true, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMatchDoNotContact(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	entries := []struct {
+		kind          DoNotContactKind
+		value, reason string
+	}{
+		{DoNotContactProfile, "https://de.linkedin.com/in/Jane-Doe?trk=x", "asked us to stop"},
+		{DoNotContactCompany, "  Acme   Corp ", "customer"},
+		// Both the profile and the company are listed; the profile wins.
+		{DoNotContactProfile, "https://www.linkedin.com/in/bob/", "former colleague"},
+		{DoNotContactCompany, "Globex", "competitor"},
+	}
+	for _, e := range entries {
+		if err := s.AddDoNotContact(ctx, e.kind, e.value, e.reason, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		profile, company string
+		wantKind         DoNotContactKind
+		wantValue        string
+		wantReason       string
+	}{
+		{"https://www.linkedin.com/in/jane-doe/", "", DoNotContactProfile, "https://www.linkedin.com/in/jane-doe/", "asked us to stop"},
+		{"https://linkedin.com/in/JANE-DOE", "Initech", DoNotContactProfile, "https://www.linkedin.com/in/jane-doe/", "asked us to stop"},
+		{"https://www.linkedin.com/in/ann/", "acme corp", DoNotContactCompany, "acme corp", "customer"},
+		{"https://www.linkedin.com/in/ann/", "ACME\tCORP", DoNotContactCompany, "acme corp", "customer"},
+		{"https://www.linkedin.com/in/bob", "Globex", DoNotContactProfile, "https://www.linkedin.com/in/bob/", "former colleague"},
+		{"https://www.linkedin.com/in/carol/", "Globex", DoNotContactCompany, "globex", "competitor"},
+		// Only whole names match, and an unknown company matches nothing.
+		{"https://www.linkedin.com/in/ann/", "Acme", "", "", ""},
+		{"https://www.linkedin.com/in/ann/", "", "", "", ""},
+		{"https://www.linkedin.com/in/ann/", "   ", "", "", ""},
+		// A reference that is not a profile URL is looked up as given.
+		{"not a url", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		e, found, err := s.MatchDoNotContact(ctx, tt.profile, tt.company)
+		if err != nil {
+			t.Fatalf("MatchDoNotContact(%q, %q): %v", tt.profile, tt.company, err)
+		}
+		if found != (tt.wantKind != "") || e.Kind != tt.wantKind || e.Value != tt.wantValue || e.Reason != tt.wantReason {
+			t.Errorf("MatchDoNotContact(%q, %q) = %+v, %v; want %s %q (%s)", tt.profile, tt.company, e, found, tt.wantKind, tt.wantValue, tt.wantReason)
+		}
+		if found && !e.AddedAt.Equal(now) {
+			t.Errorf("MatchDoNotContact(%q, %q) added at %s, want %s", tt.profile, tt.company, e.AddedAt, now)
+		}
+	}
+}
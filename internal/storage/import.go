@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ParseRequestStatus validates a request state read from an import file.
+func ParseRequestStatus(s string) (RequestStatus, error) {
+	st := RequestStatus(s)
+	if _, ok := requestStatusRank[st]; !ok {
+		return "", fmt.Errorf("unknown request status %q", s)
+	}
+	return st, nil
+}
+
+// Import is a batch of records read from an export file.
+type Import struct {
+	Requests    []RequestRecord
+	Messages    []MessageRecord
+	Candidates  []Candidate
+	Connections []ConnectionRecord
+}
+
+// ImportCounts says what happened to the records of one type.
+type ImportCounts struct {
+	Added int
+	// Updated counts existing requests moved to a more advanced state.
+	Updated int
+	// Existing counts records that were already stored and left alone.
+	Existing int
+}
+
+// ImportResult holds the counts per record type.
+type ImportResult struct {
+	Requests, Messages, Candidates, Connections ImportCounts
+}
+
+// Import stores a batch in one transaction, matching people on their
+// canonical profile URL so nothing already stored is duplicated:
+//
+//   - a request that is sent or accepted but has no confirmation time is
+//     imported as queued;
+//   - a request for a known profile only replaces the stored one when its
+//     state is more advanced (accepted beats sent beats queued), the same
+//     rule used when duplicate rows are merged;
+//   - a message is skipped when the profile already has one of the same
+//     type sent at the same time;
+//   - candidates and connections already stored are kept as they are.
+func (s *Storage) Import(ctx context.Context, batch Import) (ImportResult, error) {
+	var res ImportResult
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	for _, r := range batch.Requests {
+		if err := importRequest(ctx, tx, r, &res.Requests); err != nil {
+			return res, fmt.Errorf("request %s: %w", r.ProfileURL, err)
+		}
+	}
+	for _, m := range batch.Messages {
+		if err := importMessage(ctx, tx, m, &res.Messages); err != nil {
+			return res, fmt.Errorf("message %s: %w", m.ProfileURL, err)
+		}
+	}
+	for _, c := range batch.Candidates {
+		n, err := insertCount(tx.ExecContext(ctx, `
+INSERT INTO candidates (profile_url, name, headline, company, location, degree, keyword, status, found_at, reviewed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(profile_url) DO NOTHING`,
+			profileKey(c.ProfileURL), c.Name, c.Headline, c.Company, c.Location, c.Degree, c.Keyword,
+			string(c.Status), c.FoundAt.UTC(), nullTime(c.ReviewedAt),
+		))
+		if err != nil {
+			return res, fmt.Errorf("candidate %s: %w", c.ProfileURL, err)
+		}
+		res.Candidates.count(n)
+	}
+	for _, c := range batch.Connections {
+		n, err := insertCount(tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO connections (profile_url, name, first_seen_at) VALUES (?, ?, ?)`,
+			profileKey(c.ProfileURL), c.Name, c.FirstSeenAt.UTC(),
+		))
+		if err != nil {
+			return res, fmt.Errorf("connection %s: %w", c.ProfileURL, err)
+		}
+		res.Connections.count(n)
+	}
+	return res, tx.Commit()
+}
+
+func (c *ImportCounts) count(inserted int64) {
+	if inserted > 0 {
+		c.Added++
+	} else {
+		c.Existing++
+	}
+}
+
+func importRequest(ctx context.Context, tx *sql.Tx, r RequestRecord, counts *ImportCounts) error {
+	key := profileKey(r.ProfileURL)
+	// Only invitations confirmed as delivered count as sent (and toward the
+	// limits); one without a confirmation time is queued so the next visit
+	// establishes its real state.
+	if (r.Status == RequestSent || r.Status == RequestAccepted) && r.ConfirmedAt.IsZero() {
+		r.Reason = "imported as " + string(r.Status) + " without a confirmation time"
+		r.Status = RequestQueued
+	}
+	// An unconfirmed invitation may have gone out; without its own time it
+	// counts from the last update.
+	if r.Status == RequestUnconfirmed && r.UnconfirmedAt.IsZero() {
+		r.UnconfirmedAt = r.UpdatedAt
+		if r.UnconfirmedAt.IsZero() {
+			r.UnconfirmedAt = r.SentAt
+		}
+	}
+	var current RequestStatus
+	err := tx.QueryRowContext(ctx, `SELECT status FROM sent_requests WHERE profile_url = ?`, key).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO sent_requests (profile_url, sent_at, status, reason, confirmed_at, unconfirmed_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			key, r.SentAt.UTC(), string(r.Status), r.Reason, nullTime(r.ConfirmedAt), nullTime(r.UnconfirmedAt), nullTime(r.UpdatedAt),
+		)
+		if err == nil {
+			counts.Added++
+		}
+		return err
+	case err != nil:
+		return err
+	case requestStatusRank[r.Status] <= requestStatusRank[current]:
+		counts.Existing++
+		return nil
+	}
+
+	// Keep the earliest send and confirmation, as mergeRequests does, and
+	// the earliest unconfirmed click.
+	_, err = tx.ExecContext(ctx, `
+UPDATE sent_requests SET
+	status = ?,
+	reason = ?,
+	sent_at = MIN(sent_at, ?),
+	confirmed_at = CASE
+		WHEN confirmed_at IS NULL THEN ?
+		WHEN ? IS NULL THEN confirmed_at
+		ELSE MIN(confirmed_at, ?) END,
+	unconfirmed_at = CASE
+		WHEN unconfirmed_at IS NULL THEN ?
+		WHEN ? IS NULL THEN unconfirmed_at
+		ELSE MIN(unconfirmed_at, ?) END,
+	updated_at = COALESCE(?, updated_at)
+WHERE profile_url = ?`,
+		string(r.Status), r.Reason, r.SentAt.UTC(),
+		nullTime(r.ConfirmedAt), nullTime(r.ConfirmedAt), nullTime(r.ConfirmedAt),
+		nullTime(r.UnconfirmedAt), nullTime(r.UnconfirmedAt), nullTime(r.UnconfirmedAt),
+		nullTime(r.UpdatedAt), key,
+	)
+	if err == nil {
+		counts.Updated++
+	}
+	return err
+}
+
+func importMessage(ctx context.Context, tx *sql.Tx, m MessageRecord, counts *ImportCounts) error {
+	key := profileKey(m.ProfileURL)
+	status := m.Status
+	if status == "" {
+		status = MessageSent
+	}
+	n, err := insertCount(tx.ExecContext(ctx, `
+INSERT INTO messages (profile_url, message_type, status, sent_at)
+SELECT ?, ?, ?, ?
+WHERE NOT EXISTS (SELECT 1 FROM messages WHERE profile_url = ? AND message_type = ? AND sent_at = ?)`,
+		key, m.MessageType, string(status), m.SentAt.UTC(), key, m.MessageType, m.SentAt.UTC(),
+	))
+	if err != nil {
+		return err
+	}
+	counts.count(n)
+	return nil
+}
+
+// insertCount returns how many rows an INSERT added.
+func insertCount(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// nullTime stores a zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
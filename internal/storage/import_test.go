@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := New("file:"+filepath.Join(t.TempDir(), "test.db"), testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestImportRequests(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	t0 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	profile := func(id string) string { return "https://www.linkedin.com/in/" + id + "/" }
+
+	// upgraded is stored as sent; kept is stored as accepted.
+	for _, id := range []string{"upgraded", "kept"} {
+		if err := s.SetRequestStatus(ctx, profile(id), RequestQueued, "", t0); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRequestStatus(ctx, profile(id), RequestSent, "", t0.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.MarkRequestAccepted(ctx, profile("kept"), t0.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.Import(ctx, Import{Requests: []RequestRecord{
+		{ProfileURL: profile("confirmed"), Status: RequestSent, SentAt: t0, ConfirmedAt: t0},
+		{ProfileURL: "https://de.linkedin.com/in/Unconfirmed", Status: RequestSent, SentAt: t0},
+		{ProfileURL: profile("unconfirmed-accepted"), Status: RequestAccepted, SentAt: t0},
+		{ProfileURL: profile("upgraded"), Status: RequestAccepted, SentAt: t0.Add(-time.Hour), ConfirmedAt: t0.Add(2 * time.Hour)},
+		{ProfileURL: profile("kept"), Status: RequestSent, SentAt: t0, ConfirmedAt: t0},
+	}})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if want := (ImportCounts{Added: 3, Updated: 1, Existing: 1}); res.Requests != want {
+		t.Errorf("request counts = %+v, want %+v", res.Requests, want)
+	}
+
+	recs, err := s.Requests(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]RequestRecord)
+	for _, r := range recs {
+		got[r.ProfileURL] = r
+	}
+	for id, want := range map[string]RequestStatus{
+		"confirmed":            RequestSent,
+		"unconfirmed":          RequestQueued,
+		"unconfirmed-accepted": RequestQueued,
+		"upgraded":             RequestAccepted,
+		"kept":                 RequestAccepted,
+	} {
+		if r := got[profile(id)]; r.Status != want {
+			t.Errorf("%s imported as %q (%s), want %q", id, r.Status, r.Reason, want)
+		}
+	}
+	if r := got[profile("unconfirmed")]; r.Reason != "imported as sent without a confirmation time" || !r.ConfirmedAt.IsZero() {
+		t.Errorf("unconfirmed request = %+v", r)
+	}
+	// The stored confirmation is earlier than the imported one and kept;
+	// the imported send is earlier and taken.
+	if r := got[profile("upgraded")]; !r.ConfirmedAt.Equal(t0.Add(time.Minute)) || !r.SentAt.Equal(t0.Add(-time.Hour)) {
+		t.Errorf("upgraded request = %+v, want the earliest send and confirmation", r)
+	}
+
+	// Only confirmed invitations count toward the limits.
+	n, err := s.CountRequestsSince(ctx, t0.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("CountRequestsSince = %d, want 3", n)
+	}
+}
+
+func TestImportMessagesAndCandidates(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	t0 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	const jane = "https://www.linkedin.com/in/jane-doe/"
+
+	if _, err := s.SaveCandidate(ctx, Candidate{ProfileURL: jane, Name: "Jane"}, t0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetCandidateStatus(ctx, jane, CandidateApproved, t0); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := Import{
+		Messages: []MessageRecord{
+			{ProfileURL: jane, MessageType: "followup", SentAt: t0},
+			{ProfileURL: "https://www.linkedin.com/in/Jane-Doe?trk=x", MessageType: "followup", SentAt: t0},
+			{ProfileURL: jane, MessageType: "followup", Status: MessageUnconfirmed, SentAt: t0.Add(time.Hour)},
+		},
+		Candidates: []Candidate{
+			{ProfileURL: jane, Name: "Jane D.", Status: CandidatePending, FoundAt: t0},
+			{ProfileURL: "https://www.linkedin.com/in/john/", Name: "John", Status: CandidatePending, FoundAt: t0},
+		},
+	}
+	res, err := s.Import(ctx, batch)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if want := (ImportCounts{Added: 2, Existing: 1}); res.Messages != want {
+		t.Errorf("message counts = %+v, want %+v", res.Messages, want)
+	}
+	if want := (ImportCounts{Added: 1, Existing: 1}); res.Candidates != want {
+		t.Errorf("candidate counts = %+v, want %+v", res.Candidates, want)
+	}
+
+	msgs, err := s.Messages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Status != MessageSent || msgs[1].Status != MessageUnconfirmed {
+		t.Errorf("messages = %+v, want one sent and one unconfirmed", msgs)
+	}
+	c, _, err := s.CandidateFor(ctx, jane)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != CandidateApproved || c.Name != "Jane" {
+		t.Errorf("stored candidate = %+v, want it left as it was", c)
+	}
+
+	// Importing the same batch again adds nothing.
+	res, err = s.Import(ctx, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Messages.Added != 0 || res.Candidates.Added != 0 {
+		t.Errorf("second import = %+v, want nothing added", res)
+	}
+}
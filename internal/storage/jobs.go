@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// JobKind is the action a job performs.
+type JobKind string
+
+const (
+	// JobSearchPage reads one page of results for the keyword in Target.
+	JobSearchPage JobKind = "search_page"
+	// JobConnect invites the approved candidate in Target.
+	JobConnect JobKind = "connect"
+	// JobSyncConnections records who accepted and plans the follow-ups.
+	JobSyncConnections JobKind = "sync_connections"
+	// JobScanOptOuts adds people who replied asking not to be contacted to
+	// the do-not-contact list.
+	JobScanOptOuts JobKind = "scan_opt_outs"
+	// JobFollowUp messages the accepted connection in Target.
+	JobFollowUp JobKind = "followup"
+)
+
+// JobStatus is where a job stands.
+type JobStatus string
+
+const (
+	// JobPending jobs are run in seq order once NotBefore has passed.
+	JobPending JobStatus = "pending"
+	// JobRunning is written before a job starts, so one that is still
+	// running when a plan resumes was cut short by a crash.
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	// JobFailed jobs used up their attempts and are not retried.
+	JobFailed JobStatus = "failed"
+	// JobSkipped jobs were not needed, e.g. because a limit was reached.
+	JobSkipped JobStatus = "skipped"
+)
+
+// Job is one planned action of a run.
+type Job struct {
+	ID     int64
+	PlanID int64
+	Seq    int
+	Kind   JobKind
+	Target string
+	// Page is the results page of a JobSearchPage, counting from 1.
+	Page      int
+	Status    JobStatus
+	Attempts  int
+	LastError string
+	NotBefore time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UnfinishedPlan returns the newest plan that still has pending or running
+// jobs; found is false when every plan is finished.
+func (s *Storage) UnfinishedPlan(ctx context.Context) (planID int64, found bool, err error) {
+	err = s.db.QueryRowContext(ctx,
+		`SELECT plan_id FROM jobs WHERE status IN (?, ?) ORDER BY plan_id DESC LIMIT 1`,
+		string(JobPending), string(JobRunning),
+	).Scan(&planID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return planID, err == nil, err
+}
+
+// LatestPlan returns the newest plan; found is false when there is none.
+func (s *Storage) LatestPlan(ctx context.Context) (planID int64, found bool, err error) {
+	var id sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(plan_id) FROM jobs`).Scan(&id); err != nil {
+		return 0, false, err
+	}
+	return id.Int64, id.Valid, nil
+}
+
+// CreatePlan stores jobs, in order, as a new plan of pending jobs and
+// returns its id.
+func (s *Storage) CreatePlan(ctx context.Context, jobs []Job, now time.Time) (planID int64, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(plan_id), 0) + 1 FROM jobs`).Scan(&planID); err != nil {
+		return 0, err
+	}
+	if err := insertJobs(ctx, tx, planID, 1, jobs, now); err != nil {
+		return 0, err
+	}
+	return planID, tx.Commit()
+}
+
+// AppendJobs adds jobs, in order, after the last job of a plan.
+func (s *Storage) AppendJobs(ctx context.Context, planID int64, jobs []Job, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var next int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) + 1 FROM jobs WHERE plan_id = ?`, planID).Scan(&next); err != nil {
+		return err
+	}
+	if err := insertJobs(ctx, tx, planID, next, jobs, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertJobs(ctx context.Context, tx *sql.Tx, planID int64, seq int, jobs []Job, now time.Time) error {
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO jobs (plan_id, seq, kind, target, page, status, not_before, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, j := range jobs {
+		if _, err := stmt.ExecContext(ctx, planID, seq+i, string(j.Kind), j.Target, j.Page, string(JobPending), now.UTC(), now.UTC(), now.UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Jobs returns the jobs of a plan in seq order.
+func (s *Storage) Jobs(ctx context.Context, planID int64) ([]Job, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, plan_id, seq, kind, target, page, status, attempts, last_error, not_before, created_at, updated_at
+FROM jobs WHERE plan_id = ? ORDER BY seq`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Job
+	for rows.Next() {
+		var j Job
+		var kind, status string
+		if err := rows.Scan(&j.ID, &j.PlanID, &j.Seq, &kind, &j.Target, &j.Page, &status, &j.Attempts, &j.LastError, &j.NotBefore, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, err
+		}
+		j.Kind, j.Status = JobKind(kind), JobStatus(status)
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// ResetRunningJobs puts a plan's running jobs back to pending and returns
+// how many there were. Their attempt still counts.
+func (s *Storage) ResetRunningJobs(ctx context.Context, planID int64, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, updated_at = ? WHERE plan_id = ? AND status = ?`,
+		string(JobPending), now.UTC(), planID, string(JobRunning),
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// StartJob marks a job running and counts the attempt.
+func (s *Storage) StartJob(ctx context.Context, id int64, now time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = ? WHERE id = ?`,
+		string(JobRunning), now.UTC(), id,
+	)
+	return err
+}
+
+// FinishJob records a job's new status and error message. A pending job is
+// not run again before notBefore.
+func (s *Storage) FinishJob(ctx context.Context, id int64, status JobStatus, lastError string, notBefore, now time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, last_error = ?, not_before = ?, updated_at = ? WHERE id = ?`,
+		string(status), lastError, notBefore.UTC(), now.UTC(), id,
+	)
+	return err
+}
+
+// ReturnJob puts a job that was stopped through no fault of its own, e.g.
+// by a checkpoint, back to pending and gives back its attempt.
+func (s *Storage) ReturnJob(ctx context.Context, id int64, reason string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, attempts = MAX(attempts - 1, 0), last_error = ?, updated_at = ? WHERE id = ?`,
+		string(JobPending), reason, now.UTC(), id,
+	)
+	return err
+}
+
+// SkipJobs marks a plan's pending jobs of kind as skipped, only those for
+// target unless it is empty, and returns how many there were.
+func (s *Storage) SkipJobs(ctx context.Context, planID int64, kind JobKind, target, reason string, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, last_error = ?, updated_at = ?
+		 WHERE plan_id = ? AND kind = ? AND status = ? AND (? = '' OR target = ?)`,
+		string(JobSkipped), reason, now.UTC(), planID, string(kind), string(JobPending), target, target,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SQL migrations live in migrations/NNNN_name.sql and are embedded in the
+// binary, so upgrading is just running a newer build against the old file.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migration is one ordered schema step. Exactly one of sql and up is set:
+// most steps are plain SQL files, a few need Go logic.
+type migration struct {
+	Version int
+	Name    string
+	sql     string
+	up      func(ctx context.Context, tx *sql.Tx, log *logrus.Logger) error
+}
+
+// goMigrations are steps that cannot be expressed in SQL. Their versions
+// interleave with the SQL files.
+var goMigrations = []migration{
+	{Version: 2, Name: "request_states", up: migrateRequestStates},
+	{Version: 4, Name: "canonical_profile_urls", up: migrateCanonicalProfileURLs},
+	{Version: 14, Name: "last_run_history", up: migrateLastRun},
+}
+
+// MigrationState describes one known migration and whether it has been
+// applied to the open database.
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// loadMigrations returns every migration ordered by version.
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	all := append([]migration(nil), goMigrations...)
+	for _, f := range files {
+		base := strings.TrimSuffix(path.Base(f), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration file %s: want NNNN_name.sql", f)
+		}
+		body, err := migrationFS.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, migration{Version: version, Name: name, sql: string(body)})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i := 1; i < len(all); i++ {
+		if all[i].Version == all[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", all[i].Version, all[i-1].Name, all[i].Name)
+		}
+	}
+	return all, nil
+}
+
+func (s *Storage) ensureMigrationsTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`)
+	return err
+}
+
+func (s *Storage) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus lists every known migration and whether it has been
+// applied. It does not change the database beyond creating the
+// schema_migrations bookkeeping table.
+func (s *Storage) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	all, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationState, 0, len(all))
+	for _, m := range all {
+		at, ok := applied[m.Version]
+		out = append(out, MigrationState{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// Migrate applies every pending migration in version order. Each migration
+// runs in its own transaction together with its schema_migrations row, so a
+// failure leaves the database at the last fully applied version.
+func (s *Storage) Migrate(ctx context.Context) error {
+	all, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range all {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		done, err := s.applyMigration(ctx, m)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if !done {
+			continue
+		}
+		s.log.WithField("version", m.Version).WithField("name", m.Name).Info("applied schema migration")
+	}
+	return nil
+}
+
+// applyMigration applies m unless another process applied it since Migrate
+// looked; done reports whether this call applied it.
+func (s *Storage) applyMigration(ctx context.Context, m migration) (done bool, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Transactions take the write lock up front (see tuneDSN), so this
+	// check holds until the commit.
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.Version).Scan(&n); err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+
+	if m.up != nil {
+		err = m.up(ctx, tx, s.log)
+	} else {
+		_, err = tx.ExecContext(ctx, m.sql)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC(),
+	); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// requestStateColumns are the sent_requests columns added by version 2.
+var requestStateColumns = []struct{ name, decl string }{
+	{"status", "TEXT NOT NULL DEFAULT 'queued'"},
+	{"reason", "TEXT NOT NULL DEFAULT ''"},
+	{"confirmed_at", "TIMESTAMP"},
+	{"updated_at", "TIMESTAMP"},
+}
+
+// migrateRequestStates lets connection requests move through explicit
+// states; only confirmed sends (confirmed_at IS NOT NULL) count toward
+// limits. Builds from before versioned migrations already added some of the
+// columns on startup, so each is only added when missing.
+func migrateRequestStates(ctx context.Context, tx *sql.Tx, log *logrus.Logger) error {
+	have, err := tableColumns(ctx, tx, "sent_requests")
+	if err != nil {
+		return err
+	}
+	for _, c := range requestStateColumns {
+		if have[c.name] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE sent_requests ADD COLUMN `+c.name+` `+c.decl); err != nil {
+			return err
+		}
+	}
+
+	// Rows written before delivery verification existed were recorded
+	// whether or not the invitation went out; leave them queued so the next
+	// visit establishes their real state.
+	_, err = tx.ExecContext(ctx, `
+UPDATE sent_requests
+SET reason = 'recorded before delivery verification', updated_at = sent_at
+WHERE updated_at IS NULL`)
+	return err
+}
+
+// tableColumns returns the names of table's columns.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
+
+// migrateCanonicalProfileURLs merges rows written before profile URLs were
+// canonicalized.
+func migrateCanonicalProfileURLs(ctx context.Context, tx *sql.Tx, log *logrus.Logger) error {
+	rep, err := mergeDuplicateProfiles(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if rep.Changed() {
+		log.WithField("requests_merged", rep.RequestsMerged).
+			WithField("connections_merged", rep.ConnectionsMerged).
+			WithField("rewritten", rep.Rewritten).
+			Info("canonicalized stored profile URLs")
+	}
+	return nil
+}
+
+// migrateLastRun moves the last run, which app_state kept for "app status"
+// before the run history replaced it, into runs and drops the app_state
+// copy. A run the history already finished is left as it is.
+func migrateLastRun(ctx context.Context, tx *sql.Tx, log *logrus.Logger) error {
+	var raw string
+	err := tx.QueryRowContext(ctx, `SELECT value FROM app_state WHERE key = 'last_run'`).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var run struct {
+		RunID      string    `json:"run_id"`
+		Command    string    `json:"command"`
+		StartedAt  time.Time `json:"started_at"`
+		FinishedAt time.Time `json:"finished_at"`
+		ExitCode   int       `json:"exit_code"`
+		Outcome    string    `json:"outcome"`
+		Error      string    `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(raw), &run); err != nil {
+		log.WithError(err).Warn("dropping unreadable last run record")
+	} else {
+		// Runs from before the history have no id of their own.
+		id := run.RunID
+		if id == "" {
+			id = "legacy"
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO runs (id, command, config_hash, started_at, finished_at, exit_code, outcome, error)
+VALUES (?, ?, '', ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	finished_at = excluded.finished_at,
+	exit_code = excluded.exit_code,
+	outcome = excluded.outcome,
+	error = excluded.error
+WHERE runs.finished_at IS NULL`,
+			id, run.Command, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.ExitCode, run.Outcome, run.Error,
+		); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM app_state WHERE key = 'last_run'`)
+	return err
+}
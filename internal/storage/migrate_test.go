@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// legacySchema creates tables the way builds from before versioned
+// migrations did on startup.
+func legacySchema(t *testing.T, dsn string, withStates bool) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stmts := []string{
+		`CREATE TABLE sent_requests (id INTEGER PRIMARY KEY AUTOINCREMENT, profile_url TEXT NOT NULL UNIQUE, sent_at TIMESTAMP NOT NULL)`,
+		`CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, profile_url TEXT NOT NULL, message_type TEXT NOT NULL, sent_at TIMESTAMP NOT NULL)`,
+		`INSERT INTO sent_requests (profile_url, sent_at) VALUES ('https://www.linkedin.com/in/jane-doe/', '2026-01-02 03:04:05+00:00')`,
+	}
+	if withStates {
+		stmts = append(stmts,
+			`ALTER TABLE sent_requests ADD COLUMN status TEXT NOT NULL DEFAULT 'queued'`,
+			`ALTER TABLE sent_requests ADD COLUMN reason TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE sent_requests ADD COLUMN confirmed_at TIMESTAMP`,
+			`ALTER TABLE sent_requests ADD COLUMN updated_at TIMESTAMP`,
+		)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestMigrateLegacyDatabases(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		legacy     bool
+		withStates bool
+	}{
+		{name: "new database"},
+		{name: "before request states", legacy: true},
+		{name: "with request states added on startup", legacy: true, withStates: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dsn := "file:" + filepath.Join(t.TempDir(), "test.db")
+			if tt.legacy {
+				legacySchema(t, dsn, tt.withStates)
+			}
+			s, err := New(dsn, testLogger())
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer s.Close()
+
+			states, err := s.MigrationStatus(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range states {
+				if !m.Applied {
+					t.Errorf("migration %04d_%s not applied", m.Version, m.Name)
+				}
+			}
+			if !tt.legacy {
+				return
+			}
+			recs, err := s.Requests(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recs) != 1 {
+				t.Fatalf("got %d requests, want 1", len(recs))
+			}
+			r := recs[0]
+			if r.Status != RequestQueued || r.Reason != "recorded before delivery verification" || !r.UpdatedAt.Equal(r.SentAt) {
+				t.Errorf("legacy request = %+v, want it queued as recorded before delivery verification", r)
+			}
+		})
+	}
+}
+
+func TestMigrateLastRun(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db")
+	s, err := New(dsn, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Roll the database back to before version 14, with a last run kept in
+	// app_state and no run history.
+	for _, stmt := range []string{
+		`DELETE FROM schema_migrations WHERE version = 14`,
+		`INSERT INTO app_state (key, value, updated_at) VALUES ('last_run',
+			'{"command":"connect","started_at":"2026-01-02T03:04:05Z","finished_at":"2026-01-02T03:14:05Z","exit_code":3,"outcome":"limit_reached","error":"connect daily limit of 10 reached"}',
+			'2026-01-02 03:14:05+00:00')`,
+	} {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	s.Close()
+
+	s, err = New(dsn, testLogger())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	run, found, err := s.LastRun(ctx)
+	if err != nil || !found {
+		t.Fatalf("LastRun: found = %v, err = %v", found, err)
+	}
+	if run.ID != "legacy" || run.Command != "connect" || run.ExitCode != 3 || run.Outcome != "limit_reached" ||
+		!run.FinishedAt.Equal(time.Date(2026, 1, 2, 3, 14, 5, 0, time.UTC)) {
+		t.Errorf("migrated last run = %+v", run)
+	}
+	if found, err := s.getState(ctx, "last_run", new(json.RawMessage)); err != nil || found {
+		t.Errorf("app_state last_run still there: found = %v, err = %v", found, err)
+	}
+}
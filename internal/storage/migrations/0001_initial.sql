@@ -0,0 +1,14 @@
+-- Tables from the first release. IF NOT EXISTS lets databases created
+-- before migrations existed adopt this version in place.
+CREATE TABLE IF NOT EXISTS sent_requests (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	profile_url TEXT NOT NULL UNIQUE,
+	sent_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	profile_url TEXT NOT NULL,
+	message_type TEXT NOT NULL,
+	sent_at TIMESTAMP NOT NULL
+);
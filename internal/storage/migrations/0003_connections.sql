@@ -0,0 +1,7 @@
+-- First-degree connections read from the user's connections list.
+CREATE TABLE IF NOT EXISTS connections (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	profile_url TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	first_seen_at TIMESTAMP NOT NULL
+);
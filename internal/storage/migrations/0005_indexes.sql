@@ -0,0 +1,5 @@
+-- Indexes for the limit and history lookups run before every action.
+CREATE INDEX IF NOT EXISTS idx_sent_requests_confirmed_at ON sent_requests (confirmed_at);
+CREATE INDEX IF NOT EXISTS idx_sent_requests_status ON sent_requests (status);
+CREATE INDEX IF NOT EXISTS idx_messages_profile_type ON messages (profile_url, message_type, sent_at);
+CREATE INDEX IF NOT EXISTS idx_messages_type_sent_at ON messages (message_type, sent_at);
@@ -0,0 +1,11 @@
+-- People and companies who asked never to be contacted. value is a
+-- canonical profile URL for kind 'profile' and a normalized (lower-cased,
+-- single-spaced) company name for kind 'company'.
+CREATE TABLE do_not_contact (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL CHECK (kind IN ('profile', 'company')),
+	value TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	added_at TIMESTAMP NOT NULL,
+	UNIQUE (kind, value)
+);
@@ -0,0 +1,17 @@
+-- People found by search, waiting for a human to approve or reject them
+-- before any invitation is sent.
+CREATE TABLE candidates (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	profile_url TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	headline TEXT NOT NULL DEFAULT '',
+	company TEXT NOT NULL DEFAULT '',
+	location TEXT NOT NULL DEFAULT '',
+	degree TEXT NOT NULL DEFAULT '',
+	keyword TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+	found_at TIMESTAMP NOT NULL,
+	reviewed_at TIMESTAMP
+);
+
+CREATE INDEX idx_candidates_status ON candidates (status, found_at);
@@ -0,0 +1,7 @@
+-- Small key/value store for tool-wide state that is not tied to a profile,
+-- such as the outcome of the last run or an active cooldown.
+CREATE TABLE app_state (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
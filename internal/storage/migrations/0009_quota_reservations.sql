@@ -0,0 +1,10 @@
+-- Quota slots taken just before an action is attempted. A slot is removed
+-- once the action is recorded in sent_requests or messages, or when it did
+-- not happen; one left behind by a crash keeps counting toward the limits.
+CREATE TABLE quota_reservations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL,
+	reserved_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_quota_reservations_action ON quota_reservations (action, reserved_at);
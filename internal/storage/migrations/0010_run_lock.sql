@@ -0,0 +1,12 @@
+-- The run lock: at most one row, held by the process currently driving the
+-- browser. heartbeat_at is refreshed while it runs, so a lock left behind by
+-- a crashed process can be recognised as stale.
+CREATE TABLE run_lock (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	token TEXT NOT NULL,
+	pid INTEGER NOT NULL,
+	host TEXT NOT NULL,
+	command TEXT NOT NULL,
+	acquired_at TIMESTAMP NOT NULL,
+	heartbeat_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,20 @@
+-- The planned actions of "app run", worked through in seq order within a
+-- plan, so a run that is interrupted can resume where it stopped. Failed
+-- jobs wait until not_before before they are retried.
+CREATE TABLE jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	plan_id INTEGER NOT NULL,
+	seq INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	page INTEGER NOT NULL DEFAULT 0,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	not_before TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	UNIQUE (plan_id, seq)
+);
+
+CREATE INDEX idx_jobs_plan_status ON jobs (plan_id, status);
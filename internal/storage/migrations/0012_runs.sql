@@ -0,0 +1,30 @@
+-- One row per browser command, so every invitation and message can be
+-- traced to the run that sent it. finished_at stays NULL for a run that
+-- crashed before it could record how it ended.
+CREATE TABLE runs (
+	id TEXT PRIMARY KEY,
+	command TEXT NOT NULL,
+	args TEXT NOT NULL DEFAULT '',
+	config_hash TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP,
+	exit_code INTEGER,
+	outcome TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	found INTEGER NOT NULL DEFAULT 0,
+	invited INTEGER NOT NULL DEFAULT 0,
+	invite_failed INTEGER NOT NULL DEFAULT 0,
+	messaged INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_runs_started ON runs (started_at);
+
+-- Rows written before runs were recorded, or outside a run (e.g. by
+-- import), keep a NULL run_id.
+ALTER TABLE sent_requests ADD COLUMN run_id TEXT;
+ALTER TABLE messages ADD COLUMN run_id TEXT;
+ALTER TABLE candidates ADD COLUMN run_id TEXT;
+
+CREATE INDEX idx_sent_requests_run ON sent_requests (run_id);
+CREATE INDEX idx_messages_run ON messages (run_id);
+CREATE INDEX idx_candidates_run ON candidates (run_id);
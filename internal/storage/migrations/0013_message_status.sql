@@ -0,0 +1,4 @@
+-- Messages whose delivery could not be confirmed in the conversation thread
+-- are recorded as unconfirmed, so they still block a repeat message and
+-- count toward the limits. Earlier rows were all confirmed.
+ALTER TABLE messages ADD COLUMN status TEXT NOT NULL DEFAULT 'sent';
@@ -0,0 +1,4 @@
+-- Invitations whose Send was clicked but never showed "Pending" are recorded
+-- as unconfirmed. They may have gone out, so unconfirmed_at counts them
+-- toward the limits like confirmed_at does for sent ones.
+ALTER TABLE sent_requests ADD COLUMN unconfirmed_at TIMESTAMP;
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// QuotaConnect is the quota action for invitations; every other action is a
+// message type.
+const QuotaConnect = "connect"
+
+// QuotaTimes returns when action was taken at or after since, oldest first.
+// For QuotaConnect these are confirmed and unconfirmed invitations, otherwise
+// messages of that type; outstanding reservations are included either way.
+func (s *Storage) QuotaTimes(ctx context.Context, action string, since time.Time) ([]time.Time, error) {
+	return quotaTimes(ctx, s.db, action, since)
+}
+
+// queryer is a *sql.DB or *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func quotaTimes(ctx context.Context, q queryer, action string, since time.Time) ([]time.Time, error) {
+	query, args := `SELECT sent_at FROM messages WHERE message_type = ? AND sent_at >= ?`, []any{action, since.UTC()}
+	if action == QuotaConnect {
+		query, args = `SELECT confirmed_at FROM sent_requests WHERE confirmed_at IS NOT NULL AND confirmed_at >= ?`, []any{since.UTC()}
+	}
+	times, err := scanTimes(ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if action == QuotaConnect {
+		// An unconfirmed invitation may have gone out, so it counts from
+		// the click.
+		unconfirmed, err := scanTimes(ctx, q,
+			`SELECT unconfirmed_at FROM sent_requests WHERE unconfirmed_at IS NOT NULL AND unconfirmed_at >= ?`, since.UTC())
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, unconfirmed...)
+	}
+	reserved, err := scanTimes(ctx, q, `SELECT reserved_at FROM quota_reservations WHERE action = ? AND reserved_at >= ?`, action, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	times = append(times, reserved...)
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times, nil
+}
+
+func scanTimes(ctx context.Context, q queryer, query string, args ...any) ([]time.Time, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// ReserveQuota passes the QuotaTimes of action since since to allow and, if
+// it returns nil, stores a reservation at when and returns its id. Both
+// happen in one transaction holding the write lock, so two processes cannot
+// both take the last slot. An error from allow is returned as is.
+func (s *Storage) ReserveQuota(ctx context.Context, action string, since, when time.Time, allow func(times []time.Time) error) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	times, err := quotaTimes(ctx, tx, action, since)
+	if err != nil {
+		return 0, err
+	}
+	if err := allow(times); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO quota_reservations (action, reserved_at) VALUES (?, ?)`,
+		action, when.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ReleaseQuota removes a reservation.
+func (s *Storage) ReleaseQuota(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM quota_reservations WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReserveQuotaLastSlot(t *testing.T) {
+	ctx := context.Background()
+	// Two Storage values on one file stand in for two overlapping runs.
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db")
+	var stores [2]*Storage
+	for i := range stores {
+		s, err := New(dsn, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		stores[i] = s
+	}
+
+	now := time.Now()
+	full := errors.New("limit reached")
+	// One slot is left of a daily limit of 3.
+	for i := 0; i < 2; i++ {
+		if _, err := stores[0].ReserveQuota(ctx, QuotaConnect, now.Add(-time.Hour), now, func([]time.Time) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	allow := func(times []time.Time) error {
+		if len(times) >= 3 {
+			return full
+		}
+		return nil
+	}
+
+	const tries = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, tries)
+	for i := 0; i < tries; i++ {
+		wg.Add(1)
+		go func(s *Storage) {
+			defer wg.Done()
+			_, err := s.ReserveQuota(ctx, QuotaConnect, now.Add(-time.Hour), now, allow)
+			errs <- err
+		}(stores[i%2])
+	}
+	wg.Wait()
+	close(errs)
+
+	granted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			granted++
+		case !errors.Is(err, full):
+			t.Errorf("ReserveQuota: %v", err)
+		}
+	}
+	if granted != 1 {
+		t.Errorf("%d runs got the last slot, want 1", granted)
+	}
+	if times, err := stores[1].QuotaTimes(ctx, QuotaConnect, now.Add(-time.Hour)); err != nil || len(times) != 3 {
+		t.Errorf("QuotaTimes = %d times, %v; want 3", len(times), err)
+	}
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RequestRecord is one row of sent_requests.
+type RequestRecord struct {
+	ProfileURL string
+	Status     RequestStatus
+	Reason     string
+	SentAt     time.Time
+	// ConfirmedAt, UnconfirmedAt and UpdatedAt are zero when not set.
+	ConfirmedAt   time.Time
+	UnconfirmedAt time.Time
+	UpdatedAt     time.Time
+}
+
+// MessageRecord is one row of messages.
+type MessageRecord struct {
+	ProfileURL  string
+	MessageType string
+	Status      MessageStatus
+	SentAt      time.Time
+}
+
+// ConnectionRecord is one row of connections.
+type ConnectionRecord struct {
+	ProfileURL  string
+	Name        string
+	FirstSeenAt time.Time
+}
+
+// Requests returns every connection request, oldest first.
+func (s *Storage) Requests(ctx context.Context) ([]RequestRecord, error) {
+	return s.requests(ctx, `ORDER BY sent_at, id`)
+}
+
+// requests returns the connection requests selected by the WHERE and ORDER
+// BY clauses in tail.
+func (s *Storage) requests(ctx context.Context, tail string, args ...any) ([]RequestRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT profile_url, status, reason, sent_at, confirmed_at, unconfirmed_at, updated_at FROM sent_requests `+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RequestRecord
+	for rows.Next() {
+		var r RequestRecord
+		var confirmedAt, unconfirmedAt, updatedAt sql.NullTime
+		if err := rows.Scan(&r.ProfileURL, &r.Status, &r.Reason, &r.SentAt, &confirmedAt, &unconfirmedAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		r.ConfirmedAt, r.UnconfirmedAt, r.UpdatedAt = confirmedAt.Time, unconfirmedAt.Time, updatedAt.Time
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// Messages returns every recorded message, oldest first.
+func (s *Storage) Messages(ctx context.Context) ([]MessageRecord, error) {
+	return s.messages(ctx, `ORDER BY sent_at, id`)
+}
+
+// messages returns the messages selected by the WHERE and ORDER BY clauses
+// in tail.
+func (s *Storage) messages(ctx context.Context, tail string, args ...any) ([]MessageRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT profile_url, message_type, status, sent_at FROM messages `+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MessageRecord
+	for rows.Next() {
+		var m MessageRecord
+		if err := rows.Scan(&m.ProfileURL, &m.MessageType, &m.Status, &m.SentAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// Connections returns every synced connection in the order they were first
+// seen.
+func (s *Storage) Connections(ctx context.Context) ([]ConnectionRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT profile_url, name, first_seen_at FROM connections ORDER BY first_seen_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ConnectionRecord
+	for rows.Next() {
+		var c ConnectionRecord
+		if err := rows.Scan(&c.ProfileURL, &c.Name, &c.FirstSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// CountRequestsByStatus returns how many connection requests are in each
+// state.
+func (s *Storage) CountRequestsByStatus(ctx context.Context) (map[RequestStatus]int, error) {
+	return countBy(ctx, s.db, `SELECT status, COUNT(*) FROM sent_requests GROUP BY status`, func(k string) RequestStatus { return RequestStatus(k) })
+}
+
+// CountCandidatesByStatus returns how many candidates are in each review
+// state.
+func (s *Storage) CountCandidatesByStatus(ctx context.Context) (map[CandidateStatus]int, error) {
+	return countBy(ctx, s.db, `SELECT status, COUNT(*) FROM candidates GROUP BY status`, func(k string) CandidateStatus { return CandidateStatus(k) })
+}
+
+func countBy[K comparable](ctx context.Context, db *sql.DB, query string, key func(string) K) (map[K]int, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[K]int)
+	for rows.Next() {
+		var k string
+		var n int
+		if err := rows.Scan(&k, &n); err != nil {
+			return nil, err
+		}
+		out[key(k)] = n
+	}
+	return out, rows.Err()
+}
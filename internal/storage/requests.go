@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RequestStatus is the state of a connection request in sent_requests.
+type RequestStatus string
+
+const (
+	// RequestQueued is written before the tool touches the profile, so a
+	// crash mid-attempt still leaves a trace.
+	RequestQueued RequestStatus = "queued"
+	// RequestSent means the page confirmed the invitation (the Connect
+	// button turned into "Pending"). Only sent requests count toward limits.
+	RequestSent RequestStatus = "sent"
+	// RequestSkippedNoButton means the profile offered no Connect button.
+	RequestSkippedNoButton RequestStatus = "skipped_no_button"
+	// RequestBlockedEmailRequired means LinkedIn asked for the member's
+	// email address before it would send the invitation.
+	RequestBlockedEmailRequired RequestStatus = "blocked_email_required"
+	// RequestFailed means the attempt errored before Send was clicked, or
+	// LinkedIn refused the invitation.
+	RequestFailed RequestStatus = "failed"
+	// RequestUnconfirmed means Send was clicked but the page never showed
+	// "Pending". The invitation may have gone out, so it counts toward the
+	// limits and is not attempted again.
+	RequestUnconfirmed RequestStatus = "unconfirmed"
+	// RequestPending means an invitation was already outstanding on the
+	// profile when it was visited.
+	RequestPending   RequestStatus = "pending"
+	RequestAccepted  RequestStatus = "accepted"
+	RequestWithdrawn RequestStatus = "withdrawn"
+)
+
+// requestTransitions lists the allowed next states for each state. The empty
+// status stands for "no row yet".
+var requestTransitions = map[RequestStatus][]RequestStatus{
+	"":                          {RequestQueued, RequestPending, RequestAccepted},
+	RequestQueued:               {RequestSent, RequestUnconfirmed, RequestSkippedNoButton, RequestBlockedEmailRequired, RequestFailed, RequestPending, RequestAccepted},
+	RequestFailed:               {RequestQueued, RequestPending, RequestAccepted},
+	RequestSkippedNoButton:      {RequestQueued, RequestPending, RequestAccepted},
+	RequestSent:                 {RequestPending, RequestAccepted, RequestWithdrawn},
+	RequestUnconfirmed:          {RequestPending, RequestAccepted, RequestWithdrawn},
+	RequestPending:              {RequestAccepted, RequestWithdrawn},
+	RequestWithdrawn:            {RequestQueued, RequestAccepted},
+	RequestBlockedEmailRequired: {RequestAccepted},
+	RequestAccepted:             nil,
+}
+
+// CanTransition reports whether a request may move from one state to
+// another. Re-writing the same state is always allowed.
+func CanTransition(from, to RequestStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range requestTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Settled reports whether a request in this state should not be attempted
+// again: an invitation exists, existed, or cannot be sent by this tool.
+func (st RequestStatus) Settled() bool {
+	switch st {
+	case RequestSent, RequestUnconfirmed, RequestPending, RequestAccepted, RequestWithdrawn, RequestBlockedEmailRequired:
+		return true
+	}
+	return false
+}
+
+// RequestStatusFor returns the recorded state of the request for a profile;
+// found is false when the profile has never been attempted.
+func (s *Storage) RequestStatusFor(ctx context.Context, profileURL string) (status RequestStatus, found bool, err error) {
+	profileURL = profileKey(profileURL)
+	row := s.db.QueryRowContext(ctx, `SELECT status FROM sent_requests WHERE profile_url = ?`, profileURL)
+	err = row.Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return status, true, nil
+}
+
+// HasSentRequest returns true if an invitation is known to exist (or to
+// have existed) for the given profile URL.
+func (s *Storage) HasSentRequest(ctx context.Context, profileURL string) (bool, error) {
+	status, found, err := s.RequestStatusFor(ctx, profileURL)
+	if err != nil || !found {
+		return false, err
+	}
+	return status.Settled(), nil
+}
+
+// SetRequestStatus moves a profile's request to a new state, creating the
+// row on first use. Invalid transitions are rejected so, for example, an
+// accepted connection cannot be downgraded to failed by a later run. The
+// row keeps the id of the run (see WithRunID) that created it or last queued
+// a new attempt, so later syncs do not take the invitation over.
+func (s *Storage) SetRequestStatus(ctx context.Context, profileURL string, status RequestStatus, reason string, when time.Time) error {
+	profileURL = profileKey(profileURL)
+	current, _, err := s.RequestStatusFor(ctx, profileURL)
+	if err != nil {
+		return err
+	}
+	if !CanTransition(current, status) {
+		return fmt.Errorf("invalid request transition %q -> %q for %s", current, status, profileURL)
+	}
+
+	var confirmedAt, unconfirmedAt any
+	switch status {
+	case RequestSent:
+		confirmedAt = when.UTC()
+	case RequestUnconfirmed:
+		unconfirmedAt = when.UTC()
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO sent_requests (profile_url, sent_at, status, reason, confirmed_at, unconfirmed_at, updated_at, run_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(profile_url) DO UPDATE SET
+	status = excluded.status,
+	reason = excluded.reason,
+	confirmed_at = COALESCE(excluded.confirmed_at, sent_requests.confirmed_at),
+	unconfirmed_at = COALESCE(excluded.unconfirmed_at, sent_requests.unconfirmed_at),
+	updated_at = excluded.updated_at,
+	run_id = CASE WHEN excluded.status = ? THEN excluded.run_id ELSE sent_requests.run_id END`,
+		profileURL, when.UTC(), string(status), reason, confirmedAt, unconfirmedAt, when.UTC(), nullRunID(ctx), string(RequestQueued),
+	)
+	return err
+}
+
+// CountRequestsSince returns how many invitations were confirmed as sent
+// since the given time. Used to enforce simple daily limits.
+func (s *Storage) CountRequestsSince(ctx context.Context, since time.Time) (int, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sent_requests WHERE confirmed_at IS NOT NULL AND confirmed_at >= ?`,
+		since.UTC(),
+	)
+	var n int
+	if err := row.Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to RequestStatus
+		want     bool
+	}{
+		{"", RequestQueued, true},
+		{"", RequestPending, true},
+		{"", RequestSent, false},
+		{RequestQueued, RequestSent, true},
+		{RequestQueued, RequestUnconfirmed, true},
+		{RequestQueued, RequestFailed, true},
+		{RequestQueued, RequestWithdrawn, false},
+		{RequestFailed, RequestQueued, true},
+		{RequestFailed, RequestSent, false},
+		{RequestSkippedNoButton, RequestQueued, true},
+		{RequestSent, RequestAccepted, true},
+		{RequestSent, RequestQueued, false},
+		{RequestSent, RequestFailed, false},
+		// An unconfirmed invitation is settled: a sync may find it pending
+		// or accepted, but it is never queued again.
+		{RequestUnconfirmed, RequestPending, true},
+		{RequestUnconfirmed, RequestAccepted, true},
+		{RequestUnconfirmed, RequestWithdrawn, true},
+		{RequestUnconfirmed, RequestQueued, false},
+		{RequestUnconfirmed, RequestFailed, false},
+		{RequestPending, RequestWithdrawn, true},
+		{RequestPending, RequestQueued, false},
+		{RequestWithdrawn, RequestQueued, true},
+		{RequestBlockedEmailRequired, RequestAccepted, true},
+		{RequestBlockedEmailRequired, RequestQueued, false},
+		{RequestAccepted, RequestWithdrawn, false},
+		{RequestAccepted, RequestFailed, false},
+		{RequestAccepted, RequestAccepted, true},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSettled(t *testing.T) {
+	for st := range requestStatusRank {
+		want := st == RequestSent || st == RequestUnconfirmed || st == RequestPending ||
+			st == RequestAccepted || st == RequestWithdrawn || st == RequestBlockedEmailRequired
+		if got := st.Settled(); got != want {
+			t.Errorf("%s.Settled() = %v, want %v", st, got, want)
+		}
+	}
+}
+
+func TestUnconfirmedRequestCounts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	for url, status := range map[string]RequestStatus{
+		"https://www.linkedin.com/in/sent/":        RequestSent,
+		"https://www.linkedin.com/in/unconfirmed/": RequestUnconfirmed,
+		"https://www.linkedin.com/in/failed/":      RequestFailed,
+	} {
+		if err := s.SetRequestStatus(ctx, url, RequestQueued, "", at); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRequestStatus(ctx, url, status, "", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	times, err := s.QuotaTimes(ctx, QuotaConnect, at.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 {
+		t.Errorf("QuotaTimes = %v, want the sent and the unconfirmed invitation", times)
+	}
+
+	// Accepting an unconfirmed invitation keeps its click counted.
+	if _, err := s.MarkRequestAccepted(ctx, "https://www.linkedin.com/in/unconfirmed/", at.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if times, err = s.QuotaTimes(ctx, QuotaConnect, at.Add(-time.Hour)); err != nil || len(times) != 2 || !times[1].Equal(at) {
+		t.Errorf("QuotaTimes after acceptance = %v, %v; want two at %s", times, err, at)
+	}
+	if sent, err := s.HasSentRequest(ctx, "https://www.linkedin.com/in/unconfirmed/"); err != nil || !sent {
+		t.Errorf("HasSentRequest = %v, %v; want true", sent, err)
+	}
+}
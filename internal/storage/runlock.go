@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunLockHolder describes the process holding the run lock.
+type RunLockHolder struct {
+	PID        int
+	Host       string
+	Command    string
+	AcquiredAt time.Time
+	// HeartbeatAt is when the holder last showed it was alive.
+	HeartbeatAt time.Time
+}
+
+func (h RunLockHolder) String() string {
+	return fmt.Sprintf("%s (pid %d on %s) since %s", h.Command, h.PID, h.Host, h.AcquiredAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// RunLockedError is returned when another live process holds the run lock.
+type RunLockedError struct {
+	Holder RunLockHolder
+}
+
+func (e *RunLockedError) Error() string {
+	return "another run is in progress: " + e.Holder.String()
+}
+
+// AcquireRunLock takes the run lock for token, on behalf of me. A lock whose
+// last heartbeat is before staleBefore is taken over and its holder returned
+// as stale; a live one is left alone and a *RunLockedError returned.
+func (s *Storage) AcquireRunLock(ctx context.Context, token string, me RunLockHolder, staleBefore time.Time) (stale *RunLockHolder, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	prev, found, err := runLockHolder(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if !prev.HeartbeatAt.Before(staleBefore) {
+			return nil, &RunLockedError{Holder: prev}
+		}
+		stale = &prev
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT OR REPLACE INTO run_lock (id, token, pid, host, command, acquired_at, heartbeat_at)
+VALUES (1, ?, ?, ?, ?, ?, ?)`,
+		token, me.PID, me.Host, me.Command, me.AcquiredAt.UTC(), me.HeartbeatAt.UTC(),
+	); err != nil {
+		return nil, err
+	}
+	return stale, tx.Commit()
+}
+
+// HeartbeatRunLock records that the holder of token is alive at now. held is
+// false when the lock is no longer token's, e.g. because another process
+// took it over as stale.
+func (s *Storage) HeartbeatRunLock(ctx context.Context, token string, now time.Time) (held bool, err error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE run_lock SET heartbeat_at = ? WHERE id = 1 AND token = ?`, now.UTC(), token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseRunLock gives up the run lock if token still holds it.
+func (s *Storage) ReleaseRunLock(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM run_lock WHERE id = 1 AND token = ?`, token)
+	return err
+}
+
+// RunLock returns the current holder of the run lock, stale or not; found
+// is false when nobody holds it.
+func (s *Storage) RunLock(ctx context.Context) (holder RunLockHolder, found bool, err error) {
+	return runLockHolder(ctx, s.db)
+}
+
+// rowQueryer is a *sql.DB or *sql.Tx.
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func runLockHolder(ctx context.Context, q rowQueryer) (h RunLockHolder, found bool, err error) {
+	err = q.QueryRowContext(ctx,
+		`SELECT pid, host, command, acquired_at, heartbeat_at FROM run_lock WHERE id = 1`,
+	).Scan(&h.PID, &h.Host, &h.Command, &h.AcquiredAt, &h.HeartbeatAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RunLockHolder{}, false, nil
+	}
+	if err != nil {
+		return RunLockHolder{}, false, err
+	}
+	return h, true, nil
+}
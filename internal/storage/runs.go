@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Run is one browser command as recorded in runs.
+type Run struct {
+	ID      string
+	Command string
+	// Args are the command's flags and arguments as given.
+	Args string
+	// ConfigHash identifies the effective config the run used.
+	ConfigHash string
+	StartedAt  time.Time
+	// FinishedAt is zero, and ExitCode -1, while the run is going or when it
+	// crashed before recording how it ended.
+	FinishedAt time.Time
+	ExitCode   int
+	// Outcome is a short word for the exit code: ok, error, usage,
+	// limit_reached, checkpoint, login_required, cooldown or interrupted.
+	// Older versions also recorded runs refused by the run lock as locked.
+	Outcome string
+	Error   string
+	// Found counts new candidates, Invited confirmed invitations,
+	// InviteFailed invitations that ended failed and Messaged messages.
+	Found        int
+	Invited      int
+	InviteFailed int
+	Messaged     int
+}
+
+// Finished reports whether the run recorded how it ended.
+func (r Run) Finished() bool {
+	return !r.FinishedAt.IsZero()
+}
+
+type runIDKey struct{}
+
+// WithRunID returns a context under which candidates, sent_requests and
+// messages rows are written with run id.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFrom returns the run id set by WithRunID, or "" outside a run.
+func RunIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// nullRunID is the run id of ctx as a column value, NULL outside a run.
+func nullRunID(ctx context.Context) any {
+	if id := RunIDFrom(ctx); id != "" {
+		return id
+	}
+	return nil
+}
+
+// StartRun records that run has started.
+func (s *Storage) StartRun(ctx context.Context, run Run) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO runs (id, command, args, config_hash, started_at) VALUES (?, ?, ?, ?, ?)`,
+		run.ID, run.Command, run.Args, run.ConfigHash, run.StartedAt.UTC(),
+	)
+	return err
+}
+
+// FinishRun records how run id ended and counts what it did from the
+// candidates, invitations and messages written with its id.
+func (s *Storage) FinishRun(ctx context.Context, id string, exitCode int, outcome, errMsg string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE runs SET
+	finished_at = ?,
+	exit_code = ?,
+	outcome = ?,
+	error = ?,
+	found = (SELECT COUNT(*) FROM candidates WHERE run_id = runs.id),
+	invited = (SELECT COUNT(*) FROM sent_requests WHERE run_id = runs.id AND confirmed_at >= runs.started_at),
+	invite_failed = (SELECT COUNT(*) FROM sent_requests WHERE run_id = runs.id AND status = ?),
+	messaged = (SELECT COUNT(*) FROM messages WHERE run_id = runs.id)
+WHERE id = ?`,
+		now.UTC(), exitCode, outcome, errMsg, string(RequestFailed), id,
+	)
+	return err
+}
+
+const runColumns = `id, command, args, config_hash, started_at, finished_at, exit_code, outcome, error, found, invited, invite_failed, messaged`
+
+func scanRun(row interface{ Scan(...any) error }) (Run, error) {
+	var r Run
+	var finishedAt sql.NullTime
+	var exitCode sql.NullInt64
+	err := row.Scan(&r.ID, &r.Command, &r.Args, &r.ConfigHash, &r.StartedAt, &finishedAt, &exitCode,
+		&r.Outcome, &r.Error, &r.Found, &r.Invited, &r.InviteFailed, &r.Messaged)
+	r.FinishedAt, r.ExitCode = finishedAt.Time, -1
+	if exitCode.Valid {
+		r.ExitCode = int(exitCode.Int64)
+	}
+	return r, err
+}
+
+// Runs returns the limit most recent runs, newest first; limit 0 means all.
+func (s *Storage) Runs(ctx context.Context, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+runColumns+` FROM runs ORDER BY started_at DESC, rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// LastRun returns the most recent run that recorded how it ended, leaving
+// out those whose outcome is one of skip; found is false when there is none.
+func (s *Storage) LastRun(ctx context.Context, skip ...string) (run Run, found bool, err error) {
+	query := `SELECT ` + runColumns + ` FROM runs WHERE finished_at IS NOT NULL`
+	args := make([]any, len(skip))
+	if len(skip) > 0 {
+		query += ` AND outcome NOT IN (?` + strings.Repeat(`, ?`, len(skip)-1) + `)`
+		for i, o := range skip {
+			args[i] = o
+		}
+	}
+	run, err = scanRun(s.db.QueryRowContext(ctx, query+` ORDER BY started_at DESC, rowid DESC LIMIT 1`, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Run{}, false, nil
+	}
+	if err != nil {
+		return Run{}, false, err
+	}
+	return run, true, nil
+}
+
+// FindRun returns the run whose id is or starts with id; found is false when
+// there is none, and an error is returned when the prefix is ambiguous.
+func (s *Storage) FindRun(ctx context.Context, id string) (run Run, found bool, err error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+runColumns+` FROM runs WHERE substr(id, 1, ?) = ? LIMIT 2`, len(id), id)
+	if err != nil {
+		return Run{}, false, err
+	}
+	defer rows.Close()
+	var matches []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return Run{}, false, err
+		}
+		matches = append(matches, r)
+	}
+	if err := rows.Err(); err != nil {
+		return Run{}, false, err
+	}
+	switch len(matches) {
+	case 0:
+		return Run{}, false, nil
+	case 1:
+		return matches[0], true, nil
+	}
+	return Run{}, false, fmt.Errorf("run id %q is ambiguous", id)
+}
+
+// RunRequests returns the connection requests last attempted by run id.
+func (s *Storage) RunRequests(ctx context.Context, id string) ([]RequestRecord, error) {
+	return s.requests(ctx, `WHERE run_id = ? ORDER BY updated_at, id`, id)
+}
+
+// RunMessages returns the messages sent by run id.
+func (s *Storage) RunMessages(ctx context.Context, id string) ([]MessageRecord, error) {
+	return s.messages(ctx, `WHERE run_id = ? ORDER BY sent_at, id`, id)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLastRun(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	if _, found, err := s.LastRun(ctx); err != nil || found {
+		t.Fatalf("LastRun on an empty history: found = %v, err = %v", found, err)
+	}
+
+	t0 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	for i, r := range []struct {
+		id, outcome string
+		finished    bool
+	}{
+		{"aaa", "ok", true},
+		{"bbb", "limit_reached", true},
+		{"ccc", "locked", true},
+		// Crashed, or still going.
+		{"ddd", "", false},
+	} {
+		started := t0.Add(time.Duration(i) * time.Hour)
+		if err := s.StartRun(ctx, Run{ID: r.id, Command: "run", StartedAt: started}); err != nil {
+			t.Fatal(err)
+		}
+		if r.finished {
+			if err := s.FinishRun(ctx, r.id, 0, r.outcome, "", started.Add(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		skip []string
+		want string
+	}{
+		{nil, "ccc"},
+		{[]string{"usage", "locked"}, "bbb"},
+		{[]string{"limit_reached", "locked"}, "aaa"},
+	} {
+		run, found, err := s.LastRun(ctx, tt.skip...)
+		if err != nil || !found || run.ID != tt.want {
+			t.Errorf("LastRun(%q) = %s, %v, %v; want %s", tt.skip, run.ID, found, err, tt.want)
+		}
+	}
+}
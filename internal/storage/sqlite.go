@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -17,84 +20,105 @@ type Storage struct {
 	log *logrus.Logger
 }
 
+// New opens the database and applies any pending schema migrations.
 func New(dsn string, log *logrus.Logger) (*Storage, error) {
-	// Use the pure‑Go modernc.org/sqlite driver so this PoC works without CGO.
-	db, err := sql.Open("sqlite", dsn)
+	s, err := Open(dsn, log)
 	if err != nil {
 		return nil, err
 	}
-	s := &Storage{db: db, log: log}
-	if err := s.initSchema(); err != nil {
-		_ = db.Close()
+	if err := s.Migrate(context.Background()); err != nil {
+		_ = s.Close()
 		return nil, err
 	}
 	return s, nil
 }
 
-func (s *Storage) Close() error {
-	return s.db.Close()
+// Open opens the database without migrating it; used by commands such as
+// "migrate status" that must inspect the schema as it is.
+func Open(dsn string, log *logrus.Logger) (*Storage, error) {
+	// Use the pure‑Go modernc.org/sqlite driver so this PoC works without CGO.
+	db, err := sql.Open("sqlite", tuneDSN(dsn))
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{db: db, log: log}, nil
 }
 
-func (s *Storage) initSchema() error {
-	schema := `
-CREATE TABLE IF NOT EXISTS sent_requests (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	profile_url TEXT NOT NULL UNIQUE,
-	sent_at TIMESTAMP NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS messages (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	profile_url TEXT NOT NULL,
-	message_type TEXT NOT NULL,
-	sent_at TIMESTAMP NOT NULL
-);
-`
-	_, err := s.db.Exec(schema)
-	return err
-}
+// busyTimeout is how long a statement waits for another process to release
+// the database before failing with "database is locked".
+const busyTimeout = 10 * time.Second
 
-// HasSentRequest returns true if a connection request has already been
-// recorded for the given profile URL.
-func (s *Storage) HasSentRequest(ctx context.Context, profileURL string) (bool, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT 1 FROM sent_requests WHERE profile_url = ?`, profileURL)
-	var tmp int
-	err := row.Scan(&tmp)
-	if errors.Is(err, sql.ErrNoRows) {
-		return false, nil
+// tuneDSN adds the connection settings that make it safe for several
+// processes to share the file, unless dsn sets them itself: WAL, so readers
+// and the writer do not block each other; a busy timeout, so a second
+// process waits for a lock instead of failing; and immediate transactions,
+// so a transaction that reads before it writes takes the write lock up front
+// and nobody can write in between.
+func tuneDSN(dsn string) string {
+	_, query, _ := strings.Cut(dsn, "?")
+	params, _ := url.ParseQuery(query)
+	has := func(pragma string) bool {
+		for _, v := range params["_pragma"] {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(v)), pragma) {
+				return true
+			}
+		}
+		return false
 	}
-	if err != nil {
-		return false, err
+	var add []string
+	if !has("busy_timeout") {
+		add = append(add, fmt.Sprintf("_pragma=busy_timeout(%d)", busyTimeout.Milliseconds()))
 	}
-	return true, nil
+	if !has("journal_mode") {
+		add = append(add, "_pragma=journal_mode(WAL)")
+	}
+	if params.Get("_txlock") == "" {
+		add = append(add, "_txlock=immediate")
+	}
+	if len(add) == 0 {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(add, "&")
 }
 
-func (s *Storage) RecordRequest(ctx context.Context, profileURL string, when time.Time) error {
-	_, err := s.db.ExecContext(ctx,
-		`INSERT OR IGNORE INTO sent_requests (profile_url, sent_at) VALUES (?, ?)`,
-		profileURL, when.UTC(),
-	)
-	return err
+func (s *Storage) Close() error {
+	return s.db.Close()
 }
 
-// CountRequestsSince returns how many requests have been recorded since the
-// given time. Used to enforce simple daily limits.
-func (s *Storage) CountRequestsSince(ctx context.Context, since time.Time) (int, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM sent_requests WHERE sent_at >= ?`,
-		since.UTC(),
-	)
-	var n int
-	if err := row.Scan(&n); err != nil {
-		return 0, err
+// MessageStatus says whether a sent message was seen in the conversation
+// thread.
+type MessageStatus string
+
+const (
+	// MessageSent messages showed up in the thread after Send was clicked.
+	MessageSent MessageStatus = "sent"
+	// MessageUnconfirmed messages never showed up in the thread. They may
+	// still have gone out, so they count like sent ones.
+	MessageUnconfirmed MessageStatus = "unconfirmed"
+)
+
+// ParseMessageStatus validates a message state read from an import file; an
+// empty one, from files written before messages had a state, is sent.
+func ParseMessageStatus(s string) (MessageStatus, error) {
+	switch st := MessageStatus(s); st {
+	case "":
+		return MessageSent, nil
+	case MessageSent, MessageUnconfirmed:
+		return st, nil
 	}
-	return n, nil
+	return "", fmt.Errorf("unknown message status %q", s)
 }
 
-func (s *Storage) RecordMessage(ctx context.Context, profileURL, msgType string, when time.Time) error {
+// RecordMessage records a message of msgType sent to a profile.
+func (s *Storage) RecordMessage(ctx context.Context, profileURL, msgType string, status MessageStatus, when time.Time) error {
+	profileURL = profileKey(profileURL)
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO messages (profile_url, message_type, sent_at) VALUES (?, ?, ?)`,
-		profileURL, msgType, when.UTC(),
+		`INSERT INTO messages (profile_url, message_type, status, sent_at, run_id) VALUES (?, ?, ?, ?, ?)`,
+		profileURL, msgType, string(status), when.UTC(), nullRunID(ctx),
 	)
 	return err
 }
@@ -111,5 +135,47 @@ func (s *Storage) CountMessagesSince(ctx context.Context, msgType string, since
 	return n, nil
 }
 
+// MessagedProfiles returns the profiles sent a message since the given time
+// that are not on the do-not-contact list, most recently messaged first.
+func (s *Storage) MessagedProfiles(ctx context.Context, since time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT profile_url FROM messages
+WHERE sent_at >= ?
+  AND profile_url NOT IN (SELECT value FROM do_not_contact WHERE kind = ?)
+GROUP BY profile_url
+ORDER BY MAX(sent_at) DESC`,
+		since.UTC(), string(DoNotContactProfile),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	var out []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
 
+// LastMessageAt returns when a message of msgType was last recorded for a
+// profile; found is false when the profile has never received one.
+func (s *Storage) LastMessageAt(ctx context.Context, profileURL, msgType string) (last time.Time, found bool, err error) {
+	profileURL = profileKey(profileURL)
+	row := s.db.QueryRowContext(ctx,
+		`SELECT sent_at FROM messages WHERE profile_url = ? AND message_type = ? ORDER BY sent_at DESC LIMIT 1`,
+		profileURL, msgType,
+	)
+	err = row.Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return last, true, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import "testing"
+
+func TestTuneDSN(t *testing.T) {
+	const defaults = "_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	tests := []struct {
+		name, dsn, want string
+	}{
+		{"plain file", "file:app.db", "file:app.db?" + defaults},
+		{"other parameters kept", "file:app.db?mode=rwc", "file:app.db?mode=rwc&" + defaults},
+		{
+			name: "own busy timeout",
+			dsn:  "file:app.db?_pragma=busy_timeout(500)",
+			want: "file:app.db?_pragma=busy_timeout(500)&_pragma=journal_mode(WAL)&_txlock=immediate",
+		},
+		{
+			name: "pragma matched case-insensitively",
+			dsn:  "file:app.db?_pragma=JOURNAL_MODE(DELETE)",
+			want: "file:app.db?_pragma=JOURNAL_MODE(DELETE)&_pragma=busy_timeout(10000)&_txlock=immediate",
+		},
+		{
+			name: "unrelated pragma",
+			dsn:  "file:app.db?_pragma=foreign_keys(1)",
+			want: "file:app.db?_pragma=foreign_keys(1)&" + defaults,
+		},
+		{
+			name: "own transaction lock",
+			dsn:  "file:app.db?_txlock=deferred",
+			want: "file:app.db?_txlock=deferred&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)",
+		},
+		{
+			name: "everything set",
+			dsn:  "file:app.db?_pragma=busy_timeout(1)&_pragma=journal_mode(DELETE)&_txlock=exclusive",
+			want: "file:app.db?_pragma=busy_timeout(1)&_pragma=journal_mode(DELETE)&_txlock=exclusive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tuneDSN(tt.dsn); got != tt.want {
+				t.Errorf("tuneDSN(%q) =\n\t%s\nwant\n\t%s", tt.dsn, got, tt.want)
+			}
+		})
+	}
+}
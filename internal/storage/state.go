@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Keys used in app_state.
+const (
+	stateCooldown    = "cooldown"
+	stateCheckpoints = "checkpoints"
+)
+
+// Cooldown pauses all sending until a point in time.
+type Cooldown struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+// Active reports whether the cooldown is still in force at now.
+func (c Cooldown) Active(now time.Time) bool {
+	return now.Before(c.Until)
+}
+
+// Checkpoint is one security checkpoint LinkedIn showed.
+type Checkpoint struct {
+	At  time.Time `json:"at"`
+	URL string    `json:"url"`
+}
+
+// getState decodes the JSON value stored under key into v.
+func (s *Storage) getState(ctx context.Context, key string, v any) (found bool, err error) {
+	var raw string
+	err = s.db.QueryRowContext(ctx, `SELECT value FROM app_state WHERE key = ?`, key).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(raw), v)
+}
+
+// setState stores v as JSON under key.
+func (s *Storage) setState(ctx context.Context, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO app_state (key, value, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, string(b), time.Now().UTC(),
+	)
+	return err
+}
+
+func (s *Storage) deleteState(ctx context.Context, key string) (deleted bool, err error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM app_state WHERE key = ?`, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ExtendCooldown stores c unless a cooldown ending later is already stored,
+// so a short pause never cuts a longer one short, and returns the cooldown
+// in force afterwards.
+func (s *Storage) ExtendCooldown(ctx context.Context, c Cooldown) (Cooldown, error) {
+	var prev Cooldown
+	found, err := s.getState(ctx, stateCooldown, &prev)
+	if err != nil {
+		return c, err
+	}
+	if found && prev.Until.After(c.Until) {
+		return prev, nil
+	}
+	return c, s.setState(ctx, stateCooldown, c)
+}
+
+// Cooldown returns the stored cooldown, which may already have expired.
+func (s *Storage) Cooldown(ctx context.Context) (c Cooldown, found bool, err error) {
+	found, err = s.getState(ctx, stateCooldown, &c)
+	return c, found, err
+}
+
+// ClearCooldown removes the stored cooldown; cleared is false when there was
+// none.
+func (s *Storage) ClearCooldown(ctx context.Context) (cleared bool, err error) {
+	return s.deleteState(ctx, stateCooldown)
+}
+
+// RecordCheckpoint adds a checkpoint to the stored history, forgets the ones
+// older than window and returns those left, oldest first, including this one.
+func (s *Storage) RecordCheckpoint(ctx context.Context, c Checkpoint, window time.Duration) ([]Checkpoint, error) {
+	var history []Checkpoint
+	if _, err := s.getState(ctx, stateCheckpoints, &history); err != nil {
+		return nil, err
+	}
+	c.At = c.At.UTC()
+	recent := []Checkpoint{}
+	for _, h := range history {
+		if c.At.Sub(h.At) < window {
+			recent = append(recent, h)
+		}
+	}
+	recent = append(recent, c)
+	return recent, s.setState(ctx, stateCheckpoints, recent)
+}
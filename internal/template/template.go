@@ -0,0 +1,123 @@
+// Package template renders invitation notes and follow-up messages from the
+// config templates. Placeholders look like {{FIRST_NAME}}; a fallback used
+// when the value is unknown follows a pipe, as in {{FIRST_NAME|there}}.
+package template
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// NoteLimit is the longest invitation note LinkedIn accepts, in characters.
+const NoteLimit = 300
+
+// Vars are the profile details a template can use. Empty fields render as
+// the placeholder's fallback.
+type Vars struct {
+	FullName string
+	Headline string
+	Company  string
+	// Keyword is the search keyword that found the person.
+	Keyword    string
+	ProfileURL string
+}
+
+// FirstName is the first word of the full name.
+func (v Vars) FirstName() string {
+	if f := strings.Fields(v.FullName); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// variables maps placeholder names to their values.
+var variables = map[string]func(Vars) string{
+	"FIRST_NAME":  Vars.FirstName,
+	"FULL_NAME":   func(v Vars) string { return v.FullName },
+	"HEADLINE":    func(v Vars) string { return v.Headline },
+	"COMPANY":     func(v Vars) string { return v.Company },
+	"KEYWORD":     func(v Vars) string { return v.Keyword },
+	"PROFILE_URL": func(v Vars) string { return v.ProfileURL },
+}
+
+// Names lists the placeholders templates may use.
+func Names() []string {
+	return []string{"FIRST_NAME", "FULL_NAME", "HEADLINE", "COMPANY", "KEYWORD", "PROFILE_URL"}
+}
+
+// part is literal text, or a placeholder when name is set.
+type part struct {
+	text     string
+	name     string
+	fallback string
+}
+
+// Template is a parsed template.
+type Template struct {
+	// Name identifies the template in errors, e.g. "messaging.templates[1]".
+	Name  string
+	parts []part
+}
+
+// Parse parses text, rejecting unknown placeholders and unclosed braces.
+// name is only used by RenderWithin's error; callers add it to Parse errors.
+func Parse(name, text string) (*Template, error) {
+	t := &Template{Name: name}
+	for rest := text; rest != ""; {
+		open := strings.Index(rest, "{{")
+		if open < 0 {
+			t.parts = append(t.parts, part{text: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, part{text: rest[:open]})
+		}
+		end := strings.Index(rest[open:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed {{ in %q", rest[open:])
+		}
+		inner := rest[open+2 : open+end]
+		varName, fallback, _ := strings.Cut(inner, "|")
+		varName = strings.TrimSpace(varName)
+		if _, ok := variables[varName]; !ok {
+			return nil, fmt.Errorf("unknown placeholder {{%s}} (known: %s)", inner, strings.Join(Names(), ", "))
+		}
+		t.parts = append(t.parts, part{name: varName, fallback: strings.TrimSpace(fallback)})
+		rest = rest[open+end+2:]
+	}
+	return t, nil
+}
+
+// Render fills in the placeholders.
+func (t *Template) Render(v Vars) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.name == "" {
+			b.WriteString(p.text)
+			continue
+		}
+		value := strings.TrimSpace(variables[p.name](v))
+		if value == "" {
+			value = p.fallback
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
+
+// FallbackLength is the length of the template rendered for a profile with
+// no known details, i.e. with every placeholder at its fallback.
+func (t *Template) FallbackLength() int {
+	return utf8.RuneCountInString(t.Render(Vars{}))
+}
+
+// RenderWithin renders the template and fails if the result is longer than
+// limit characters, naming the template.
+func (t *Template) RenderWithin(v Vars, limit int) (string, error) {
+	out := t.Render(v)
+	if n := utf8.RuneCountInString(out); n > limit {
+		return "", fmt.Errorf("%s renders to %d characters, over the %d-character limit", t.Name, n, limit)
+	}
+	return out, nil
+}
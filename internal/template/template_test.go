@@ -0,0 +1,63 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	jane := Vars{FullName: "Jane Doe", Headline: "Go Developer", Company: "Acme", Keyword: "golang", ProfileURL: "https://www.linkedin.com/in/jane-doe/"}
+	tests := []struct {
+		text string
+		v    Vars
+		want string
+	}{
+		{"Hi {{FIRST_NAME}}!", jane, "Hi Jane!"},
+		{"Hi {{FIRST_NAME|there}}!", jane, "Hi Jane!"},
+		{"Hi {{FIRST_NAME|there}}!", Vars{}, "Hi there!"},
+		{"Hi {{ FIRST_NAME | there }}!", Vars{FullName: "   "}, "Hi there!"},
+		{"Hi {{FIRST_NAME}}!", Vars{}, "Hi !"},
+		{"{{FULL_NAME}} at {{COMPANY|your company}}", Vars{FullName: "Jane Doe"}, "Jane Doe at your company"},
+		{"{{HEADLINE}} / {{KEYWORD}} / {{PROFILE_URL}}", jane, "Go Developer / golang / https://www.linkedin.com/in/jane-doe/"},
+		{"No placeholders.", jane, "No placeholders."},
+	}
+	for _, tt := range tests {
+		tmpl, err := Parse("test", tt.text)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.text, err)
+			continue
+		}
+		if got := tmpl.Render(tt.v); got != tt.want {
+			t.Errorf("Render(%q, %+v) = %q, want %q", tt.text, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"Hi {{FIRST_NAME",
+		"Hi {{NAME}}",
+		"Hi {{first_name|there}}",
+	} {
+		if _, err := Parse("test", text); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestLengths(t *testing.T) {
+	tmpl, err := Parse("connect.note_template", "Hi {{FIRST_NAME|there}}, "+strings.Repeat("é", 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tmpl.FallbackLength(), len("Hi there, ")+20; got != want {
+		t.Errorf("FallbackLength = %d, want %d", got, want)
+	}
+	if _, err := tmpl.RenderWithin(Vars{FullName: "Jo"}, 27); err != nil {
+		t.Errorf("RenderWithin at the limit: %v", err)
+	}
+	_, err = tmpl.RenderWithin(Vars{FullName: "Jonathan"}, 27)
+	if err == nil || !strings.Contains(err.Error(), "connect.note_template renders to 33 characters") {
+		t.Errorf("RenderWithin over the limit: %v", err)
+	}
+}
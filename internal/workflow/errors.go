@@ -0,0 +1,32 @@
+// Package workflow holds the outcomes shared by the automation workflows, so
+// callers (and the exit codes of cmd/app) can tell an expected stop apart
+// from a failure.
+package workflow
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrLimitReached means a daily or weekly limit stopped a workflow while
+	// work was still left; it is an expected stop, not a failure.
+	ErrLimitReached = errors.New("limit reached")
+	// ErrCheckpoint means LinkedIn showed a security checkpoint or challenge
+	// that needs a human before automation can continue.
+	ErrCheckpoint = errors.New("LinkedIn security checkpoint")
+	// ErrLoginRequired means there is no valid session and no way to log in
+	// without the operator.
+	ErrLoginRequired = errors.New("login required")
+	// ErrCooldown means sending is paused: LinkedIn warned that the account
+	// is being limited, or showed checkpoints repeatedly. Nothing is sent
+	// until the cooldown ends or an operator clears it.
+	ErrCooldown = errors.New("sending paused by cooldown")
+)
+
+// Stops reports whether err must end a whole run rather than just the step
+// that returned it: a checkpoint, a lost login, a cooldown or an interrupt.
+func Stops(err error) bool {
+	return errors.Is(err, ErrCheckpoint) || errors.Is(err, ErrLoginRequired) || errors.Is(err, ErrCooldown) ||
+		errors.Is(err, context.Canceled)
+}